@@ -1,36 +1,143 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"time"
 
+	"github.com/yuchida-tamu/git-workout-api/internal/auth"
+	"github.com/yuchida-tamu/git-workout-api/internal/coach"
 	"github.com/yuchida-tamu/git-workout-api/internal/db"
+	"github.com/yuchida-tamu/git-workout-api/internal/mailer"
+	"github.com/yuchida-tamu/git-workout-api/internal/memstore"
+	"github.com/yuchida-tamu/git-workout-api/internal/oidc"
+	"github.com/yuchida-tamu/git-workout-api/internal/password"
 	"github.com/yuchida-tamu/git-workout-api/internal/record"
+	"github.com/yuchida-tamu/git-workout-api/internal/signing"
 	transportHttp "github.com/yuchida-tamu/git-workout-api/internal/transport/http"
 	"github.com/yuchida-tamu/git-workout-api/internal/user"
 )
 
+// Store - everything the services persist, implemented by db.Database and memstore.Store
+type Store interface {
+	user.Store
+	record.Store
+	coach.Store
+	auth.Store
+}
+
+// newStoreFromEnv - picks the store from STORE: "postgres", the default, connects to and migrates the database
+// configured by the DB_* variables; "sqlite" does the same with the single file at SQLITE_PATH; "memory" keeps
+// everything in memory, for demos and trying the API out.
+func newStoreFromEnv() (Store, error) {
+	switch os.Getenv("STORE") {
+	case "", "postgres":
+		// connect to database
+		db, err := db.NewDatabase()
+		if err != nil {
+			fmt.Println("failed to connect to the database")
+			return nil, err
+		}
+		// migrate database
+		if err := db.MigrateDB(); err != nil {
+			fmt.Println("failed to migrate database")
+			return nil, err
+		}
+		return db, nil
+	case "sqlite":
+		path := os.Getenv("SQLITE_PATH")
+		if path == "" {
+			path = "git-gym.db"
+		}
+		db, err := db.NewSQLiteDatabase(path)
+		if err != nil {
+			fmt.Println("failed to open the sqlite database")
+			return nil, err
+		}
+		if err := db.MigrateDB(); err != nil {
+			fmt.Println("failed to migrate database")
+			return nil, err
+		}
+		return db, nil
+	case "memory":
+		fmt.Println("using the in-memory store, nothing is persisted")
+		return memstore.NewStore(), nil
+	default:
+		return nil, fmt.Errorf("unknown store: %q", os.Getenv("STORE"))
+	}
+}
+
+// purgeInterval - how often accounts whose deletion grace period is over get purged
+const purgeInterval = time.Hour
+
+// purgeDueUsers - purges the accounts due for deletion now and then every purgeInterval, for as long as the
+// server runs
+func purgeDueUsers(userService *user.Service) {
+	ticker := time.NewTicker(purgeInterval)
+	defer ticker.Stop()
+
+	for {
+		purged, err := userService.PurgeDueUsers(context.Background(), time.Now().UTC())
+		if err != nil {
+			fmt.Println("failed to purge the accounts due for deletion")
+		} else if purged > 0 {
+			fmt.Printf("purged %d accounts due for deletion\n", purged)
+		}
+		<-ticker.C
+	}
+}
+
 func Run() error {
 	fmt.Println("starting up the application")
-	// connect to database
-	db, err := db.NewDatabase()
+	store, err := newStoreFromEnv()
+	if err != nil {
+		return err
+	}
+
+	passwordPolicy, err := password.NewPolicyFromEnv()
 	if err != nil {
-		fmt.Println("failed to connect to the database")
+		fmt.Println("failed to load the password policy")
 		return err
 	}
-	// migrate database
-	if err := db.MigrateDB(); err != nil {
-		fmt.Println("failed to migrate database")
+
+	hasher, err := password.NewHasherFromEnv()
+	if err != nil {
+		fmt.Println("failed to configure password hashing")
+		return err
+	}
+
+	userService := user.NewService(store, passwordPolicy, hasher)
+	go purgeDueUsers(userService)
+	recordService := record.NewService(store)
+	coachService := coach.NewService(store)
+	mail, err := mailer.NewMailerFromEnv()
+	if err != nil {
+		fmt.Println("failed to set up the mailer")
+		return err
+	}
+
+	providers, err := oidc.LoadProvidersFromEnv(context.Background())
+	if err != nil {
+		fmt.Println("failed to set up the identity providers")
 		return err
 	}
 
-	userService := user.NewService(db)
-	recordService := record.NewService(db)
+	authService := auth.NewService(store, mail, passwordPolicy, hasher, providers)
 	service := transportHttp.Service{
 		User:   userService,
 		Record: recordService,
+		Coach:  coachService,
+		Auth:   authService,
+	}
+
+	keys, err := signing.LoadKeySet()
+	if err != nil {
+		fmt.Println("failed to load the token signing keys")
+		return err
 	}
 
-	httpHandler := transportHttp.NewHandler(service)
+	httpHandler := transportHttp.NewHandler(service, keys)
 	if err := httpHandler.Serve(); err != nil {
 		return nil
 	}
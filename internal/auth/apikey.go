@@ -0,0 +1,155 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/yuchida-tamu/git-workout-api/internal/user"
+)
+
+const (
+	apiKeyPrefix = "gw"
+	// lastUsedResolution limits how often using a key writes to the store
+	lastUsedResolution = time.Minute
+)
+
+var (
+	ErrInvalidAPIKey  = errors.New("invalid api key")
+	ErrAPIKeyNotFound = errors.New("api key not found")
+)
+
+// APIKey - a long-lived credential a user creates for scripts. Only the hash of the key is stored;
+// Prefix is the start of the key, kept so users can tell their keys apart. A zero ExpiresAt never expires.
+type APIKey struct {
+	ID          string
+	UserID      string
+	Name        string
+	Prefix      string
+	KeyHash     string
+	Scopes      []Scope
+	Revoked     bool
+	ExpiresAt   time.Time
+	LastUsed    time.Time
+	DateCreated time.Time
+}
+
+// CreateAPIKey - creates a key for the user and returns it together with the raw key, which is not stored
+// and can only be shown this once
+func (s *Service) CreateAPIKey(ctx context.Context, userID string, name string, scopes []Scope, expiresAt time.Time) (string, APIKey, error) {
+	if strings.TrimSpace(name) == "" {
+		return "", APIKey{}, fmt.Errorf("%w: an api key needs a name", ErrValidation)
+	}
+	if len(scopes) == 0 {
+		return "", APIKey{}, fmt.Errorf("%w: an api key needs at least one scope", ErrValidation)
+	}
+	for _, scope := range scopes {
+		if !ValidScope(scope) {
+			return "", APIKey{}, fmt.Errorf("%w: unknown scope: %q", ErrValidation, scope)
+		}
+	}
+	now := time.Now().UTC()
+	if !expiresAt.IsZero() && !expiresAt.After(now) {
+		return "", APIKey{}, fmt.Errorf("%w: the expiry must be in the future", ErrValidation)
+	}
+
+	secret, err := GenerateToken()
+	if err != nil {
+		return "", APIKey{}, fmt.Errorf("failed to generate api key: %w", err)
+	}
+	// the secret is url-safe base64, which never contains the "." separator
+	raw := apiKeyPrefix + "." + secret
+	prefix := raw[:len(apiKeyPrefix)+1+8]
+
+	key, err := s.Store.PostAPIKey(ctx, APIKey{
+		UserID:      userID,
+		Name:        name,
+		Prefix:      prefix,
+		KeyHash:     HashToken(raw),
+		Scopes:      scopes,
+		ExpiresAt:   expiresAt,
+		DateCreated: now,
+	})
+	if err != nil {
+		fmt.Println(err)
+		return "", APIKey{}, err
+	}
+
+	return raw, key, nil
+}
+
+// AuthenticateAPIKey - looks up a valid key by its raw value, together with the user it acts for,
+// and notes that it was used. Keys of disabled users are rejected like any invalid key, as are the keys of
+// users whose account is pending deletion; they work again once the user logs in and keeps the account.
+func (s *Service) AuthenticateAPIKey(ctx context.Context, raw string) (APIKey, user.User, error) {
+	key, err := s.Store.GetAPIKeyByHash(ctx, HashToken(raw))
+	if err != nil {
+		fmt.Println(err)
+		return APIKey{}, user.User{}, ErrInvalidAPIKey
+	}
+
+	now := time.Now().UTC()
+	if key.Revoked || (!key.ExpiresAt.IsZero() && now.After(key.ExpiresAt)) {
+		return APIKey{}, user.User{}, ErrInvalidAPIKey
+	}
+
+	u, err := s.Store.GetUser(ctx, key.UserID)
+	if err != nil {
+		fmt.Println(err)
+		return APIKey{}, user.User{}, ErrInvalidAPIKey
+	}
+	if u.Disabled || !u.DeleteAfter.IsZero() {
+		return APIKey{}, user.User{}, ErrInvalidAPIKey
+	}
+
+	if now.Sub(key.LastUsed) > lastUsedResolution {
+		if err := s.Store.TouchAPIKey(ctx, key.ID, now); err != nil {
+			fmt.Println(err)
+		}
+		key.LastUsed = now
+	}
+
+	return key, u, nil
+}
+
+// GetAPIKeysByUser - lists the user's keys which have not been revoked
+func (s *Service) GetAPIKeysByUser(ctx context.Context, userID string) ([]APIKey, error) {
+	keys, err := s.Store.GetAPIKeysByUser(ctx, userID)
+	if err != nil {
+		fmt.Println(err)
+		return []APIKey{}, err
+	}
+
+	active := []APIKey{}
+	for _, key := range keys {
+		if !key.Revoked {
+			active = append(active, key)
+		}
+	}
+
+	return active, nil
+}
+
+// RevokeAPIKey - revokes one of the user's keys
+func (s *Service) RevokeAPIKey(ctx context.Context, userID string, ID string) error {
+	key, err := s.Store.GetAPIKey(ctx, ID)
+	if errors.Is(err, ErrNotFound) {
+		return ErrAPIKeyNotFound
+	}
+	if err != nil {
+		fmt.Println(err)
+		return err
+	}
+	if key.UserID != userID {
+		return ErrAPIKeyNotFound
+	}
+
+	if err := s.Store.RevokeAPIKey(ctx, ID); err != nil {
+		fmt.Println(err)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,56 @@
+package auth_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/yuchida-tamu/git-workout-api/internal/auth"
+	"github.com/yuchida-tamu/git-workout-api/internal/mailer"
+	"github.com/yuchida-tamu/git-workout-api/internal/memstore"
+	"github.com/yuchida-tamu/git-workout-api/internal/password"
+	"github.com/yuchida-tamu/git-workout-api/internal/user"
+)
+
+func TestAuthenticateAPIKey(t *testing.T) {
+	ctx := context.Background()
+	store := memstore.NewStore()
+	service := auth.NewService(store, mailer.NewLogMailer(""), password.DefaultPolicy(), password.DefaultHasher(), nil)
+
+	u, err := store.PostUser(ctx, user.User{Username: "alice", Email: "alice@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, key, err := service.CreateAPIKey(ctx, u.ID, "importer", []auth.Scope{auth.ScopeRecordsRead}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, _, err := service.AuthenticateAPIKey(ctx, raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != key.ID {
+		t.Fatalf("expected the key %s, got %s", key.ID, got.ID)
+	}
+
+	// a user who asked for their account to be deleted can no longer use it through their keys
+	if err := store.SetUserDeleteAfter(ctx, u.ID, time.Now().Add(user.DeletionGracePeriod), 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := service.AuthenticateAPIKey(ctx, raw); !errors.Is(err, auth.ErrInvalidAPIKey) {
+		t.Fatalf("expected the key of a user pending deletion to be rejected, got %v", err)
+	}
+
+	if err := store.SetUserDeleteAfter(ctx, u.ID, time.Time{}, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := service.AuthenticateAPIKey(ctx, raw); err != nil {
+		t.Fatalf("expected the key to work again once the deletion is cancelled, got %v", err)
+	}
+
+	if _, _, err := service.AuthenticateAPIKey(ctx, raw+"x"); !errors.Is(err, auth.ErrInvalidAPIKey) {
+		t.Fatalf("expected an unknown key to be rejected, got %v", err)
+	}
+}
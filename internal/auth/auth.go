@@ -0,0 +1,279 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/yuchida-tamu/git-workout-api/internal/mailer"
+	"github.com/yuchida-tamu/git-workout-api/internal/oidc"
+	"github.com/yuchida-tamu/git-workout-api/internal/password"
+	"github.com/yuchida-tamu/git-workout-api/internal/user"
+)
+
+const refreshTokenLifetime = 24 * time.Hour
+
+var (
+	// ErrNotFound is what the store returns for a missing row; the service turns it into one of the more
+	// specific errors below where the caller needs to tell them apart
+	ErrNotFound            = errors.New("not found")
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	// ErrRefreshTokenReused is returned when an already rotated token is presented again,
+	// which means it leaked; the whole token family is revoked when this happens
+	ErrRefreshTokenReused = errors.New("refresh token reused")
+	// ErrValidation is returned, wrapped with what is wrong, for a credential or client which cannot be created
+	// as requested
+	ErrValidation = errors.New("invalid request")
+)
+
+// RefreshToken - a server-side record of an issued refresh token; only the hash of the token is stored.
+// Every token rotated from the same login shares a FamilyID, which is the ID of that login's Session.
+type RefreshToken struct {
+	ID          string
+	UserID      string
+	FamilyID    string
+	TokenHash   string
+	Used        bool
+	Revoked     bool
+	ExpiresAt   time.Time
+	DateCreated time.Time
+}
+
+type Store interface {
+	PostRefreshToken(context.Context, RefreshToken) (RefreshToken, error)
+	GetRefreshTokenByHash(ctx context.Context, hash string) (RefreshToken, error)
+	// MarkRefreshTokenUsed flags an unused token as used and reports false if it had already been used
+	MarkRefreshTokenUsed(ctx context.Context, ID string) (bool, error)
+	RevokeRefreshTokenFamily(ctx context.Context, familyID string) error
+
+	PostSession(context.Context, Session) (Session, error)
+	GetSession(ctx context.Context, ID string) (Session, error)
+	GetSessionsByUser(ctx context.Context, userID string) ([]Session, error)
+	TouchSession(ctx context.Context, ID string, client ClientInfo, lastUsed time.Time) error
+	RevokeSession(ctx context.Context, ID string) error
+
+	GetMFA(ctx context.Context, userID string) (MFA, error)
+	PutMFA(context.Context, MFA) error
+	// MarkMFAStepUsed records step as the user's last used TOTP step and reports false if that step, or a
+	// later one, had already been used
+	MarkMFAStepUsed(ctx context.Context, userID string, step int64) (bool, error)
+	// RemoveMFARecoveryCode removes the recovery code hash of the user and reports false if it was not there
+	// (anymore)
+	RemoveMFARecoveryCode(ctx context.Context, userID string, hash string) (bool, error)
+	DeleteMFA(ctx context.Context, userID string) error
+
+	PostOneTimeToken(context.Context, OneTimeToken) (OneTimeToken, error)
+	GetOneTimeTokenByHash(ctx context.Context, hash string) (OneTimeToken, error)
+	// MarkOneTimeTokenUsed flags an unused token as used and reports false if it had already been used
+	MarkOneTimeTokenUsed(ctx context.Context, ID string) (bool, error)
+	DeleteOneTimeTokensByUser(ctx context.Context, userID string, purpose Purpose) error
+
+	PostLockoutEvent(context.Context, LockoutEvent) (LockoutEvent, error)
+	GetLockoutEventsByUser(ctx context.Context, userID string) ([]LockoutEvent, error)
+
+	PostAPIKey(context.Context, APIKey) (APIKey, error)
+	GetAPIKey(ctx context.Context, ID string) (APIKey, error)
+	GetAPIKeyByHash(ctx context.Context, hash string) (APIKey, error)
+	GetAPIKeysByUser(ctx context.Context, userID string) ([]APIKey, error)
+	TouchAPIKey(ctx context.Context, ID string, lastUsed time.Time) error
+	RevokeAPIKey(ctx context.Context, ID string) error
+
+	PostOIDCLogin(context.Context, OIDCLogin) (OIDCLogin, error)
+	GetOIDCLoginByStateHash(ctx context.Context, hash string) (OIDCLogin, error)
+	// DeleteOIDCLogin reports false if the login had already been deleted
+	DeleteOIDCLogin(ctx context.Context, ID string) (bool, error)
+	PostIdentity(context.Context, Identity) (Identity, error)
+	GetIdentity(ctx context.Context, provider string, subject string) (Identity, error)
+	GetIdentitiesByUser(ctx context.Context, userID string) ([]Identity, error)
+	DeleteIdentity(ctx context.Context, ID string) error
+
+	PostOAuthClient(context.Context, OAuthClient) (OAuthClient, error)
+	GetOAuthClient(ctx context.Context, ID string) (OAuthClient, error)
+	GetOAuthClientsByOwner(ctx context.Context, ownerID string) ([]OAuthClient, error)
+	DeleteOAuthClient(ctx context.Context, ID string) error
+	RevokeSessionsByClient(ctx context.Context, clientID string) error
+	PostAuthorizationCode(context.Context, AuthorizationCode) (AuthorizationCode, error)
+	GetAuthorizationCodeByHash(ctx context.Context, hash string) (AuthorizationCode, error)
+	// MarkAuthorizationCodeUsed flags an unused code as used and reports false if it had already been used
+	MarkAuthorizationCodeUsed(ctx context.Context, ID string) (bool, error)
+	SetAuthorizationCodeSession(ctx context.Context, ID string, sessionID string) error
+	GetConsent(ctx context.Context, userID string, clientID string) (Consent, error)
+	PutConsent(context.Context, Consent) error
+
+	GetUser(context.Context, string) (user.User, error)
+	GetUserByUsername(context.Context, string) (user.User, error)
+	GetUserByEmail(context.Context, string) (user.User, error)
+	PostUser(context.Context, user.User) (user.User, error)
+	UpdateUserPassword(ctx context.Context, ID string, hash string) error
+	SetUserEmailVerified(ctx context.Context, ID string, verified bool) error
+
+	// WithTx - runs fn in a transaction which every call passing on fn's context takes part in; it rolls back
+	// when fn returns an error or panics
+	WithTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type Service struct {
+	Store          Store
+	Mailer         mailer.Mailer
+	PasswordPolicy password.Policy
+	Hasher         password.Hasher
+	// UserThrottle and IPThrottle count failed logins per username and per client ip
+	UserThrottle *Throttle
+	IPThrottle   *Throttle
+	// Providers are the external identity providers users can sign in with, by name
+	Providers map[string]*oidc.Provider
+}
+
+func NewService(
+	store Store,
+	mailer mailer.Mailer,
+	passwordPolicy password.Policy,
+	hasher password.Hasher,
+	providers map[string]*oidc.Provider,
+) *Service {
+	return &Service{
+		Store:          store,
+		Mailer:         mailer,
+		PasswordPolicy: passwordPolicy,
+		Hasher:         hasher,
+		Providers:      providers,
+		// a shared ip (an office, a carrier NAT) sees more honest failures than a single account
+		UserThrottle: NewThrottle(5, time.Second, 15*time.Minute, time.Hour),
+		IPThrottle:   NewThrottle(20, time.Second, 15*time.Minute, time.Hour),
+	}
+}
+
+// HashToken - hashes an opaque token for storage and lookup
+func HashToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+// GenerateToken - returns a random url-safe opaque token
+func GenerateToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func (s *Service) issue(ctx context.Context, userID string, familyID string) (string, RefreshToken, error) {
+	raw, err := GenerateToken()
+	if err != nil {
+		return "", RefreshToken{}, fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+
+	now := time.Now().UTC()
+	token, err := s.Store.PostRefreshToken(ctx, RefreshToken{
+		UserID:      userID,
+		FamilyID:    familyID,
+		TokenHash:   HashToken(raw),
+		ExpiresAt:   now.Add(refreshTokenLifetime),
+		DateCreated: now,
+	})
+	if err != nil {
+		fmt.Println(err)
+		return "", RefreshToken{}, err
+	}
+
+	return raw, token, nil
+}
+
+// IssueRefreshToken - starts a new session for the user and returns the raw refresh token to hand to the client
+func (s *Service) IssueRefreshToken(ctx context.Context, userID string, client ClientInfo) (string, RefreshToken, error) {
+	_, raw, token, err := s.startSession(ctx, Session{UserID: userID}, client)
+	return raw, token, err
+}
+
+func (s *Service) startSession(ctx context.Context, session Session, client ClientInfo) (Session, string, RefreshToken, error) {
+	now := time.Now().UTC()
+	session.Device = client.Device
+	session.IP = client.IP
+	session.UserAgent = client.UserAgent
+	session.LastUsed = now
+	session.DateCreated = now
+
+	session, err := s.Store.PostSession(ctx, session)
+	if err != nil {
+		fmt.Println(err)
+		return Session{}, "", RefreshToken{}, err
+	}
+
+	raw, token, err := s.issue(ctx, session.UserID, session.ID)
+	if err != nil {
+		return Session{}, "", RefreshToken{}, err
+	}
+
+	return session, raw, token, nil
+}
+
+// RotateRefreshToken - exchanges a valid refresh token for a new one in the same family.
+// Presenting a token which was already rotated revokes the whole family along with its session.
+// Tokens granted to third-party apps are refused here and only rotate through RefreshOAuthToken.
+func (s *Service) RotateRefreshToken(ctx context.Context, raw string, client ClientInfo) (string, RefreshToken, error) {
+	_, newRaw, token, err := s.rotate(ctx, raw, client, "")
+	return newRaw, token, err
+}
+
+// rotate - rotates a refresh token of a session belonging to clientID, "" being this API's own clients
+func (s *Service) rotate(ctx context.Context, raw string, client ClientInfo, clientID string) (Session, string, RefreshToken, error) {
+	token, err := s.Store.GetRefreshTokenByHash(ctx, HashToken(raw))
+	if err != nil {
+		fmt.Println(err)
+		return Session{}, "", RefreshToken{}, ErrInvalidRefreshToken
+	}
+
+	if token.Revoked || time.Now().After(token.ExpiresAt) {
+		return Session{}, "", RefreshToken{}, ErrInvalidRefreshToken
+	}
+
+	session, err := s.Store.GetSession(ctx, token.FamilyID)
+	if err != nil {
+		fmt.Println(err)
+		return Session{}, "", RefreshToken{}, ErrInvalidRefreshToken
+	}
+	if session.ClientID != clientID {
+		return Session{}, "", RefreshToken{}, ErrInvalidRefreshToken
+	}
+
+	marked, err := s.Store.MarkRefreshTokenUsed(ctx, token.ID)
+	if err != nil {
+		fmt.Println(err)
+		return Session{}, "", RefreshToken{}, err
+	}
+	if token.Used || !marked {
+		if err := s.revokeSession(ctx, token.FamilyID); err != nil {
+			return Session{}, "", RefreshToken{}, err
+		}
+		return Session{}, "", RefreshToken{}, ErrRefreshTokenReused
+	}
+
+	if err := s.Store.TouchSession(ctx, token.FamilyID, client, time.Now().UTC()); err != nil {
+		fmt.Println(err)
+		return Session{}, "", RefreshToken{}, err
+	}
+
+	newRaw, newToken, err := s.issue(ctx, token.UserID, token.FamilyID)
+	if err != nil {
+		return Session{}, "", RefreshToken{}, err
+	}
+
+	return session, newRaw, newToken, nil
+}
+
+// RevokeRefreshToken - revokes the session the token belongs to, logging the client out
+func (s *Service) RevokeRefreshToken(ctx context.Context, raw string) error {
+	token, err := s.Store.GetRefreshTokenByHash(ctx, HashToken(raw))
+	if err != nil {
+		fmt.Println(err)
+		return ErrInvalidRefreshToken
+	}
+
+	return s.revokeSession(ctx, token.FamilyID)
+}
@@ -0,0 +1,11 @@
+package auth
+
+import "time"
+
+// SetNow - replaces the throttle's clock, so that the tests can move time on instead of waiting
+func (t *Throttle) SetNow(now func() time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.now = now
+}
@@ -0,0 +1,109 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/yuchida-tamu/git-workout-api/internal/mailer"
+)
+
+// LockoutReason - which counter caused a lockout
+type LockoutReason string
+
+const (
+	LockoutReasonUsername LockoutReason = "username"
+	LockoutReasonIP       LockoutReason = "ip"
+)
+
+// LockoutEvent - a record of a lockout caused by repeated failed logins, kept so users can be told about it.
+// UserID is empty when the username does not belong to an account.
+type LockoutEvent struct {
+	ID          string
+	UserID      string
+	Username    string
+	IP          string
+	Reason      LockoutReason
+	LockedUntil time.Time
+	DateCreated time.Time
+}
+
+func usernameThrottleKey(username string) string {
+	return "user:" + strings.ToLower(username)
+}
+
+func ipThrottleKey(ip string) string {
+	return "ip:" + ip
+}
+
+// LoginLockedFor - how long logins for the username from the ip are still locked out, zero when allowed
+func (s *Service) LoginLockedFor(username string, ip string) time.Duration {
+	userLock := s.UserThrottle.LockedFor(usernameThrottleKey(username))
+	ipLock := s.IPThrottle.LockedFor(ipThrottleKey(ip))
+	if ipLock > userLock {
+		return ipLock
+	}
+	return userLock
+}
+
+// RecordLoginFailure - counts a failed login against the username and the ip, and records a lockout event
+// whenever one of them gets locked out
+func (s *Service) RecordLoginFailure(ctx context.Context, username string, ip string) {
+	if lockout := s.UserThrottle.Failure(usernameThrottleKey(username)); lockout > 0 {
+		s.recordLockout(ctx, username, ip, LockoutReasonUsername, lockout)
+	}
+	if lockout := s.IPThrottle.Failure(ipThrottleKey(ip)); lockout > 0 {
+		s.recordLockout(ctx, username, ip, LockoutReasonIP, lockout)
+	}
+}
+
+// RecordLoginSuccess - clears the failures of the username; the ip keeps its count so that logging into
+// an account of one's own cannot be used to keep guessing at others
+func (s *Service) RecordLoginSuccess(username string) {
+	s.UserThrottle.Success(usernameThrottleKey(username))
+}
+
+func (s *Service) recordLockout(ctx context.Context, username string, ip string, reason LockoutReason, lockout time.Duration) {
+	now := time.Now().UTC()
+	event := LockoutEvent{
+		Username:    username,
+		IP:          ip,
+		Reason:      reason,
+		LockedUntil: now.Add(lockout),
+		DateCreated: now,
+	}
+
+	u, err := s.Store.GetUserByUsername(ctx, username)
+	if err == nil {
+		event.UserID = u.ID
+	}
+
+	if _, err := s.Store.PostLockoutEvent(ctx, event); err != nil {
+		fmt.Println(err)
+	}
+
+	// only the first lockout of a run is mailed, later ones would just flood the inbox
+	if event.UserID == "" || u.Email == "" || !u.EmailVerified || reason != LockoutReasonUsername || lockout > s.UserThrottle.BaseDelay {
+		return
+	}
+	if err := s.Mailer.Send(ctx, mailer.Message{
+		To:      u.Email,
+		Subject: "Repeated failed sign-ins to your git-workout account",
+		Body: "There were several failed attempts to sign in to your account, so signing in is paused for a while.\n\n" +
+			"If this was not you, consider changing your password and enabling two-factor authentication.\n",
+	}); err != nil {
+		fmt.Println(err)
+	}
+}
+
+// GetLockoutEventsByUser - lists the lockouts recorded for the user's account
+func (s *Service) GetLockoutEventsByUser(ctx context.Context, userID string) ([]LockoutEvent, error) {
+	events, err := s.Store.GetLockoutEventsByUser(ctx, userID)
+	if err != nil {
+		fmt.Println(err)
+		return []LockoutEvent{}, err
+	}
+
+	return events, nil
+}
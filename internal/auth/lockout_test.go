@@ -0,0 +1,111 @@
+package auth_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/yuchida-tamu/git-workout-api/internal/auth"
+	"github.com/yuchida-tamu/git-workout-api/internal/mailer"
+	"github.com/yuchida-tamu/git-workout-api/internal/memstore"
+	"github.com/yuchida-tamu/git-workout-api/internal/password"
+	"github.com/yuchida-tamu/git-workout-api/internal/user"
+)
+
+func TestLoginLockout(t *testing.T) {
+	ctx := context.Background()
+
+	// newLockoutService - usernames are locked out after 2 failures, ips after 4
+	newLockoutService := func() (*auth.Service, *memstore.Store) {
+		store := memstore.NewStore()
+		service := auth.NewService(store, mailer.NewLogMailer(""), password.DefaultPolicy(), password.DefaultHasher(), nil)
+		clock := &fakeClock{now: time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)}
+		service.UserThrottle = auth.NewThrottle(2, time.Second, time.Minute, time.Hour)
+		service.UserThrottle.SetNow(clock.Now)
+		service.IPThrottle = auth.NewThrottle(4, time.Second, time.Minute, time.Hour)
+		service.IPThrottle.SetNow(clock.Now)
+
+		return service, store
+	}
+
+	t.Run("Username", func(t *testing.T) {
+		service, store := newLockoutService()
+		alice, err := store.PostUser(ctx, user.User{Username: "alice", Email: "alice@example.com"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// from a new ip every time, so only the username counts
+		for _, ip := range []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"} {
+			service.RecordLoginFailure(ctx, "alice", ip)
+		}
+
+		for _, c := range []struct {
+			username string
+			ip       string
+			locked   bool
+		}{
+			{"alice", "10.0.0.9", true},
+			// the username is the same account whatever its case
+			{"ALICE", "10.0.0.9", true},
+			{"bob", "10.0.0.1", false},
+		} {
+			if locked := service.LoginLockedFor(c.username, c.ip) > 0; locked != c.locked {
+				t.Fatalf("%s from %s: expected locked=%v", c.username, c.ip, c.locked)
+			}
+		}
+
+		events, err := service.GetLockoutEventsByUser(ctx, alice.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(events) != 1 || events[0].Reason != auth.LockoutReasonUsername || events[0].IP != "10.0.0.3" {
+			t.Fatalf("expected one username lockout, got %+v", events)
+		}
+	})
+
+	t.Run("IP", func(t *testing.T) {
+		service, _ := newLockoutService()
+
+		// a different username every time, so only the ip counts
+		for _, username := range []string{"alice", "bob", "carol", "dave", "erin"} {
+			service.RecordLoginFailure(ctx, username, "10.0.0.1")
+		}
+
+		for _, c := range []struct {
+			username string
+			ip       string
+			locked   bool
+		}{
+			{"frank", "10.0.0.1", true},
+			{"frank", "10.0.0.2", false},
+			{"alice", "10.0.0.2", false},
+		} {
+			if locked := service.LoginLockedFor(c.username, c.ip) > 0; locked != c.locked {
+				t.Fatalf("%s from %s: expected locked=%v", c.username, c.ip, c.locked)
+			}
+		}
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		service, _ := newLockoutService()
+
+		for i := 0; i < 2; i++ {
+			service.RecordLoginFailure(ctx, "alice", "10.0.0.1")
+		}
+		service.RecordLoginSuccess("alice")
+		// the username starts over with its free attempts
+		for i := 0; i < 2; i++ {
+			service.RecordLoginFailure(ctx, "alice", "10.0.0.1")
+		}
+		if got := service.LoginLockedFor("alice", "10.0.0.2"); got != 0 {
+			t.Fatalf("expected the success to reset the username's failures, got a lockout of %v", got)
+		}
+
+		// but the ip keeps counting, its fifth failure locks it out
+		service.RecordLoginFailure(ctx, "bob", "10.0.0.1")
+		if got := service.LoginLockedFor("carol", "10.0.0.1"); got == 0 {
+			t.Fatal("expected a success not to reset the ip's failures")
+		}
+	})
+}
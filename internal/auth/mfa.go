@@ -0,0 +1,253 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/yuchida-tamu/git-workout-api/internal/totp"
+)
+
+// MFAChallengeLifetime - how long a user has to enter their code after the password was accepted
+const MFAChallengeLifetime = 5 * time.Minute
+
+const (
+	recoveryCodeCount  = 10
+	recoveryCodeLength = 10
+	// recoveryCodeAlphabet leaves out characters which are easily confused when copied by hand
+	recoveryCodeAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+)
+
+var (
+	ErrMFANotEnrolled    = errors.New("two-factor authentication is not enrolled")
+	ErrMFAAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrInvalidMFACode    = errors.New("invalid two-factor authentication code")
+)
+
+// MFA - a user's TOTP enrollment. It only protects logins once Enabled, which happens after the first
+// code has been verified. Recovery codes are stored as hashes and removed once used.
+type MFA struct {
+	UserID             string
+	Secret             string
+	Enabled            bool
+	RecoveryCodeHashes []string
+	LastUsedStep       int64
+	DateCreated        time.Time
+}
+
+// Enrollment - what a user needs to set up their authenticator app; only ever shown once
+type Enrollment struct {
+	Secret          string
+	ProvisioningURI string
+	RecoveryCodes   []string
+}
+
+func mfaIssuer() string {
+	if issuer := os.Getenv("MFA_ISSUER"); issuer != "" {
+		return issuer
+	}
+	return "git-workout"
+}
+
+func generateRecoveryCode() (string, error) {
+	b := make([]byte, recoveryCodeLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	for i := range b {
+		b[i] = recoveryCodeAlphabet[int(b[i])%len(recoveryCodeAlphabet)]
+	}
+	return string(b), nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+}
+
+// EnrollMFA - starts (or restarts) TOTP enrollment for a user which has not enabled it yet
+func (s *Service) EnrollMFA(ctx context.Context, userID string, accountName string) (Enrollment, error) {
+	existing, err := s.Store.GetMFA(ctx, userID)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		fmt.Println(err)
+		return Enrollment{}, err
+	}
+	if err == nil && existing.Enabled {
+		return Enrollment{}, ErrMFAAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return Enrollment{}, fmt.Errorf("failed to generate totp secret: %w", err)
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return Enrollment{}, fmt.Errorf("failed to generate recovery code: %w", err)
+		}
+		codes = append(codes, code)
+		hashes = append(hashes, HashToken(code))
+	}
+
+	if err := s.Store.PutMFA(ctx, MFA{
+		UserID:             userID,
+		Secret:             secret,
+		RecoveryCodeHashes: hashes,
+		DateCreated:        time.Now().UTC(),
+	}); err != nil {
+		fmt.Println(err)
+		return Enrollment{}, err
+	}
+
+	return Enrollment{
+		Secret:          secret,
+		ProvisioningURI: totp.ProvisioningURI(mfaIssuer(), accountName, secret),
+		RecoveryCodes:   codes,
+	}, nil
+}
+
+// ConfirmMFA - enables a pending enrollment once the user proves their app produces valid codes
+func (s *Service) ConfirmMFA(ctx context.Context, userID string, code string) error {
+	mfa, err := s.Store.GetMFA(ctx, userID)
+	if errors.Is(err, ErrNotFound) {
+		return ErrMFANotEnrolled
+	}
+	if err != nil {
+		fmt.Println(err)
+		return err
+	}
+	if mfa.Enabled {
+		return ErrMFAAlreadyEnabled
+	}
+
+	step, ok := totp.Validate(mfa.Secret, code, time.Now())
+	if !ok {
+		return ErrInvalidMFACode
+	}
+
+	mfa.Enabled = true
+	mfa.LastUsedStep = step
+	if err := s.Store.PutMFA(ctx, mfa); err != nil {
+		fmt.Println(err)
+		return err
+	}
+
+	return nil
+}
+
+// IsMFAEnabled - reports whether logins of the user need a second factor
+func (s *Service) IsMFAEnabled(ctx context.Context, userID string) (bool, error) {
+	mfa, err := s.Store.GetMFA(ctx, userID)
+	if errors.Is(err, ErrNotFound) {
+		// no enrollment is the common case and not an error for the caller
+		return false, nil
+	}
+	if err != nil {
+		// failing closed: a login must not skip the second factor because it could not be looked up
+		fmt.Println(err)
+		return false, err
+	}
+
+	return mfa.Enabled, nil
+}
+
+// IssueMFAChallenge - starts the second step of a login and returns the id the challenge token carries. A
+// newer challenge replaces the user's earlier one.
+func (s *Service) IssueMFAChallenge(ctx context.Context, userID string) (string, error) {
+	return s.issueOneTimeToken(ctx, userID, PurposeMFAChallenge, MFAChallengeLifetime)
+}
+
+// CompleteMFAChallenge - the second step of a login: checks the challenge of the user with the id, then the
+// code, and redeems the challenge once the code is accepted. A mistyped code can be corrected with the same
+// challenge, but the challenge completes one login only, and a code is not used up by a challenge which is
+// not valid anymore.
+func (s *Service) CompleteMFAChallenge(ctx context.Context, userID string, ID string, code string) error {
+	return s.Store.WithTx(ctx, func(ctx context.Context) error {
+		token, err := s.Store.GetOneTimeTokenByHash(ctx, HashToken(ID))
+		if errors.Is(err, ErrNotFound) {
+			return ErrInvalidOneTimeToken
+		}
+		if err != nil {
+			return err
+		}
+		if token.Purpose != PurposeMFAChallenge || token.UserID != userID || token.Used || time.Now().After(token.ExpiresAt) {
+			return ErrInvalidOneTimeToken
+		}
+
+		if err := s.VerifyMFA(ctx, userID, code); err != nil {
+			return err
+		}
+
+		marked, err := s.Store.MarkOneTimeTokenUsed(ctx, token.ID)
+		if err != nil {
+			return err
+		}
+		if !marked {
+			// rolls back the code just used, the login it was entered for does not happen
+			return ErrInvalidOneTimeToken
+		}
+
+		return nil
+	})
+}
+
+// VerifyMFA - checks a TOTP code, or consumes a recovery code, for a user with MFA enabled.
+// A TOTP code is accepted only once so an observed code cannot be replayed.
+func (s *Service) VerifyMFA(ctx context.Context, userID string, code string) error {
+	mfa, err := s.Store.GetMFA(ctx, userID)
+	if errors.Is(err, ErrNotFound) || (err == nil && !mfa.Enabled) {
+		return ErrMFANotEnrolled
+	}
+	if err != nil {
+		return err
+	}
+
+	if step, ok := totp.Validate(mfa.Secret, code, time.Now()); ok {
+		// the store only moves the step forward, of two logins with the same code one loses here
+		marked, err := s.Store.MarkMFAStepUsed(ctx, userID, step)
+		if err != nil {
+			return err
+		}
+		if !marked {
+			return ErrInvalidMFACode
+		}
+		return nil
+	}
+
+	hash := HashToken(normalizeRecoveryCode(code))
+	for _, stored := range mfa.RecoveryCodeHashes {
+		if subtle.ConstantTimeCompare([]byte(stored), []byte(hash)) == 1 {
+			removed, err := s.Store.RemoveMFARecoveryCode(ctx, userID, stored)
+			if err != nil {
+				return err
+			}
+			if !removed {
+				return ErrInvalidMFACode
+			}
+			return nil
+		}
+	}
+
+	return ErrInvalidMFACode
+}
+
+// DisableMFA - turns two-factor authentication off after verifying a current code
+func (s *Service) DisableMFA(ctx context.Context, userID string, code string) error {
+	if err := s.VerifyMFA(ctx, userID, code); err != nil {
+		return err
+	}
+
+	if err := s.Store.DeleteMFA(ctx, userID); err != nil {
+		fmt.Println(err)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,391 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/yuchida-tamu/git-workout-api/internal/oidc"
+)
+
+const authorizationCodeLifetime = 5 * time.Minute
+
+// OAuthError - an error answered in the format of RFC 6749, section 5.2
+type OAuthError struct {
+	Code        string
+	Description string
+}
+
+func (e *OAuthError) Error() string {
+	return e.Code + ": " + e.Description
+}
+
+func oauthError(code string, description string) *OAuthError {
+	return &OAuthError{Code: code, Description: description}
+}
+
+var ErrOAuthClientNotFound = errors.New("oauth client not found")
+
+// OAuthClient - a third-party app registered by one of the users. Public clients, such as native or
+// browser apps, have no secret and rely on PKCE alone; PKCE is required from every client.
+type OAuthClient struct {
+	ID           string
+	OwnerID      string
+	Name         string
+	SecretHash   string
+	RedirectURIs []string
+	Scopes       []Scope
+	DateCreated  time.Time
+}
+
+// Confidential - reports whether the client authenticates with a secret
+func (c OAuthClient) Confidential() bool {
+	return c.SecretHash != ""
+}
+
+// AuthorizationCode - a single-use code handed to a client's redirect uri once the user consents.
+// SessionID is the session the code was exchanged for, revoked if the code is presented again.
+type AuthorizationCode struct {
+	ID            string
+	CodeHash      string
+	ClientID      string
+	UserID        string
+	RedirectURI   string
+	Scopes        []Scope
+	CodeChallenge string
+	Used          bool
+	SessionID     string
+	ExpiresAt     time.Time
+	DateCreated   time.Time
+}
+
+// Consent - the scopes a user has granted a client, so that they are not asked again for the same ones
+type Consent struct {
+	UserID      string
+	ClientID    string
+	Scopes      []Scope
+	DateCreated time.Time
+}
+
+// AuthorizationRequest - the parameters of an authorization request (RFC 6749, section 4.1.1, with RFC 7636)
+type AuthorizationRequest struct {
+	ClientID            string
+	RedirectURI         string
+	Scopes              []Scope
+	State               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+}
+
+// RegisterOAuthClient - registers an app owned by the user, returning its secret for confidential clients.
+// The secret is only stored hashed and can only be shown this once.
+func (s *Service) RegisterOAuthClient(
+	ctx context.Context,
+	ownerID string,
+	name string,
+	redirectURIs []string,
+	scopes []Scope,
+	confidential bool,
+) (string, OAuthClient, error) {
+	if strings.TrimSpace(name) == "" {
+		return "", OAuthClient{}, fmt.Errorf("%w: a client needs a name", ErrValidation)
+	}
+	if len(redirectURIs) == 0 {
+		return "", OAuthClient{}, fmt.Errorf("%w: a client needs at least one redirect uri", ErrValidation)
+	}
+	for _, redirectURI := range redirectURIs {
+		if err := validateRedirectURI(redirectURI); err != nil {
+			return "", OAuthClient{}, err
+		}
+	}
+	if len(scopes) == 0 {
+		return "", OAuthClient{}, fmt.Errorf("%w: a client needs at least one scope", ErrValidation)
+	}
+	for _, scope := range scopes {
+		if !ValidScope(scope) {
+			return "", OAuthClient{}, fmt.Errorf("%w: unknown scope: %q", ErrValidation, scope)
+		}
+	}
+
+	client := OAuthClient{
+		OwnerID:      ownerID,
+		Name:         name,
+		RedirectURIs: redirectURIs,
+		Scopes:       scopes,
+		DateCreated:  time.Now().UTC(),
+	}
+
+	var secret string
+	if confidential {
+		var err error
+		secret, err = GenerateToken()
+		if err != nil {
+			return "", OAuthClient{}, fmt.Errorf("failed to generate client secret: %w", err)
+		}
+		client.SecretHash = HashToken(secret)
+	}
+
+	client, err := s.Store.PostOAuthClient(ctx, client)
+	if err != nil {
+		fmt.Println(err)
+		return "", OAuthClient{}, err
+	}
+
+	return secret, client, nil
+}
+
+// validateRedirectURI - redirect uris must be absolute and without a fragment, and plain http is only
+// allowed to the loopback interface for native apps
+func validateRedirectURI(redirectURI string) error {
+	u, err := url.Parse(redirectURI)
+	if err != nil || u.Scheme == "" || u.Host == "" || u.Fragment != "" {
+		return fmt.Errorf("%w: not a valid redirect uri: %q", ErrValidation, redirectURI)
+	}
+
+	host := u.Hostname()
+	if u.Scheme != "https" && !(u.Scheme == "http" && (host == "localhost" || host == "127.0.0.1" || host == "::1")) {
+		return fmt.Errorf("%w: redirect uris must use https: %q", ErrValidation, redirectURI)
+	}
+
+	return nil
+}
+
+// GetOAuthClientsByOwner - lists the apps the user registered
+func (s *Service) GetOAuthClientsByOwner(ctx context.Context, ownerID string) ([]OAuthClient, error) {
+	clients, err := s.Store.GetOAuthClientsByOwner(ctx, ownerID)
+	if err != nil {
+		fmt.Println(err)
+		return []OAuthClient{}, err
+	}
+
+	return clients, nil
+}
+
+// DeleteOAuthClient - removes one of the user's apps and revokes every session granted to it
+func (s *Service) DeleteOAuthClient(ctx context.Context, ownerID string, ID string) error {
+	client, err := s.Store.GetOAuthClient(ctx, ID)
+	if err != nil {
+		fmt.Println(err)
+		return ErrOAuthClientNotFound
+	}
+	if client.OwnerID != ownerID {
+		return ErrOAuthClientNotFound
+	}
+
+	if err := s.Store.RevokeSessionsByClient(ctx, ID); err != nil {
+		fmt.Println(err)
+		return err
+	}
+
+	return s.Store.DeleteOAuthClient(ctx, ID)
+}
+
+// ValidateAuthorizationRequest - checks an authorization request before the user is asked for consent,
+// returning the client and whether the user still has to consent to the requested scopes.
+// Errors with an invalid client or redirect uri must be shown to the user rather than redirected.
+func (s *Service) ValidateAuthorizationRequest(ctx context.Context, userID string, req *AuthorizationRequest) (OAuthClient, bool, error) {
+	client, err := s.Store.GetOAuthClient(ctx, req.ClientID)
+	if err != nil {
+		fmt.Println(err)
+		return OAuthClient{}, false, oauthError("invalid_client", "unknown client")
+	}
+
+	registered := false
+	for _, redirectURI := range client.RedirectURIs {
+		if redirectURI == req.RedirectURI {
+			registered = true
+			break
+		}
+	}
+	if !registered {
+		return OAuthClient{}, false, oauthError("invalid_request", "the redirect uri is not registered")
+	}
+
+	if req.CodeChallenge == "" || req.CodeChallengeMethod != "S256" {
+		return OAuthClient{}, false, oauthError("invalid_request", "a S256 code challenge is required")
+	}
+
+	// without a scope parameter the client asks for everything it registered
+	if len(req.Scopes) == 0 {
+		req.Scopes = client.Scopes
+	}
+	if !ContainsScopes(client.Scopes, req.Scopes) {
+		return OAuthClient{}, false, oauthError("invalid_scope", "the client may not request these scopes")
+	}
+
+	consent, err := s.Store.GetConsent(ctx, userID, client.ID)
+	consentRequired := err != nil || !ContainsScopes(consent.Scopes, req.Scopes)
+
+	return client, consentRequired, nil
+}
+
+// Authorize - records the user's consent to the request and returns the code to redirect to the client with
+func (s *Service) Authorize(ctx context.Context, userID string, req AuthorizationRequest) (string, error) {
+	client, _, err := s.ValidateAuthorizationRequest(ctx, userID, &req)
+	if err != nil {
+		return "", err
+	}
+
+	// consenting to more scopes adds to what was granted before rather than replacing it
+	granted := append([]Scope{}, req.Scopes...)
+	if consent, err := s.Store.GetConsent(ctx, userID, client.ID); err == nil {
+		for _, scope := range consent.Scopes {
+			if !ContainsScopes(granted, []Scope{scope}) {
+				granted = append(granted, scope)
+			}
+		}
+	}
+
+	now := time.Now().UTC()
+	if err := s.Store.PutConsent(ctx, Consent{
+		UserID:      userID,
+		ClientID:    client.ID,
+		Scopes:      granted,
+		DateCreated: now,
+	}); err != nil {
+		fmt.Println(err)
+		return "", err
+	}
+
+	raw, err := GenerateToken()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate authorization code: %w", err)
+	}
+
+	if _, err := s.Store.PostAuthorizationCode(ctx, AuthorizationCode{
+		CodeHash:      HashToken(raw),
+		ClientID:      client.ID,
+		UserID:        userID,
+		RedirectURI:   req.RedirectURI,
+		Scopes:        req.Scopes,
+		CodeChallenge: req.CodeChallenge,
+		ExpiresAt:     now.Add(authorizationCodeLifetime),
+		DateCreated:   now,
+	}); err != nil {
+		fmt.Println(err)
+		return "", err
+	}
+
+	return raw, nil
+}
+
+// AuthenticateOAuthClient - checks a client's credentials; public clients only present their id
+func (s *Service) AuthenticateOAuthClient(ctx context.Context, clientID string, secret string) (OAuthClient, error) {
+	client, err := s.Store.GetOAuthClient(ctx, clientID)
+	if err != nil {
+		fmt.Println(err)
+		return OAuthClient{}, oauthError("invalid_client", "client authentication failed")
+	}
+
+	if client.Confidential() && subtle.ConstantTimeCompare([]byte(HashToken(secret)), []byte(client.SecretHash)) != 1 {
+		return OAuthClient{}, oauthError("invalid_client", "client authentication failed")
+	}
+
+	return client, nil
+}
+
+// ExchangeAuthorizationCode - redeems a code for a new session granted to the client, returning the session
+// and its refresh token. A code presented a second time revokes the session it was first exchanged for.
+func (s *Service) ExchangeAuthorizationCode(
+	ctx context.Context,
+	client OAuthClient,
+	raw string,
+	redirectURI string,
+	verifier string,
+	info ClientInfo,
+) (Session, string, error) {
+	code, err := s.Store.GetAuthorizationCodeByHash(ctx, HashToken(raw))
+	if err != nil {
+		fmt.Println(err)
+		return Session{}, "", oauthError("invalid_grant", "invalid authorization code")
+	}
+
+	if code.ClientID != client.ID || code.RedirectURI != redirectURI || time.Now().After(code.ExpiresAt) {
+		return Session{}, "", oauthError("invalid_grant", "invalid authorization code")
+	}
+	if oidc.CodeChallenge(verifier) != code.CodeChallenge {
+		return Session{}, "", oauthError("invalid_grant", "the code verifier does not match")
+	}
+
+	marked, err := s.Store.MarkAuthorizationCodeUsed(ctx, code.ID)
+	if err != nil {
+		fmt.Println(err)
+		return Session{}, "", err
+	}
+	if code.Used || !marked {
+		if code.SessionID != "" {
+			if err := s.revokeSession(ctx, code.SessionID); err != nil {
+				return Session{}, "", err
+			}
+		}
+		return Session{}, "", oauthError("invalid_grant", "invalid authorization code")
+	}
+
+	u, err := s.Store.GetUser(ctx, code.UserID)
+	if err != nil || u.Disabled {
+		return Session{}, "", oauthError("invalid_grant", "invalid authorization code")
+	}
+
+	info.Device = client.Name
+	session, refreshToken, _, err := s.startSession(ctx, Session{
+		UserID:   code.UserID,
+		ClientID: client.ID,
+		Scopes:   code.Scopes,
+	}, info)
+	if err != nil {
+		return Session{}, "", err
+	}
+
+	if err := s.Store.SetAuthorizationCodeSession(ctx, code.ID, session.ID); err != nil {
+		fmt.Println(err)
+		return Session{}, "", err
+	}
+
+	return session, refreshToken, nil
+}
+
+// RefreshOAuthToken - rotates a refresh token the client was granted, like RotateRefreshToken does for
+// this API's own clients
+func (s *Service) RefreshOAuthToken(ctx context.Context, client OAuthClient, raw string, info ClientInfo) (Session, string, error) {
+	info.Device = client.Name
+	session, refreshToken, _, err := s.rotate(ctx, raw, info, client.ID)
+	if err != nil {
+		return Session{}, "", oauthError("invalid_grant", "invalid refresh token")
+	}
+
+	return session, refreshToken, nil
+}
+
+// GetOAuthTokenSession - finds the session of a token the client holds, for introspection and revocation.
+// The token is either a session id taken from a verified access token or a raw refresh token; tokens
+// of other clients are reported as not found.
+func (s *Service) GetOAuthTokenSession(ctx context.Context, client OAuthClient, sessionID string, rawRefreshToken string) (Session, error) {
+	if rawRefreshToken != "" {
+		token, err := s.Store.GetRefreshTokenByHash(ctx, HashToken(rawRefreshToken))
+		if err != nil || token.Used || token.Revoked || time.Now().After(token.ExpiresAt) {
+			return Session{}, ErrSessionNotFound
+		}
+		sessionID = token.FamilyID
+	}
+
+	session, err := s.Store.GetSession(ctx, sessionID)
+	if err != nil || session.Revoked || session.ClientID != client.ID {
+		return Session{}, ErrSessionNotFound
+	}
+
+	return session, nil
+}
+
+// RevokeOAuthSession - ends a session the client was granted, as RFC 7009 revocation does
+func (s *Service) RevokeOAuthSession(ctx context.Context, client OAuthClient, sessionID string) error {
+	session, err := s.Store.GetSession(ctx, sessionID)
+	if err != nil || session.ClientID != client.ID {
+		return ErrSessionNotFound
+	}
+
+	return s.revokeSession(ctx, sessionID)
+}
@@ -0,0 +1,293 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/yuchida-tamu/git-workout-api/internal/oidc"
+	"github.com/yuchida-tamu/git-workout-api/internal/user"
+)
+
+// OIDCLoginLifetime - how long a login started with a provider can be completed
+const OIDCLoginLifetime = 10 * time.Minute
+
+var (
+	ErrUnknownProvider     = errors.New("unknown identity provider")
+	ErrInvalidOIDCLogin    = errors.New("invalid or expired login")
+	ErrIdentityLinked      = errors.New("this identity is linked to another user")
+	ErrIdentityNotFound    = errors.New("identity not found")
+	usernameDisallowedChar = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+)
+
+// OIDCLogin - a login started with an external provider and waiting for its callback. The state sent to
+// the provider is only stored hashed; the PKCE verifier and nonce never leave the server.
+// LinkUserID is set when a logged in user is adding the provider to their account.
+type OIDCLogin struct {
+	ID          string
+	StateHash   string
+	Provider    string
+	Verifier    string
+	Nonce       string
+	LinkUserID  string
+	ExpiresAt   time.Time
+	DateCreated time.Time
+}
+
+// Identity - an account at an external provider linked to a user
+type Identity struct {
+	ID          string
+	UserID      string
+	Provider    string
+	Subject     string
+	Email       string
+	DateCreated time.Time
+}
+
+// ProviderNames - the configured providers, sorted
+func (s *Service) ProviderNames() []string {
+	names := make([]string, 0, len(s.Providers))
+	for name := range s.Providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// BeginOIDCLogin - starts a login with the provider and returns the url to send the user to, along with the
+// binding the callback has to present: the hash of the state, which the browser starting the login keeps, so
+// that nobody can have a login they started completed in someone else's browser.
+// With a linkUserID the provider's identity is linked to that user instead of logging in as whoever it belongs to.
+func (s *Service) BeginOIDCLogin(ctx context.Context, providerName string, linkUserID string) (string, string, error) {
+	provider, ok := s.Providers[providerName]
+	if !ok {
+		return "", "", ErrUnknownProvider
+	}
+
+	state, err := GenerateToken()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate state: %w", err)
+	}
+	nonce, err := GenerateToken()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	verifier, err := oidc.NewVerifier()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate code verifier: %w", err)
+	}
+
+	now := time.Now().UTC()
+	if _, err := s.Store.PostOIDCLogin(ctx, OIDCLogin{
+		StateHash:   HashToken(state),
+		Provider:    providerName,
+		Verifier:    verifier,
+		Nonce:       nonce,
+		LinkUserID:  linkUserID,
+		ExpiresAt:   now.Add(OIDCLoginLifetime),
+		DateCreated: now,
+	}); err != nil {
+		fmt.Println(err)
+		return "", "", err
+	}
+
+	return provider.AuthCodeURL(state, nonce, verifier), HashToken(state), nil
+}
+
+// CompleteOIDCLogin - finishes a login from the provider's callback and returns the user it signs in as.
+// An identity seen for the first time is linked to the user who started a link, else to the user with the
+// same email when both the provider and this API have verified it, and otherwise gets a new user.
+// binding is what BeginOIDCLogin returned to the browser which started the login.
+func (s *Service) CompleteOIDCLogin(ctx context.Context, providerName string, code string, state string, binding string) (user.User, error) {
+	provider, ok := s.Providers[providerName]
+	if !ok {
+		return user.User{}, ErrUnknownProvider
+	}
+	if binding == "" || subtle.ConstantTimeCompare([]byte(binding), []byte(HashToken(state))) != 1 {
+		return user.User{}, ErrInvalidOIDCLogin
+	}
+
+	login, err := s.Store.GetOIDCLoginByStateHash(ctx, HashToken(state))
+	if err != nil {
+		fmt.Println(err)
+		return user.User{}, ErrInvalidOIDCLogin
+	}
+	// the login is deleted whether or not the exchange succeeds, so a state can only be used once
+	deleted, err := s.Store.DeleteOIDCLogin(ctx, login.ID)
+	if err != nil {
+		fmt.Println(err)
+		return user.User{}, err
+	}
+	if !deleted || login.Provider != providerName || time.Now().After(login.ExpiresAt) {
+		return user.User{}, ErrInvalidOIDCLogin
+	}
+
+	tokens, err := provider.Exchange(ctx, code, login.Verifier)
+	if err != nil {
+		fmt.Println(err)
+		return user.User{}, ErrInvalidOIDCLogin
+	}
+	external, err := provider.Identify(ctx, tokens, login.Nonce)
+	if err != nil {
+		fmt.Println(err)
+		return user.User{}, ErrInvalidOIDCLogin
+	}
+
+	u, err := s.resolveIdentity(ctx, providerName, external, login.LinkUserID)
+	if err != nil {
+		return user.User{}, err
+	}
+	if u.Disabled {
+		return user.User{}, errors.New("user is disabled")
+	}
+
+	return u, nil
+}
+
+func (s *Service) resolveIdentity(ctx context.Context, providerName string, external oidc.Identity, linkUserID string) (user.User, error) {
+	identity, err := s.Store.GetIdentity(ctx, providerName, external.Subject)
+	if err == nil {
+		if linkUserID != "" && identity.UserID != linkUserID {
+			return user.User{}, ErrIdentityLinked
+		}
+		return s.Store.GetUser(ctx, identity.UserID)
+	}
+	// only an identity which is certainly not linked yet may get a user, any other failure ends the login
+	if !errors.Is(err, ErrNotFound) {
+		fmt.Println(err)
+		return user.User{}, err
+	}
+
+	var u user.User
+	switch {
+	case linkUserID != "":
+		u, err = s.Store.GetUser(ctx, linkUserID)
+	case external.Email != "" && external.EmailVerified:
+		u, err = s.Store.GetUserByEmail(ctx, external.Email)
+		if errors.Is(err, user.ErrNotFound) || (err == nil && !u.EmailVerified) {
+			u, err = s.createUserForIdentity(ctx, providerName, external)
+		}
+	default:
+		u, err = s.createUserForIdentity(ctx, providerName, external)
+	}
+	if err != nil {
+		fmt.Println(err)
+		return user.User{}, err
+	}
+
+	if _, err := s.Store.PostIdentity(ctx, Identity{
+		UserID:      u.ID,
+		Provider:    providerName,
+		Subject:     external.Subject,
+		Email:       external.Email,
+		DateCreated: time.Now().UTC(),
+	}); err != nil {
+		fmt.Println(err)
+		return user.User{}, err
+	}
+
+	return u, nil
+}
+
+// createUserForIdentity - registers a user signing in with a provider for the first time. The password is
+// random and never shown, so the account is only reachable through the provider or a password reset.
+func (s *Service) createUserForIdentity(ctx context.Context, providerName string, external oidc.Identity) (user.User, error) {
+	username, err := s.freeUsername(ctx, providerName, external)
+	if err != nil {
+		return user.User{}, err
+	}
+
+	randomPassword, err := GenerateToken()
+	if err != nil {
+		return user.User{}, err
+	}
+	hash, err := s.Hasher.Hash(randomPassword)
+	if err != nil {
+		return user.User{}, err
+	}
+
+	u := user.User{
+		Username: username,
+		Password: hash,
+		Role:     user.RoleUser,
+	}
+	// take the email only when it is verified and no other user has it, as emails are unique
+	if external.Email != "" && external.EmailVerified {
+		_, err := s.Store.GetUserByEmail(ctx, external.Email)
+		if err != nil && !errors.Is(err, user.ErrNotFound) {
+			return user.User{}, err
+		}
+		if err != nil {
+			u.Email = external.Email
+			u.EmailVerified = true
+		}
+	}
+
+	return s.Store.PostUser(ctx, u)
+}
+
+// freeUsername - derives a username from the provider's, adding a random suffix until it is not taken
+func (s *Service) freeUsername(ctx context.Context, providerName string, external oidc.Identity) (string, error) {
+	base := external.Username
+	if base == "" && external.Email != "" {
+		base = strings.SplitN(external.Email, "@", 2)[0]
+	}
+	base = usernameDisallowedChar.ReplaceAllString(base, "")
+	if base == "" {
+		base = providerName
+	}
+	if len(base) > 32 {
+		base = base[:32]
+	}
+
+	candidate := base
+	for i := 0; i < 5; i++ {
+		_, err := s.Store.GetUserByUsername(ctx, candidate)
+		if errors.Is(err, user.ErrNotFound) {
+			return candidate, nil
+		}
+		if err != nil {
+			return "", err
+		}
+		suffix, err := GenerateToken()
+		if err != nil {
+			return "", err
+		}
+		candidate = base + "-" + strings.ToLower(usernameDisallowedChar.ReplaceAllString(suffix, ""))[:6]
+	}
+
+	return "", errors.New("could not find a free username")
+}
+
+// GetIdentitiesByUser - lists the providers linked to the user
+func (s *Service) GetIdentitiesByUser(ctx context.Context, userID string) ([]Identity, error) {
+	identities, err := s.Store.GetIdentitiesByUser(ctx, userID)
+	if err != nil {
+		fmt.Println(err)
+		return []Identity{}, err
+	}
+
+	return identities, nil
+}
+
+// UnlinkIdentity - removes one of the user's linked providers
+func (s *Service) UnlinkIdentity(ctx context.Context, userID string, ID string) error {
+	identities, err := s.Store.GetIdentitiesByUser(ctx, userID)
+	if err != nil {
+		fmt.Println(err)
+		return err
+	}
+
+	for _, identity := range identities {
+		if identity.ID == ID {
+			return s.Store.DeleteIdentity(ctx, ID)
+		}
+	}
+
+	return ErrIdentityNotFound
+}
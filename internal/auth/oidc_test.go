@@ -0,0 +1,269 @@
+package auth_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/yuchida-tamu/git-workout-api/internal/auth"
+	"github.com/yuchida-tamu/git-workout-api/internal/mailer"
+	"github.com/yuchida-tamu/git-workout-api/internal/memstore"
+	"github.com/yuchida-tamu/git-workout-api/internal/oidc"
+	"github.com/yuchida-tamu/git-workout-api/internal/oidc/oidctest"
+	"github.com/yuchida-tamu/git-workout-api/internal/password"
+	"github.com/yuchida-tamu/git-workout-api/internal/user"
+)
+
+// failingIdentities - a store whose identity lookups fail with something other than not found
+type failingIdentities struct {
+	*memstore.Store
+}
+
+func (s failingIdentities) GetIdentity(ctx context.Context, provider string, subject string) (auth.Identity, error) {
+	return auth.Identity{}, errors.New("connection reset")
+}
+
+// failingEmails - a store whose email lookups fail with something other than not found, once the first
+// lookups have gone through
+type failingEmails struct {
+	*memstore.Store
+	lookupsLeft int
+}
+
+func (s *failingEmails) GetUserByEmail(ctx context.Context, email string) (user.User, error) {
+	if s.lookupsLeft == 0 {
+		return user.User{}, errors.New("connection reset")
+	}
+	s.lookupsLeft--
+	return s.Store.GetUserByEmail(ctx, email)
+}
+
+func newOIDCService(t *testing.T, store auth.Store) (*auth.Service, *oidctest.Provider) {
+	t.Helper()
+
+	mock := oidctest.NewProvider(t)
+	provider := mock.Config("mock")
+	if err := provider.Discover(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// the lowest bcrypt cost, the random passwords of new users are never used to log in
+	hasher := password.DefaultHasher()
+	hasher.BcryptCost = 4
+
+	return auth.NewService(
+		store,
+		mailer.NewLogMailer(""),
+		password.DefaultPolicy(),
+		hasher,
+		map[string]*oidc.Provider{"mock": provider},
+	), mock
+}
+
+// signIn - goes through a login with the provider as identity, from the browser which started it
+func signIn(t *testing.T, service *auth.Service, mock *oidctest.Provider, identity oidc.Identity, linkUserID string) (user.User, error) {
+	t.Helper()
+
+	authURL, binding, err := service.BeginOIDCLogin(context.Background(), "mock", linkUserID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, state := mock.Authorize(t, authURL, identity)
+
+	return service.CompleteOIDCLogin(context.Background(), "mock", code, state, binding)
+}
+
+func TestCompleteOIDCLogin(t *testing.T) {
+	ctx := context.Background()
+	alice := oidc.Identity{Subject: "alice-subject", Email: "alice@example.com", EmailVerified: true, Username: "alice"}
+
+	t.Run("NewUser", func(t *testing.T) {
+		service, mock := newOIDCService(t, memstore.NewStore())
+
+		u, err := signIn(t, service, mock, alice, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u.Username != "alice" || u.Email != alice.Email || !u.EmailVerified {
+			t.Fatalf("unexpected new user: %+v", u)
+		}
+
+		again, err := signIn(t, service, mock, alice, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if again.ID != u.ID {
+			t.Fatalf("expected the second login to be the same user %s, got %s", u.ID, again.ID)
+		}
+	})
+
+	t.Run("Binding", func(t *testing.T) {
+		service, mock := newOIDCService(t, memstore.NewStore())
+
+		for name, binding := range map[string]func(string) string{
+			"Missing": func(string) string { return "" },
+			"Other":   func(string) string { return auth.HashToken("another state") },
+		} {
+			t.Run(name, func(t *testing.T) {
+				authURL, own, err := service.BeginOIDCLogin(ctx, "mock", "")
+				if err != nil {
+					t.Fatal(err)
+				}
+				code, state := mock.Authorize(t, authURL, alice)
+
+				if _, err := service.CompleteOIDCLogin(ctx, "mock", code, state, binding(own)); !errors.Is(err, auth.ErrInvalidOIDCLogin) {
+					t.Fatalf("expected a callback without the browser's binding to be refused, got %v", err)
+				}
+			})
+		}
+	})
+
+	t.Run("StateReuse", func(t *testing.T) {
+		service, mock := newOIDCService(t, memstore.NewStore())
+
+		authURL, binding, err := service.BeginOIDCLogin(ctx, "mock", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		code, state := mock.Authorize(t, authURL, alice)
+		if _, err := service.CompleteOIDCLogin(ctx, "mock", code, state, binding); err != nil {
+			t.Fatal(err)
+		}
+
+		// the provider would refuse the code as well, a fresh one shows it is the state being refused
+		code, _ = mock.Authorize(t, authURL, alice)
+		if _, err := service.CompleteOIDCLogin(ctx, "mock", code, state, binding); !errors.Is(err, auth.ErrInvalidOIDCLogin) {
+			t.Fatalf("expected a used state to be refused, got %v", err)
+		}
+	})
+
+	t.Run("UnknownState", func(t *testing.T) {
+		service, mock := newOIDCService(t, memstore.NewStore())
+
+		authURL, _, err := service.BeginOIDCLogin(ctx, "mock", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		code, _ := mock.Authorize(t, authURL, alice)
+		if _, err := service.CompleteOIDCLogin(ctx, "mock", code, "forged", auth.HashToken("forged")); !errors.Is(err, auth.ErrInvalidOIDCLogin) {
+			t.Fatalf("expected a state which was never issued to be refused, got %v", err)
+		}
+	})
+
+	t.Run("Link", func(t *testing.T) {
+		store := memstore.NewStore()
+		service, mock := newOIDCService(t, store)
+		bob, err := store.PostUser(ctx, user.User{Username: "bob", Email: "bob@example.com"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		u, err := signIn(t, service, mock, alice, bob.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u.ID != bob.ID {
+			t.Fatalf("expected the identity to be linked to %s, got %s", bob.ID, u.ID)
+		}
+		identities, err := service.GetIdentitiesByUser(ctx, bob.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(identities) != 1 || identities[0].Subject != alice.Subject {
+			t.Fatalf("unexpected identities: %+v", identities)
+		}
+
+		// the linked identity now logs in as bob
+		u, err = signIn(t, service, mock, alice, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u.ID != bob.ID {
+			t.Fatalf("expected the linked identity to log in as %s, got %s", bob.ID, u.ID)
+		}
+	})
+
+	t.Run("LinkedToAnotherUser", func(t *testing.T) {
+		store := memstore.NewStore()
+		service, mock := newOIDCService(t, store)
+		if _, err := signIn(t, service, mock, alice, ""); err != nil {
+			t.Fatal(err)
+		}
+		bob, err := store.PostUser(ctx, user.User{Username: "bob", Email: "bob@example.com"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := signIn(t, service, mock, alice, bob.ID); !errors.Is(err, auth.ErrIdentityLinked) {
+			t.Fatalf("expected linking an identity of another user to fail, got %v", err)
+		}
+	})
+
+	t.Run("VerifiedEmail", func(t *testing.T) {
+		store := memstore.NewStore()
+		service, mock := newOIDCService(t, store)
+		existing, err := store.PostUser(ctx, user.User{Username: "alice-local", Email: alice.Email, EmailVerified: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		u, err := signIn(t, service, mock, alice, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u.ID != existing.ID {
+			t.Fatalf("expected the identity to be linked to the user with the verified email %s, got %s", existing.ID, u.ID)
+		}
+	})
+
+	t.Run("UnverifiedEmail", func(t *testing.T) {
+		store := memstore.NewStore()
+		service, mock := newOIDCService(t, store)
+		existing, err := store.PostUser(ctx, user.User{Username: "alice-local", Email: alice.Email})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		u, err := signIn(t, service, mock, alice, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u.ID == existing.ID {
+			t.Fatal("an email the user never verified must not let the provider take over their account")
+		}
+	})
+
+	t.Run("EmailLookupFails", func(t *testing.T) {
+		store := memstore.NewStore()
+		// the login finds no user with the email, but creating one cannot check it is still free
+		service, mock := newOIDCService(t, &failingEmails{Store: store, lookupsLeft: 1})
+
+		// the email may well belong to someone by now, the new user must not be given it on a guess
+		if _, err := signIn(t, service, mock, alice, ""); err == nil {
+			t.Fatal("expected the login to fail when the email cannot be looked up")
+		}
+		users, err := store.GetUsers(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(users) != 0 {
+			t.Fatalf("expected no user to be created, got %+v", users)
+		}
+	})
+
+	t.Run("IdentityLookupFails", func(t *testing.T) {
+		store := memstore.NewStore()
+		service, mock := newOIDCService(t, failingIdentities{store})
+
+		if _, err := signIn(t, service, mock, alice, ""); err == nil {
+			t.Fatal("expected the login to fail when the identity cannot be looked up")
+		}
+		users, err := store.GetUsers(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(users) != 0 {
+			t.Fatalf("expected no user to be created, got %+v", users)
+		}
+	})
+}
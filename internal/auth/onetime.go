@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Purpose - what a one-time token may be redeemed for; a token is only valid for its own purpose
+type Purpose string
+
+const (
+	PurposePasswordReset     Purpose = "password_reset"
+	PurposeEmailVerification Purpose = "email_verification"
+	PurposeMFAChallenge      Purpose = "mfa_challenge"
+)
+
+var ErrInvalidOneTimeToken = errors.New("invalid or expired token")
+
+// OneTimeToken - a single-use, expiring token sent to a user out of band; only its hash is stored
+type OneTimeToken struct {
+	ID          string
+	UserID      string
+	Purpose     Purpose
+	TokenHash   string
+	Used        bool
+	ExpiresAt   time.Time
+	DateCreated time.Time
+}
+
+// issueOneTimeToken - creates a token for the purpose, invalidating any earlier one the user still holds
+func (s *Service) issueOneTimeToken(ctx context.Context, userID string, purpose Purpose, lifetime time.Duration) (string, error) {
+	if err := s.Store.DeleteOneTimeTokensByUser(ctx, userID, purpose); err != nil {
+		fmt.Println(err)
+		return "", err
+	}
+
+	raw, err := GenerateToken()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	now := time.Now().UTC()
+	if _, err := s.Store.PostOneTimeToken(ctx, OneTimeToken{
+		UserID:      userID,
+		Purpose:     purpose,
+		TokenHash:   HashToken(raw),
+		ExpiresAt:   now.Add(lifetime),
+		DateCreated: now,
+	}); err != nil {
+		fmt.Println(err)
+		return "", err
+	}
+
+	return raw, nil
+}
+
+// redeemOneTimeToken - consumes a token of the purpose and returns it; a token can only be redeemed once
+func (s *Service) redeemOneTimeToken(ctx context.Context, raw string, purpose Purpose) (OneTimeToken, error) {
+	token, err := s.Store.GetOneTimeTokenByHash(ctx, HashToken(raw))
+	if err != nil {
+		fmt.Println(err)
+		return OneTimeToken{}, ErrInvalidOneTimeToken
+	}
+
+	if token.Purpose != purpose || token.Used || time.Now().After(token.ExpiresAt) {
+		return OneTimeToken{}, ErrInvalidOneTimeToken
+	}
+
+	marked, err := s.Store.MarkOneTimeTokenUsed(ctx, token.ID)
+	if err != nil {
+		fmt.Println(err)
+		return OneTimeToken{}, err
+	}
+	if !marked {
+		return OneTimeToken{}, ErrInvalidOneTimeToken
+	}
+
+	return token, nil
+}
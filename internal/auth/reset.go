@@ -0,0 +1,92 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/yuchida-tamu/git-workout-api/internal/mailer"
+)
+
+const passwordResetLifetime = time.Hour
+
+func passwordResetMessage(to string, token string) mailer.Message {
+	body := "Someone asked to reset the password of your git-workout account.\n\n"
+	if base := os.Getenv("PASSWORD_RESET_URL"); base != "" {
+		body += "Open this link to choose a new password:\n" + base + token + "\n\n"
+	} else {
+		body += "Use this code to choose a new password:\n" + token + "\n\n"
+	}
+	body += fmt.Sprintf("It expires in %d minutes. If this was not you, you can ignore this email.\n", int(passwordResetLifetime.Minutes()))
+
+	return mailer.Message{
+		To:      to,
+		Subject: "Reset your git-workout password",
+		Body:    body,
+	}
+}
+
+// RequestPasswordReset - mails a reset token to the user. Neither unknown usernames nor failures to send the
+// token are reported to the caller, they are only logged, so the endpoint cannot be used to find out which
+// accounts exist.
+func (s *Service) RequestPasswordReset(ctx context.Context, username string) error {
+	u, err := s.Store.GetUserByUsername(ctx, username)
+	if err != nil {
+		fmt.Println(err)
+		return nil
+	}
+	// without an address there is nobody to send the token to
+	if u.Disabled || u.Email == "" {
+		return nil
+	}
+
+	token, err := s.issueOneTimeToken(ctx, u.ID, PurposePasswordReset, passwordResetLifetime)
+	if err != nil {
+		fmt.Println(err)
+		return nil
+	}
+
+	if err := s.Mailer.Send(ctx, passwordResetMessage(u.Email, token)); err != nil {
+		fmt.Println(err)
+		return nil
+	}
+
+	return nil
+}
+
+// ResetPassword - sets a new password with a reset token and signs the user out everywhere.
+// The token is only consumed once the new password meets the policy, so the user can try again.
+func (s *Service) ResetPassword(ctx context.Context, raw string, password string) error {
+	pending, err := s.Store.GetOneTimeTokenByHash(ctx, HashToken(raw))
+	if err != nil {
+		fmt.Println(err)
+		return ErrInvalidOneTimeToken
+	}
+	u, err := s.Store.GetUser(ctx, pending.UserID)
+	if err != nil {
+		fmt.Println(err)
+		return ErrInvalidOneTimeToken
+	}
+	if err := s.PasswordPolicy.Validate(password, u.Username, u.Email); err != nil {
+		return err
+	}
+
+	hash, err := s.Hasher.Hash(password)
+	if err != nil {
+		fmt.Println(err)
+		return err
+	}
+
+	token, err := s.redeemOneTimeToken(ctx, raw, PurposePasswordReset)
+	if err != nil {
+		return err
+	}
+
+	if err := s.Store.UpdateUserPassword(ctx, token.UserID, hash); err != nil {
+		fmt.Println(err)
+		return err
+	}
+
+	return s.RevokeSessionsByUser(ctx, token.UserID)
+}
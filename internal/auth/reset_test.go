@@ -0,0 +1,37 @@
+package auth_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/yuchida-tamu/git-workout-api/internal/auth"
+	"github.com/yuchida-tamu/git-workout-api/internal/mailer"
+	"github.com/yuchida-tamu/git-workout-api/internal/memstore"
+	"github.com/yuchida-tamu/git-workout-api/internal/password"
+	"github.com/yuchida-tamu/git-workout-api/internal/user"
+)
+
+// failingMailer - a mailer whose server is down
+type failingMailer struct{}
+
+func (failingMailer) Send(ctx context.Context, msg mailer.Message) error {
+	return errors.New("connection refused")
+}
+
+func TestRequestPasswordReset(t *testing.T) {
+	ctx := context.Background()
+	store := memstore.NewStore()
+	service := auth.NewService(store, failingMailer{}, password.DefaultPolicy(), password.DefaultHasher(), nil)
+
+	if _, err := store.PostUser(ctx, user.User{Username: "alice", Email: "alice@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// an account which exists must look the same as one which does not, even when the mail cannot be sent
+	for _, username := range []string{"alice", "nobody"} {
+		if err := service.RequestPasswordReset(ctx, username); err != nil {
+			t.Fatalf("expected no error for %q, got %v", username, err)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package auth
+
+import "strings"
+
+// Scope - an action a credential is allowed to perform. Access tokens carry their scopes in the scope claim
+// and API keys store theirs; routes declare the scope they need in mapRoutes.
+type Scope string
+
+const (
+	// ScopeRecordsRead - reading workout records and their comments
+	ScopeRecordsRead Scope = "records:read"
+	// ScopeRecordsWrite - creating, changing and deleting records, and commenting on them
+	ScopeRecordsWrite Scope = "records:write"
+	// ScopeProfileRead - reading the user's profile, coaches and athletes
+	ScopeProfileRead Scope = "profile:read"
+	// ScopeProfileWrite - managing the user's coaching relationships
+	ScopeProfileWrite Scope = "profile:write"
+)
+
+// AllScopes - every scope, in the order they are documented
+var AllScopes = []Scope{
+	ScopeRecordsRead,
+	ScopeRecordsWrite,
+	ScopeProfileRead,
+	ScopeProfileWrite,
+}
+
+// ValidScope - reports whether the scope is one of AllScopes
+func ValidScope(scope Scope) bool {
+	for _, s := range AllScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseScopes - reads a space separated scope parameter, as OAuth2 sends scopes
+func ParseScopes(s string) []Scope {
+	scopes := []Scope{}
+	for _, field := range strings.Fields(s) {
+		scopes = append(scopes, Scope(field))
+	}
+	return scopes
+}
+
+// FormatScopes - writes scopes as a space separated scope parameter
+func FormatScopes(scopes []Scope) string {
+	parts := make([]string, 0, len(scopes))
+	for _, s := range scopes {
+		parts = append(parts, string(s))
+	}
+	return strings.Join(parts, " ")
+}
+
+// ContainsScopes - reports whether every scope in wanted is in granted
+func ContainsScopes(granted []Scope, wanted []Scope) bool {
+	for _, w := range wanted {
+		found := false
+		for _, g := range granted {
+			if g == w {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
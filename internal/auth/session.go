@@ -0,0 +1,114 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrSessionNotFound = errors.New("session not found")
+
+// Session - a single login on a device. Its ID is the family ID shared by the refresh tokens
+// rotated from that login, and access tokens carry it in their sid claim.
+// Sessions a user granted to a third-party app carry the app's ClientID and the Scopes it was granted.
+type Session struct {
+	ID          string
+	UserID      string
+	ClientID    string
+	Scopes      []Scope
+	Device      string
+	IP          string
+	UserAgent   string
+	Revoked     bool
+	LastUsed    time.Time
+	DateCreated time.Time
+}
+
+// ClientInfo - what is known about the client starting or using a session
+type ClientInfo struct {
+	Device    string
+	IP        string
+	UserAgent string
+}
+
+// IsSessionActive - reports whether the session exists and has not been revoked
+func (s *Service) IsSessionActive(ctx context.Context, ID string) (bool, error) {
+	session, err := s.Store.GetSession(ctx, ID)
+	if err != nil {
+		fmt.Println(err)
+		return false, err
+	}
+
+	return !session.Revoked, nil
+}
+
+// GetSessionsByUser - lists the user's sessions which have not been revoked
+func (s *Service) GetSessionsByUser(ctx context.Context, userID string) ([]Session, error) {
+	sessions, err := s.Store.GetSessionsByUser(ctx, userID)
+	if err != nil {
+		fmt.Println(err)
+		return []Session{}, err
+	}
+
+	active := []Session{}
+	for _, session := range sessions {
+		if !session.Revoked {
+			active = append(active, session)
+		}
+	}
+
+	return active, nil
+}
+
+// RevokeSession - ends one of the user's sessions, invalidating its refresh tokens and access tokens
+func (s *Service) RevokeSession(ctx context.Context, userID string, ID string) error {
+	session, err := s.Store.GetSession(ctx, ID)
+	if errors.Is(err, ErrNotFound) {
+		return ErrSessionNotFound
+	}
+	if err != nil {
+		fmt.Println(err)
+		return err
+	}
+
+	// do not reveal that sessions of other users exist
+	if session.UserID != userID {
+		return ErrSessionNotFound
+	}
+
+	return s.revokeSession(ctx, ID)
+}
+
+// RevokeSessionsByUser - ends every session of the user, logging them out everywhere
+func (s *Service) RevokeSessionsByUser(ctx context.Context, userID string) error {
+	sessions, err := s.Store.GetSessionsByUser(ctx, userID)
+	if err != nil {
+		fmt.Println(err)
+		return err
+	}
+	for _, session := range sessions {
+		if session.Revoked {
+			continue
+		}
+		if err := s.revokeSession(ctx, session.ID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *Service) revokeSession(ctx context.Context, ID string) error {
+	if err := s.Store.RevokeSession(ctx, ID); err != nil {
+		fmt.Println(err)
+		return err
+	}
+
+	if err := s.Store.RevokeRefreshTokenFamily(ctx, ID); err != nil {
+		fmt.Println(err)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,108 @@
+package auth
+
+import (
+	"sync"
+	"time"
+)
+
+// sweepEvery is how many recorded failures pass between removing idle entries
+const sweepEvery = 1000
+
+type attemptState struct {
+	failures    int
+	lastFailure time.Time
+	lockedUntil time.Time
+}
+
+// Throttle - counts failed attempts per key in memory and locks a key out for an exponentially growing
+// time once it exceeds its free attempts. A key is forgotten after ResetAfter without failures.
+type Throttle struct {
+	FreeAttempts int
+	BaseDelay    time.Duration
+	MaxLockout   time.Duration
+	ResetAfter   time.Duration
+
+	mu       sync.Mutex
+	attempts map[string]*attemptState
+	failures int
+	now      func() time.Time
+}
+
+func NewThrottle(freeAttempts int, baseDelay time.Duration, maxLockout time.Duration, resetAfter time.Duration) *Throttle {
+	return &Throttle{
+		FreeAttempts: freeAttempts,
+		BaseDelay:    baseDelay,
+		MaxLockout:   maxLockout,
+		ResetAfter:   resetAfter,
+		attempts:     map[string]*attemptState{},
+		now:          time.Now,
+	}
+}
+
+// LockedFor - how long the key is still locked out, zero when it may try again
+func (t *Throttle) LockedFor(key string) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	state, ok := t.attempts[key]
+	if !ok {
+		return 0
+	}
+
+	if remaining := state.lockedUntil.Sub(t.now()); remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
+// Failure - records a failed attempt and returns the lockout it starts, zero while attempts are still free
+func (t *Throttle) Failure(key string) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	t.failures++
+	if t.failures%sweepEvery == 0 {
+		t.sweep(now)
+	}
+
+	state, ok := t.attempts[key]
+	if !ok || now.Sub(state.lastFailure) > t.ResetAfter {
+		state = &attemptState{}
+		t.attempts[key] = state
+	}
+	state.failures++
+	state.lastFailure = now
+
+	over := state.failures - t.FreeAttempts
+	if over <= 0 {
+		return 0
+	}
+
+	lockout := t.MaxLockout
+	// stop doubling well before the shift could overflow
+	if over < 32 {
+		if delay := t.BaseDelay << (over - 1); delay > 0 && delay < t.MaxLockout {
+			lockout = delay
+		}
+	}
+	state.lockedUntil = now.Add(lockout)
+
+	return lockout
+}
+
+// Success - forgets the failures of the key
+func (t *Throttle) Success(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.attempts, key)
+}
+
+func (t *Throttle) sweep(now time.Time) {
+	for key, state := range t.attempts {
+		if now.Sub(state.lastFailure) > t.ResetAfter && now.After(state.lockedUntil) {
+			delete(t.attempts, key)
+		}
+	}
+}
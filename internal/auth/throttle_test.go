@@ -0,0 +1,125 @@
+package auth_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/yuchida-tamu/git-workout-api/internal/auth"
+)
+
+// fakeClock - a clock which only moves when told to
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+// newTestThrottle - two free attempts, then lockouts of 1s, 2s, 4s, ... up to 10s
+func newTestThrottle() (*auth.Throttle, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)}
+	throttle := auth.NewThrottle(2, time.Second, 10*time.Second, time.Hour)
+	throttle.SetNow(clock.Now)
+
+	return throttle, clock
+}
+
+func TestThrottleFailure(t *testing.T) {
+	throttle, _ := newTestThrottle()
+
+	for i, want := range []time.Duration{
+		0,
+		0,
+		time.Second,
+		2 * time.Second,
+		4 * time.Second,
+		8 * time.Second,
+		// capped from here on
+		10 * time.Second,
+		10 * time.Second,
+	} {
+		if got := throttle.Failure("alice"); got != want {
+			t.Fatalf("failure %d: expected a lockout of %v, got %v", i+1, want, got)
+		}
+	}
+
+	// far more failures than the delay could be doubled for without overflowing
+	for i := 0; i < 100; i++ {
+		throttle.Failure("alice")
+	}
+	if got := throttle.LockedFor("alice"); got != 10*time.Second {
+		t.Fatalf("expected the lockout to stay at the cap, got %v", got)
+	}
+}
+
+func TestThrottleLockedFor(t *testing.T) {
+	for _, c := range []struct {
+		name    string
+		advance time.Duration
+		want    time.Duration
+	}{
+		{"Locked", 0, 2 * time.Second},
+		{"Running", 1500 * time.Millisecond, 500 * time.Millisecond},
+		{"Over", 2 * time.Second, 0},
+		{"LongOver", time.Minute, 0},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			throttle, clock := newTestThrottle()
+			for i := 0; i < 4; i++ {
+				throttle.Failure("alice")
+			}
+
+			clock.Advance(c.advance)
+			if got := throttle.LockedFor("alice"); got != c.want {
+				t.Fatalf("expected %v of the lockout to be left, got %v", c.want, got)
+			}
+		})
+	}
+}
+
+func TestThrottleReset(t *testing.T) {
+	for _, c := range []struct {
+		name  string
+		reset func(throttle *auth.Throttle, clock *fakeClock)
+		want  time.Duration
+	}{
+		{"Success", func(throttle *auth.Throttle, clock *fakeClock) { throttle.Success("alice") }, 0},
+		{"Idle", func(throttle *auth.Throttle, clock *fakeClock) { clock.Advance(time.Hour + time.Second) }, 0},
+		// failures within ResetAfter of each other keep counting
+		{"NotIdleLongEnough", func(throttle *auth.Throttle, clock *fakeClock) { clock.Advance(time.Hour) }, 4 * time.Second},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			throttle, clock := newTestThrottle()
+			for i := 0; i < 4; i++ {
+				throttle.Failure("alice")
+			}
+
+			c.reset(throttle, clock)
+			if got := throttle.Failure("alice"); got != c.want {
+				t.Fatalf("expected the next failure to lock out for %v, got %v", c.want, got)
+			}
+		})
+	}
+}
+
+func TestThrottleKeys(t *testing.T) {
+	throttle, _ := newTestThrottle()
+	for i := 0; i < 3; i++ {
+		throttle.Failure("alice")
+	}
+
+	if throttle.LockedFor("alice") == 0 {
+		t.Fatal("expected alice to be locked out")
+	}
+	if got := throttle.LockedFor("bob"); got != 0 {
+		t.Fatalf("expected bob not to be locked out by alice's failures, got %v", got)
+	}
+	if got := throttle.Failure("bob"); got != 0 {
+		t.Fatalf("expected bob's first failure to be free, got %v", got)
+	}
+}
@@ -0,0 +1,74 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/yuchida-tamu/git-workout-api/internal/mailer"
+)
+
+const emailVerificationLifetime = 48 * time.Hour
+
+var ErrNoEmail = errors.New("the user has no email address")
+
+func emailVerificationMessage(to string, token string) mailer.Message {
+	body := "Please confirm this email address for your git-workout account.\n\n"
+	if base := os.Getenv("EMAIL_VERIFICATION_URL"); base != "" {
+		body += "Open this link to confirm it:\n" + base + token + "\n\n"
+	} else {
+		body += "Use this code to confirm it:\n" + token + "\n\n"
+	}
+	body += fmt.Sprintf("It expires in %d hours.\n", int(emailVerificationLifetime.Hours()))
+
+	return mailer.Message{
+		To:      to,
+		Subject: "Confirm your email address",
+		Body:    body,
+	}
+}
+
+// SendEmailVerification - mails a verification token to the user's current address.
+// Sending a new one invalidates any earlier token, so after an address change only the new address can be confirmed.
+func (s *Service) SendEmailVerification(ctx context.Context, userID string) error {
+	u, err := s.Store.GetUser(ctx, userID)
+	if err != nil {
+		fmt.Println(err)
+		return err
+	}
+	if u.Email == "" {
+		return ErrNoEmail
+	}
+	if u.EmailVerified {
+		return nil
+	}
+
+	token, err := s.issueOneTimeToken(ctx, u.ID, PurposeEmailVerification, emailVerificationLifetime)
+	if err != nil {
+		return err
+	}
+
+	if err := s.Mailer.Send(ctx, emailVerificationMessage(u.Email, token)); err != nil {
+		fmt.Println(err)
+		return err
+	}
+
+	return nil
+}
+
+// VerifyEmail - marks the email address of the token's user as verified
+func (s *Service) VerifyEmail(ctx context.Context, raw string) error {
+	token, err := s.redeemOneTimeToken(ctx, raw, PurposeEmailVerification)
+	if err != nil {
+		return err
+	}
+
+	if err := s.Store.SetUserEmailVerified(ctx, token.UserID, true); err != nil {
+		fmt.Println(err)
+		return err
+	}
+
+	return nil
+}
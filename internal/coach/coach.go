@@ -0,0 +1,250 @@
+package coach
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/yuchida-tamu/git-workout-api/internal/user"
+)
+
+// Scope - a permission an athlete delegates to their coach
+type Scope string
+
+const (
+	ScopeReadRecords Scope = "records:read"
+	ScopeComment     Scope = "records:comment"
+	// ScopeAssignTemplates is granted ahead of workout templates so athletes do not need to re-accept later
+	ScopeAssignTemplates Scope = "templates:assign"
+)
+
+var knownScopes = map[Scope]bool{
+	ScopeReadRecords:     true,
+	ScopeComment:         true,
+	ScopeAssignTemplates: true,
+}
+
+type Status string
+
+const (
+	StatusPending  Status = "pending"
+	StatusActive   Status = "active"
+	StatusDeclined Status = "declined"
+)
+
+type Relationship struct {
+	ID          string
+	CoachID     string
+	AthleteID   string
+	Scopes      []Scope
+	Status      Status
+	DateCreated time.Time
+}
+
+// HasScope - reports whether the relationship is active and grants the scope
+func (rel Relationship) HasScope(scope Scope) bool {
+	if rel.Status != StatusActive {
+		return false
+	}
+	for _, s := range rel.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// JoinScopes - encodes scopes as the comma separated list the stores persist
+func JoinScopes(scopes []Scope) string {
+	parts := make([]string, 0, len(scopes))
+	for _, s := range scopes {
+		parts = append(parts, string(s))
+	}
+	return strings.Join(parts, ",")
+}
+
+// SplitScopes - decodes the comma separated list written by JoinScopes
+func SplitScopes(joined string) []Scope {
+	if joined == "" {
+		return []Scope{}
+	}
+	parts := strings.Split(joined, ",")
+	scopes := make([]Scope, 0, len(parts))
+	for _, p := range parts {
+		scopes = append(scopes, Scope(p))
+	}
+	return scopes
+}
+
+type Store interface {
+	GetRelationship(ctx context.Context, ID string) (Relationship, error)
+	GetRelationshipsByCoach(ctx context.Context, coachID string) ([]Relationship, error)
+	GetRelationshipsByAthlete(ctx context.Context, athleteID string) ([]Relationship, error)
+	PostRelationship(context.Context, Relationship) (Relationship, error)
+	UpdateRelationshipStatus(ctx context.Context, ID string, status Status) error
+	DeleteRelationship(context.Context, string) error
+
+	GetUser(context.Context, string) (user.User, error)
+}
+
+type Service struct {
+	Store Store
+}
+
+func NewService(store Store) *Service {
+	return &Service{
+		Store: store,
+	}
+}
+
+// Invite - creates a pending relationship which the athlete has to accept before the coach gains any access
+func (s *Service) Invite(ctx context.Context, coachID string, athleteID string, scopes []Scope) (Relationship, error) {
+	if coachID == athleteID {
+		return Relationship{}, fmt.Errorf("%w: a coach cannot invite themselves", ErrValidation)
+	}
+	if len(scopes) == 0 {
+		return Relationship{}, fmt.Errorf("%w: at least one scope is required", ErrValidation)
+	}
+	for _, scope := range scopes {
+		if !knownScopes[scope] {
+			return Relationship{}, fmt.Errorf("%w: unknown scope %q", ErrValidation, scope)
+		}
+	}
+
+	// validate uuid format
+	if _, err := uuid.FromString(athleteID); err != nil {
+		fmt.Println(err)
+		return Relationship{}, fmt.Errorf("%w: the athlete is not a valid id", ErrValidation)
+	}
+	// check if the athlete exists
+	if _, err := s.Store.GetUser(ctx, athleteID); err != nil {
+		fmt.Println(err)
+		return Relationship{}, err
+	}
+
+	existing, err := s.Store.GetRelationshipsByCoach(ctx, coachID)
+	if err != nil {
+		fmt.Println(err)
+		return Relationship{}, err
+	}
+	for _, rel := range existing {
+		if rel.AthleteID == athleteID && rel.Status != StatusDeclined {
+			return Relationship{}, fmt.Errorf("%w: a relationship with this athlete already exists", ErrConflict)
+		}
+	}
+
+	rel, err := s.Store.PostRelationship(ctx, Relationship{
+		CoachID:     coachID,
+		AthleteID:   athleteID,
+		Scopes:      scopes,
+		Status:      StatusPending,
+		DateCreated: time.Now().UTC(),
+	})
+	if err != nil {
+		fmt.Println(err)
+		return Relationship{}, err
+	}
+
+	return rel, nil
+}
+
+// Respond - lets the invited athlete accept or decline a pending invitation
+func (s *Service) Respond(ctx context.Context, athleteID string, ID string, accept bool) (Relationship, error) {
+	rel, err := s.Store.GetRelationship(ctx, ID)
+	if err != nil {
+		fmt.Println(err)
+		return Relationship{}, err
+	}
+
+	if rel.AthleteID != athleteID {
+		return Relationship{}, fmt.Errorf("%w: the invitation is not addressed to this user", ErrForbidden)
+	}
+	if rel.Status != StatusPending {
+		return Relationship{}, fmt.Errorf("%w: the invitation is no longer pending", ErrConflict)
+	}
+
+	rel.Status = StatusDeclined
+	if accept {
+		rel.Status = StatusActive
+	}
+
+	if err := s.Store.UpdateRelationshipStatus(ctx, ID, rel.Status); err != nil {
+		fmt.Println(err)
+		return Relationship{}, err
+	}
+
+	return rel, nil
+}
+
+func (s *Service) GetRelationshipsByCoach(ctx context.Context, coachID string) ([]Relationship, error) {
+	rels, err := s.Store.GetRelationshipsByCoach(ctx, coachID)
+	if err != nil {
+		fmt.Println(err)
+		return []Relationship{}, err
+	}
+
+	return rels, nil
+}
+
+func (s *Service) GetRelationshipsByAthlete(ctx context.Context, athleteID string) ([]Relationship, error) {
+	rels, err := s.Store.GetRelationshipsByAthlete(ctx, athleteID)
+	if err != nil {
+		fmt.Println(err)
+		return []Relationship{}, err
+	}
+
+	return rels, nil
+}
+
+// EndRelationship - removes a relationship; either the coach or the athlete may end it
+func (s *Service) EndRelationship(ctx context.Context, userID string, ID string) error {
+	rel, err := s.Store.GetRelationship(ctx, ID)
+	if err != nil {
+		fmt.Println(err)
+		return err
+	}
+
+	if rel.CoachID != userID && rel.AthleteID != userID {
+		return fmt.Errorf("%w: the user is not part of this relationship", ErrForbidden)
+	}
+
+	if err := s.Store.DeleteRelationship(ctx, ID); err != nil {
+		fmt.Println(err)
+		return err
+	}
+
+	return nil
+}
+
+// HasScope - reports whether coachID holds an active relationship with athleteID granting the scope. The
+// relationships outlive the coach's role, so a coach demoted since the athlete accepted has no access.
+func (s *Service) HasScope(ctx context.Context, coachID string, athleteID string, scope Scope) (bool, error) {
+	coach, err := s.Store.GetUser(ctx, coachID)
+	if errors.Is(err, user.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		fmt.Println(err)
+		return false, err
+	}
+	if !coach.Role.Can(user.PermissionCoachAthletes) {
+		return false, nil
+	}
+
+	rels, err := s.Store.GetRelationshipsByCoach(ctx, coachID)
+	if err != nil {
+		fmt.Println(err)
+		return false, err
+	}
+
+	for _, rel := range rels {
+		if rel.AthleteID == athleteID && rel.HasScope(scope) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
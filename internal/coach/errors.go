@@ -0,0 +1,14 @@
+package coach
+
+import "errors"
+
+var (
+	// ErrNotFound - the relationship does not exist
+	ErrNotFound = errors.New("relationship not found")
+	// ErrConflict - the relationship exists already, or is no longer in a state allowing the change
+	ErrConflict = errors.New("relationship conflict")
+	// ErrValidation - the relationship is not valid
+	ErrValidation = errors.New("invalid relationship")
+	// ErrForbidden - the caller may not access the relationship
+	ErrForbidden = errors.New("not allowed to access the relationship")
+)
@@ -0,0 +1,209 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	uuid "github.com/satori/go.uuid"
+	"github.com/yuchida-tamu/git-workout-api/internal/auth"
+)
+
+type APIKeyRow struct {
+	ID          string
+	UserID      string
+	Name        string
+	Prefix      string
+	KeyHash     string
+	Scopes      string
+	Revoked     bool
+	ExpiresAt   sql.NullTime
+	LastUsed    sql.NullTime
+	DateCreated time.Time
+}
+
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
+
+// nullString - stores an empty string as NULL, for optional references to other rows
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+func convertAPIKeyRowToAPIKey(row APIKeyRow) auth.APIKey {
+	return auth.APIKey{
+		ID:          row.ID,
+		UserID:      row.UserID,
+		Name:        row.Name,
+		Prefix:      row.Prefix,
+		KeyHash:     row.KeyHash,
+		Scopes:      splitAuthScopes(row.Scopes),
+		Revoked:     row.Revoked,
+		ExpiresAt:   row.ExpiresAt.Time,
+		LastUsed:    row.LastUsed.Time,
+		DateCreated: row.DateCreated,
+	}
+}
+
+func joinAuthScopes(scopes []auth.Scope) string {
+	parts := make([]string, 0, len(scopes))
+	for _, s := range scopes {
+		parts = append(parts, string(s))
+	}
+	return strings.Join(parts, ",")
+}
+
+func splitAuthScopes(s string) []auth.Scope {
+	scopes := []auth.Scope{}
+	if s == "" {
+		return scopes
+	}
+	for _, part := range strings.Split(s, ",") {
+		scopes = append(scopes, auth.Scope(part))
+	}
+	return scopes
+}
+
+func scanAPIKeyRow(scanner interface{ Scan(...interface{}) error }) (APIKeyRow, error) {
+	var keyRow APIKeyRow
+	err := scanner.Scan(
+		&keyRow.ID,
+		&keyRow.UserID,
+		&keyRow.Name,
+		&keyRow.Prefix,
+		&keyRow.KeyHash,
+		&keyRow.Scopes,
+		&keyRow.Revoked,
+		&keyRow.ExpiresAt,
+		&keyRow.LastUsed,
+		&keyRow.DateCreated,
+	)
+	return keyRow, err
+}
+
+func (d *Database) PostAPIKey(ctx context.Context, key auth.APIKey) (auth.APIKey, error) {
+	key.ID = uuid.NewV4().String()
+	postRow := APIKeyRow{
+		ID:          key.ID,
+		UserID:      key.UserID,
+		Name:        key.Name,
+		Prefix:      key.Prefix,
+		KeyHash:     key.KeyHash,
+		Scopes:      joinAuthScopes(key.Scopes),
+		Revoked:     key.Revoked,
+		ExpiresAt:   nullTime(key.ExpiresAt),
+		LastUsed:    nullTime(key.LastUsed),
+		DateCreated: key.DateCreated,
+	}
+
+	row, err := sqlx.NamedQueryContext(
+		ctx,
+		d.conn(ctx),
+		`INSERT INTO api_keys
+		(id, user_id, name, prefix, key_hash, scopes, revoked, expires_at, last_used, date_created)
+		VALUES
+		(:id, :userid, :name, :prefix, :keyhash, :scopes, :revoked, :expiresat, :lastused, :datecreated)`,
+		postRow,
+	)
+	if err != nil {
+		return auth.APIKey{}, fmt.Errorf("failed to insert api key: %w", err)
+	}
+
+	if err := row.Close(); err != nil {
+		return auth.APIKey{}, fmt.Errorf("failed to insert api key: %w", err)
+	}
+
+	return key, nil
+}
+
+func (d *Database) GetAPIKey(ctx context.Context, ID string) (auth.APIKey, error) {
+	row := d.conn(ctx).QueryRowContext(
+		ctx,
+		`SELECT id, user_id, name, prefix, key_hash, scopes, revoked, expires_at, last_used, date_created
+		FROM api_keys
+		WHERE id = $1`,
+		ID,
+	)
+
+	keyRow, err := scanAPIKeyRow(row)
+	if err != nil {
+		return auth.APIKey{}, fmt.Errorf("error fetching the api key by id: %w", notFound(err, auth.ErrNotFound))
+	}
+
+	return convertAPIKeyRowToAPIKey(keyRow), nil
+}
+
+func (d *Database) GetAPIKeyByHash(ctx context.Context, hash string) (auth.APIKey, error) {
+	row := d.conn(ctx).QueryRowContext(
+		ctx,
+		`SELECT id, user_id, name, prefix, key_hash, scopes, revoked, expires_at, last_used, date_created
+		FROM api_keys
+		WHERE key_hash = $1`,
+		hash,
+	)
+
+	keyRow, err := scanAPIKeyRow(row)
+	if err != nil {
+		return auth.APIKey{}, fmt.Errorf("error fetching the api key by hash: %w", notFound(err, auth.ErrNotFound))
+	}
+
+	return convertAPIKeyRowToAPIKey(keyRow), nil
+}
+
+func (d *Database) GetAPIKeysByUser(ctx context.Context, userID string) ([]auth.APIKey, error) {
+	keys := []auth.APIKey{}
+	rows, err := d.conn(ctx).QueryContext(
+		ctx,
+		`SELECT id, user_id, name, prefix, key_hash, scopes, revoked, expires_at, last_used, date_created
+		FROM api_keys
+		WHERE user_id = $1
+		ORDER BY date_created`,
+		userID,
+	)
+	if err != nil {
+		return []auth.APIKey{}, fmt.Errorf("error fetching api keys by user id: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		keyRow, err := scanAPIKeyRow(rows)
+		if err != nil {
+			return []auth.APIKey{}, fmt.Errorf("error fetching api keys by user id: %w", err)
+		}
+
+		keys = append(keys, convertAPIKeyRowToAPIKey(keyRow))
+	}
+
+	return keys, nil
+}
+
+func (d *Database) TouchAPIKey(ctx context.Context, ID string, lastUsed time.Time) error {
+	_, err := d.conn(ctx).ExecContext(
+		ctx,
+		`UPDATE api_keys SET last_used = $1 WHERE id = $2`,
+		lastUsed,
+		ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update api key last used time: %w", err)
+	}
+
+	return nil
+}
+
+func (d *Database) RevokeAPIKey(ctx context.Context, ID string) error {
+	_, err := d.conn(ctx).ExecContext(
+		ctx,
+		`UPDATE api_keys SET revoked = true WHERE id = $1`,
+		ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to revoke api key: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	uuid "github.com/satori/go.uuid"
+	appUser "github.com/yuchida-tamu/git-workout-api/internal/user"
+)
+
+type AuditEventRow struct {
+	ID          string
+	UserID      string
+	Action      string
+	DateCreated time.Time
+}
+
+func convertAuditEventRowToAuditEvent(row AuditEventRow) appUser.AuditEvent {
+	return appUser.AuditEvent{
+		ID:          row.ID,
+		UserID:      row.UserID,
+		Action:      appUser.AuditAction(row.Action),
+		DateCreated: row.DateCreated,
+	}
+}
+
+func (d *Database) PostAuditEvent(ctx context.Context, event appUser.AuditEvent) (appUser.AuditEvent, error) {
+	event.ID = uuid.NewV4().String()
+	postRow := AuditEventRow{
+		ID:          event.ID,
+		UserID:      event.UserID,
+		Action:      string(event.Action),
+		DateCreated: event.DateCreated,
+	}
+
+	row, err := sqlx.NamedQueryContext(
+		ctx,
+		d.conn(ctx),
+		`INSERT INTO audit_events
+		(id, user_id, action, date_created)
+		VALUES
+		(:id, :userid, :action, :datecreated)`,
+		postRow,
+	)
+	if err != nil {
+		return appUser.AuditEvent{}, fmt.Errorf("failed to insert audit event: %w", err)
+	}
+
+	if err := row.Close(); err != nil {
+		return appUser.AuditEvent{}, fmt.Errorf("failed to insert audit event: %w", err)
+	}
+
+	return event, nil
+}
+
+func (d *Database) GetAuditEventsByUser(ctx context.Context, userID string) ([]appUser.AuditEvent, error) {
+	events := []appUser.AuditEvent{}
+	rows, err := d.conn(ctx).QueryContext(
+		ctx,
+		`SELECT id, user_id, action, date_created
+		FROM audit_events
+		WHERE user_id = $1
+		ORDER BY date_created DESC`,
+		userID,
+	)
+	if err != nil {
+		return []appUser.AuditEvent{}, fmt.Errorf("error fetching audit events by user id: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var eventRow AuditEventRow
+		err := rows.Scan(
+			&eventRow.ID,
+			&eventRow.UserID,
+			&eventRow.Action,
+			&eventRow.DateCreated,
+		)
+		if err != nil {
+			return []appUser.AuditEvent{}, fmt.Errorf("error fetching audit events by user id: %w", err)
+		}
+
+		events = append(events, convertAuditEventRowToAuditEvent(eventRow))
+	}
+
+	return events, nil
+}
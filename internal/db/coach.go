@@ -0,0 +1,144 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	uuid "github.com/satori/go.uuid"
+	"github.com/yuchida-tamu/git-workout-api/internal/coach"
+)
+
+type RelationshipRow struct {
+	ID          string
+	CoachID     string
+	AthleteID   string
+	Scopes      string
+	Status      string
+	DateCreated time.Time
+}
+
+func convertRelationshipRowToRelationship(row RelationshipRow) coach.Relationship {
+	return coach.Relationship{
+		ID:          row.ID,
+		CoachID:     row.CoachID,
+		AthleteID:   row.AthleteID,
+		Scopes:      coach.SplitScopes(row.Scopes),
+		Status:      coach.Status(row.Status),
+		DateCreated: row.DateCreated,
+	}
+}
+
+func (d *Database) getRelationshipsWhere(ctx context.Context, column string, ID string) ([]coach.Relationship, error) {
+	rels := []coach.Relationship{}
+	rows, err := d.conn(ctx).QueryContext(
+		ctx,
+		`SELECT id, coach_id, athlete_id, scopes, status, date_created
+		FROM coach_relationships
+		WHERE `+column+` = $1
+		ORDER BY date_created`,
+		ID,
+	)
+	if err != nil {
+		return []coach.Relationship{}, fmt.Errorf("error fetching relationships by %s: %w", column, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var relRow RelationshipRow
+		err := rows.Scan(&relRow.ID, &relRow.CoachID, &relRow.AthleteID, &relRow.Scopes, &relRow.Status, &relRow.DateCreated)
+		if err != nil {
+			return []coach.Relationship{}, fmt.Errorf("error fetching relationships by %s: %w", column, err)
+		}
+
+		rels = append(rels, convertRelationshipRowToRelationship(relRow))
+	}
+
+	return rels, nil
+}
+
+func (d *Database) GetRelationshipsByCoach(ctx context.Context, coachID string) ([]coach.Relationship, error) {
+	return d.getRelationshipsWhere(ctx, "coach_id", coachID)
+}
+
+func (d *Database) GetRelationshipsByAthlete(ctx context.Context, athleteID string) ([]coach.Relationship, error) {
+	return d.getRelationshipsWhere(ctx, "athlete_id", athleteID)
+}
+
+func (d *Database) GetRelationship(ctx context.Context, ID string) (coach.Relationship, error) {
+	var relRow RelationshipRow
+
+	row := d.conn(ctx).QueryRowContext(
+		ctx,
+		`SELECT id, coach_id, athlete_id, scopes, status, date_created
+		FROM coach_relationships
+		WHERE id = $1`,
+		ID,
+	)
+
+	err := row.Scan(&relRow.ID, &relRow.CoachID, &relRow.AthleteID, &relRow.Scopes, &relRow.Status, &relRow.DateCreated)
+	if err != nil {
+		return coach.Relationship{}, fmt.Errorf("error fetching the relationship by id: %w", notFound(err, coach.ErrNotFound))
+	}
+
+	return convertRelationshipRowToRelationship(relRow), nil
+}
+
+func (d *Database) PostRelationship(ctx context.Context, rel coach.Relationship) (coach.Relationship, error) {
+	rel.ID = uuid.NewV4().String()
+	postRow := RelationshipRow{
+		ID:          rel.ID,
+		CoachID:     rel.CoachID,
+		AthleteID:   rel.AthleteID,
+		Scopes:      coach.JoinScopes(rel.Scopes),
+		Status:      string(rel.Status),
+		DateCreated: rel.DateCreated,
+	}
+
+	row, err := sqlx.NamedQueryContext(
+		ctx,
+		d.conn(ctx),
+		`INSERT INTO coach_relationships
+		(id, coach_id, athlete_id, scopes, status, date_created)
+		VALUES
+		(:id, :coachid, :athleteid, :scopes, :status, :datecreated)`,
+		postRow,
+	)
+	if err != nil {
+		return coach.Relationship{}, fmt.Errorf("failed to insert relationship: %w", err)
+	}
+
+	if err := row.Close(); err != nil {
+		return coach.Relationship{}, fmt.Errorf("failed to insert relationship: %w", err)
+	}
+
+	return rel, nil
+}
+
+func (d *Database) UpdateRelationshipStatus(ctx context.Context, ID string, status coach.Status) error {
+	_, err := d.conn(ctx).ExecContext(
+		ctx,
+		`UPDATE coach_relationships SET status = $1 WHERE id = $2`,
+		string(status),
+		ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update relationship status: %w", err)
+	}
+
+	return nil
+}
+
+func (d *Database) DeleteRelationship(ctx context.Context, ID string) error {
+	_, err := d.conn(ctx).ExecContext(
+		ctx,
+		`DELETE FROM coach_relationships WHERE id = $1`,
+		ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to delete relationship from database: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,87 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	uuid "github.com/satori/go.uuid"
+	"github.com/yuchida-tamu/git-workout-api/internal/record"
+)
+
+type CommentRow struct {
+	ID          string
+	RecordID    string
+	Author      string
+	MessageBody string
+	DateCreated time.Time
+}
+
+func convertCommentRowToComment(row CommentRow) record.Comment {
+	return record.Comment{
+		ID:          row.ID,
+		RecordID:    row.RecordID,
+		Author:      row.Author,
+		MessageBody: row.MessageBody,
+		DateCreated: row.DateCreated,
+	}
+}
+
+func (d *Database) GetCommentsByRecord(ctx context.Context, recordID string) ([]record.Comment, error) {
+	comments := []record.Comment{}
+	rows, err := d.conn(ctx).QueryContext(
+		ctx,
+		`SELECT id, record_id, author, message_body, date_created
+		FROM record_comments
+		WHERE record_id = $1
+		ORDER BY date_created`,
+		recordID,
+	)
+	if err != nil {
+		return []record.Comment{}, fmt.Errorf("error fetching comments by record id: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var commentRow CommentRow
+		err := rows.Scan(&commentRow.ID, &commentRow.RecordID, &commentRow.Author, &commentRow.MessageBody, &commentRow.DateCreated)
+		if err != nil {
+			return []record.Comment{}, fmt.Errorf("error fetching comments by record id: %w", err)
+		}
+
+		comments = append(comments, convertCommentRowToComment(commentRow))
+	}
+
+	return comments, nil
+}
+
+func (d *Database) PostComment(ctx context.Context, comment record.Comment) (record.Comment, error) {
+	comment.ID = uuid.NewV4().String()
+	postRow := CommentRow{
+		ID:          comment.ID,
+		RecordID:    comment.RecordID,
+		Author:      comment.Author,
+		MessageBody: comment.MessageBody,
+		DateCreated: comment.DateCreated,
+	}
+
+	row, err := sqlx.NamedQueryContext(
+		ctx,
+		d.conn(ctx),
+		`INSERT INTO record_comments
+		(id, record_id, author, message_body, date_created)
+		VALUES
+		(:id, :recordid, :author, :messagebody, :datecreated)`,
+		postRow,
+	)
+	if err != nil {
+		return record.Comment{}, fmt.Errorf("failed to insert comment: %w", err)
+	}
+
+	if err := row.Close(); err != nil {
+		return record.Comment{}, fmt.Errorf("failed to insert comment: %w", err)
+	}
+
+	return comment, nil
+}
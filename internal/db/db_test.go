@@ -0,0 +1,147 @@
+package db
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/yuchida-tamu/git-workout-api/internal/record"
+	"github.com/yuchida-tamu/git-workout-api/internal/storetest"
+	"github.com/yuchida-tamu/git-workout-api/internal/user"
+)
+
+// TestPostgres - runs against the database configured by the DB_* variables and is skipped without them;
+// `task test:postgres` starts a disposable one. Every test shares the database, the suite does not need it
+// to be empty.
+func TestPostgres(t *testing.T) {
+	if os.Getenv("DB_HOST") == "" {
+		t.Skip("DB_HOST is not set, skipping the Postgres store tests")
+	}
+	useLocalMigrations(t)
+
+	database, err := NewDatabase()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := database.MigrateDB(); err != nil {
+		t.Fatal(err)
+	}
+
+	storetest.Run(t, func(t *testing.T) storetest.Store {
+		return database
+	})
+	t.Run("AddedColumns", func(t *testing.T) {
+		testAddedColumns(t, database)
+	})
+}
+
+func TestSQLite(t *testing.T) {
+	useLocalMigrations(t)
+
+	storetest.Run(t, func(t *testing.T) storetest.Store {
+		database, err := NewSQLiteDatabase(filepath.Join(t.TempDir(), "test.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := database.MigrateDB(); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { database.Client.Close() })
+
+		return database
+	})
+}
+
+func TestSQLiteAddedColumns(t *testing.T) {
+	useLocalMigrations(t)
+
+	database, err := NewSQLiteDatabase(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := database.MigrateDB(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { database.Client.Close() })
+
+	testAddedColumns(t, database)
+}
+
+// testAddedColumns - a migration adding a column must not break reading users and records; the column is
+// dropped again afterwards
+func testAddedColumns(t *testing.T, database *Database) {
+	ctx := context.Background()
+	for _, table := range []string{"users", "records"} {
+		if _, err := database.Client.ExecContext(ctx, `ALTER TABLE `+table+` ADD COLUMN added_by_test text DEFAULT 'added'`); err != nil {
+			t.Fatal(err)
+		}
+		table := table
+		t.Cleanup(func() {
+			if _, err := database.Client.ExecContext(ctx, `ALTER TABLE `+table+` DROP COLUMN added_by_test`); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+
+	username := "added-" + uuid.NewV4().String()[:8]
+	posted, err := database.PostUser(ctx, user.User{
+		Username: username,
+		Password: "hash",
+		Email:    username + "@example.com",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, get := range map[string]func() (user.User, error){
+		"GetUser":           func() (user.User, error) { return database.GetUser(ctx, posted.ID) },
+		"GetUserByUsername": func() (user.User, error) { return database.GetUserByUsername(ctx, posted.Username) },
+		"GetUserByEmail":    func() (user.User, error) { return database.GetUserByEmail(ctx, posted.Email) },
+	} {
+		got, err := get()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if got != posted {
+			t.Fatalf("%s returned %+v, want %+v", name, got, posted)
+		}
+	}
+	if _, err := database.GetUsers(ctx); err != nil {
+		t.Fatalf("GetUsers: %v", err)
+	}
+
+	rcd, err := database.PostRecord(ctx, record.Record{
+		DateCreated: "2022-05-01",
+		MessageBody: "bench press 5x5",
+		Author:      posted.ID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := database.GetRecordById(ctx, rcd.ID)
+	if err != nil {
+		t.Fatalf("GetRecordById: %v", err)
+	}
+	if got != rcd {
+		t.Fatalf("GetRecordById returned %+v, want %+v", got, rcd)
+	}
+	records, err := database.GetRecordsByAuthor(ctx, posted.ID)
+	if err != nil {
+		t.Fatalf("GetRecordsByAuthor: %v", err)
+	}
+	if len(records) != 1 || records[0] != rcd {
+		t.Fatalf("GetRecordsByAuthor returned %+v, want only %+v", records, rcd)
+	}
+}
+
+// useLocalMigrations - reads the migrations from the repository rather than from /migrations
+func useLocalMigrations(t *testing.T) {
+	t.Helper()
+
+	dir, err := filepath.Abs(filepath.Join("..", "..", "migrations"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("MIGRATIONS_DIR", dir)
+}
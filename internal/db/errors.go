@@ -0,0 +1,55 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// notFound - turns a missing row into the domain's not found error. Postgres rejects an ID which is not a
+// uuid instead of finding nothing, which the caller cannot tell apart, so that counts as missing too.
+func notFound(err error, domainErr error) error {
+	var pqErr *pq.Error
+	if errors.Is(err, sql.ErrNoRows) || (errors.As(err, &pqErr) && pqErr.Code == "22P02") {
+		return domainErr
+	}
+
+	return err
+}
+
+// invalidReference - turns a foreign key violation, a row pointing at one which does not exist, into the
+// domain's error for it
+func invalidReference(err error, domainErr error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+		return domainErr
+	}
+
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY {
+		return domainErr
+	}
+
+	return err
+}
+
+// conflict - turns a unique constraint violation into the domain's conflict error
+func conflict(err error, domainErr error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return domainErr
+	}
+
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		switch sqliteErr.Code() {
+		case sqlite3.SQLITE_CONSTRAINT_UNIQUE, sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY:
+			return domainErr
+		}
+	}
+
+	return err
+}
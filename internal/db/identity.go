@@ -0,0 +1,237 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	uuid "github.com/satori/go.uuid"
+	"github.com/yuchida-tamu/git-workout-api/internal/auth"
+)
+
+type OIDCLoginRow struct {
+	ID          string
+	StateHash   string
+	Provider    string
+	Verifier    string
+	Nonce       string
+	LinkUserID  sql.NullString
+	ExpiresAt   time.Time
+	DateCreated time.Time
+}
+
+type IdentityRow struct {
+	ID          string
+	UserID      string
+	Provider    string
+	Subject     string
+	Email       string
+	DateCreated time.Time
+}
+
+func convertIdentityRowToIdentity(row IdentityRow) auth.Identity {
+	return auth.Identity{
+		ID:          row.ID,
+		UserID:      row.UserID,
+		Provider:    row.Provider,
+		Subject:     row.Subject,
+		Email:       row.Email,
+		DateCreated: row.DateCreated,
+	}
+}
+
+func (d *Database) PostOIDCLogin(ctx context.Context, login auth.OIDCLogin) (auth.OIDCLogin, error) {
+	login.ID = uuid.NewV4().String()
+	postRow := OIDCLoginRow{
+		ID:          login.ID,
+		StateHash:   login.StateHash,
+		Provider:    login.Provider,
+		Verifier:    login.Verifier,
+		Nonce:       login.Nonce,
+		LinkUserID:  nullString(login.LinkUserID),
+		ExpiresAt:   login.ExpiresAt,
+		DateCreated: login.DateCreated,
+	}
+
+	row, err := sqlx.NamedQueryContext(
+		ctx,
+		d.conn(ctx),
+		`INSERT INTO oidc_logins
+		(id, state_hash, provider, verifier, nonce, link_user_id, expires_at, date_created)
+		VALUES
+		(:id, :statehash, :provider, :verifier, :nonce, :linkuserid, :expiresat, :datecreated)`,
+		postRow,
+	)
+	if err != nil {
+		return auth.OIDCLogin{}, fmt.Errorf("failed to insert oidc login: %w", err)
+	}
+
+	if err := row.Close(); err != nil {
+		return auth.OIDCLogin{}, fmt.Errorf("failed to insert oidc login: %w", err)
+	}
+
+	return login, nil
+}
+
+func (d *Database) GetOIDCLoginByStateHash(ctx context.Context, hash string) (auth.OIDCLogin, error) {
+	var loginRow OIDCLoginRow
+
+	row := d.conn(ctx).QueryRowContext(
+		ctx,
+		`SELECT id, state_hash, provider, verifier, nonce, link_user_id, expires_at, date_created
+		FROM oidc_logins
+		WHERE state_hash = $1`,
+		hash,
+	)
+
+	err := row.Scan(
+		&loginRow.ID,
+		&loginRow.StateHash,
+		&loginRow.Provider,
+		&loginRow.Verifier,
+		&loginRow.Nonce,
+		&loginRow.LinkUserID,
+		&loginRow.ExpiresAt,
+		&loginRow.DateCreated,
+	)
+	if err != nil {
+		return auth.OIDCLogin{}, fmt.Errorf("error fetching the oidc login by state: %w", notFound(err, auth.ErrNotFound))
+	}
+
+	return auth.OIDCLogin{
+		ID:          loginRow.ID,
+		StateHash:   loginRow.StateHash,
+		Provider:    loginRow.Provider,
+		Verifier:    loginRow.Verifier,
+		Nonce:       loginRow.Nonce,
+		LinkUserID:  loginRow.LinkUserID.String,
+		ExpiresAt:   loginRow.ExpiresAt,
+		DateCreated: loginRow.DateCreated,
+	}, nil
+}
+
+func (d *Database) DeleteOIDCLogin(ctx context.Context, ID string) (bool, error) {
+	result, err := d.conn(ctx).ExecContext(
+		ctx,
+		`DELETE FROM oidc_logins WHERE id = $1`,
+		ID,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete oidc login: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete oidc login: %w", err)
+	}
+
+	return affected == 1, nil
+}
+
+func (d *Database) PostIdentity(ctx context.Context, identity auth.Identity) (auth.Identity, error) {
+	identity.ID = uuid.NewV4().String()
+	postRow := IdentityRow{
+		ID:          identity.ID,
+		UserID:      identity.UserID,
+		Provider:    identity.Provider,
+		Subject:     identity.Subject,
+		Email:       identity.Email,
+		DateCreated: identity.DateCreated,
+	}
+
+	row, err := sqlx.NamedQueryContext(
+		ctx,
+		d.conn(ctx),
+		`INSERT INTO user_identities
+		(id, user_id, provider, subject, email, date_created)
+		VALUES
+		(:id, :userid, :provider, :subject, :email, :datecreated)`,
+		postRow,
+	)
+	if err != nil {
+		return auth.Identity{}, fmt.Errorf("failed to insert identity: %w", err)
+	}
+
+	if err := row.Close(); err != nil {
+		return auth.Identity{}, fmt.Errorf("failed to insert identity: %w", err)
+	}
+
+	return identity, nil
+}
+
+func (d *Database) GetIdentity(ctx context.Context, provider string, subject string) (auth.Identity, error) {
+	var identityRow IdentityRow
+
+	row := d.conn(ctx).QueryRowContext(
+		ctx,
+		`SELECT id, user_id, provider, subject, email, date_created
+		FROM user_identities
+		WHERE provider = $1 AND subject = $2`,
+		provider,
+		subject,
+	)
+
+	err := row.Scan(
+		&identityRow.ID,
+		&identityRow.UserID,
+		&identityRow.Provider,
+		&identityRow.Subject,
+		&identityRow.Email,
+		&identityRow.DateCreated,
+	)
+	if err != nil {
+		return auth.Identity{}, fmt.Errorf("error fetching the identity: %w", notFound(err, auth.ErrNotFound))
+	}
+
+	return convertIdentityRowToIdentity(identityRow), nil
+}
+
+func (d *Database) GetIdentitiesByUser(ctx context.Context, userID string) ([]auth.Identity, error) {
+	identities := []auth.Identity{}
+	rows, err := d.conn(ctx).QueryContext(
+		ctx,
+		`SELECT id, user_id, provider, subject, email, date_created
+		FROM user_identities
+		WHERE user_id = $1
+		ORDER BY date_created`,
+		userID,
+	)
+	if err != nil {
+		return []auth.Identity{}, fmt.Errorf("error fetching identities by user id: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var identityRow IdentityRow
+		err := rows.Scan(
+			&identityRow.ID,
+			&identityRow.UserID,
+			&identityRow.Provider,
+			&identityRow.Subject,
+			&identityRow.Email,
+			&identityRow.DateCreated,
+		)
+		if err != nil {
+			return []auth.Identity{}, fmt.Errorf("error fetching identities by user id: %w", err)
+		}
+
+		identities = append(identities, convertIdentityRowToIdentity(identityRow))
+	}
+
+	return identities, nil
+}
+
+func (d *Database) DeleteIdentity(ctx context.Context, ID string) error {
+	_, err := d.conn(ctx).ExecContext(
+		ctx,
+		`DELETE FROM user_identities WHERE id = $1`,
+		ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to delete identity: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,102 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	uuid "github.com/satori/go.uuid"
+	"github.com/yuchida-tamu/git-workout-api/internal/auth"
+)
+
+type LockoutEventRow struct {
+	ID          string
+	UserID      sql.NullString
+	Username    string
+	IP          string
+	Reason      string
+	LockedUntil time.Time
+	DateCreated time.Time
+}
+
+func convertLockoutEventRowToLockoutEvent(row LockoutEventRow) auth.LockoutEvent {
+	return auth.LockoutEvent{
+		ID:          row.ID,
+		UserID:      row.UserID.String,
+		Username:    row.Username,
+		IP:          row.IP,
+		Reason:      auth.LockoutReason(row.Reason),
+		LockedUntil: row.LockedUntil,
+		DateCreated: row.DateCreated,
+	}
+}
+
+func (d *Database) PostLockoutEvent(ctx context.Context, event auth.LockoutEvent) (auth.LockoutEvent, error) {
+	event.ID = uuid.NewV4().String()
+	postRow := LockoutEventRow{
+		ID:          event.ID,
+		UserID:      nullString(event.UserID),
+		Username:    event.Username,
+		IP:          event.IP,
+		Reason:      string(event.Reason),
+		LockedUntil: event.LockedUntil,
+		DateCreated: event.DateCreated,
+	}
+
+	row, err := sqlx.NamedQueryContext(
+		ctx,
+		d.conn(ctx),
+		`INSERT INTO lockout_events
+		(id, user_id, username, ip, reason, locked_until, date_created)
+		VALUES
+		(:id, :userid, :username, :ip, :reason, :lockeduntil, :datecreated)`,
+		postRow,
+	)
+	if err != nil {
+		return auth.LockoutEvent{}, fmt.Errorf("failed to insert lockout event: %w", err)
+	}
+
+	if err := row.Close(); err != nil {
+		return auth.LockoutEvent{}, fmt.Errorf("failed to insert lockout event: %w", err)
+	}
+
+	return event, nil
+}
+
+func (d *Database) GetLockoutEventsByUser(ctx context.Context, userID string) ([]auth.LockoutEvent, error) {
+	events := []auth.LockoutEvent{}
+	rows, err := d.conn(ctx).QueryContext(
+		ctx,
+		`SELECT id, user_id, username, ip, reason, locked_until, date_created
+		FROM lockout_events
+		WHERE user_id = $1
+		ORDER BY date_created DESC`,
+		userID,
+	)
+	if err != nil {
+		return []auth.LockoutEvent{}, fmt.Errorf("error fetching lockout events by user id: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var eventRow LockoutEventRow
+		err := rows.Scan(
+			&eventRow.ID,
+			&eventRow.UserID,
+			&eventRow.Username,
+			&eventRow.IP,
+			&eventRow.Reason,
+			&eventRow.LockedUntil,
+			&eventRow.DateCreated,
+		)
+		if err != nil {
+			return []auth.LockoutEvent{}, fmt.Errorf("error fetching lockout events by user id: %w", err)
+		}
+
+		events = append(events, convertLockoutEventRowToLockoutEvent(eventRow))
+	}
+
+	return events, nil
+}
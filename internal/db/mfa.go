@@ -0,0 +1,159 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/yuchida-tamu/git-workout-api/internal/auth"
+)
+
+type MFARow struct {
+	UserID             string
+	Secret             string
+	Enabled            bool
+	RecoveryCodeHashes string
+	LastUsedStep       int64
+	DateCreated        time.Time
+}
+
+func convertMFARowToMFA(row MFARow) auth.MFA {
+	hashes := []string{}
+	if row.RecoveryCodeHashes != "" {
+		hashes = strings.Split(row.RecoveryCodeHashes, ",")
+	}
+
+	return auth.MFA{
+		UserID:             row.UserID,
+		Secret:             row.Secret,
+		Enabled:            row.Enabled,
+		RecoveryCodeHashes: hashes,
+		LastUsedStep:       row.LastUsedStep,
+		DateCreated:        row.DateCreated,
+	}
+}
+
+func (d *Database) GetMFA(ctx context.Context, userID string) (auth.MFA, error) {
+	var mfaRow MFARow
+
+	row := d.conn(ctx).QueryRowContext(
+		ctx,
+		`SELECT user_id, secret, enabled, recovery_code_hashes, last_used_step, date_created
+		FROM user_mfa
+		WHERE user_id = $1`,
+		userID,
+	)
+
+	err := row.Scan(
+		&mfaRow.UserID,
+		&mfaRow.Secret,
+		&mfaRow.Enabled,
+		&mfaRow.RecoveryCodeHashes,
+		&mfaRow.LastUsedStep,
+		&mfaRow.DateCreated,
+	)
+	if err != nil {
+		return auth.MFA{}, fmt.Errorf("error fetching the mfa enrollment by user id: %w", notFound(err, auth.ErrNotFound))
+	}
+
+	return convertMFARowToMFA(mfaRow), nil
+}
+
+func (d *Database) PutMFA(ctx context.Context, mfa auth.MFA) error {
+	putRow := MFARow{
+		UserID:             mfa.UserID,
+		Secret:             mfa.Secret,
+		Enabled:            mfa.Enabled,
+		RecoveryCodeHashes: strings.Join(mfa.RecoveryCodeHashes, ","),
+		LastUsedStep:       mfa.LastUsedStep,
+		DateCreated:        mfa.DateCreated,
+	}
+
+	row, err := sqlx.NamedQueryContext(
+		ctx,
+		d.conn(ctx),
+		`INSERT INTO user_mfa
+		(user_id, secret, enabled, recovery_code_hashes, last_used_step, date_created)
+		VALUES
+		(:userid, :secret, :enabled, :recoverycodehashes, :lastusedstep, :datecreated)
+		ON CONFLICT (user_id) DO UPDATE SET
+		secret = EXCLUDED.secret,
+		enabled = EXCLUDED.enabled,
+		recovery_code_hashes = EXCLUDED.recovery_code_hashes,
+		last_used_step = EXCLUDED.last_used_step,
+		date_created = EXCLUDED.date_created`,
+		putRow,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save mfa enrollment: %w", err)
+	}
+
+	if err := row.Close(); err != nil {
+		return fmt.Errorf("failed to save mfa enrollment: %w", err)
+	}
+
+	return nil
+}
+
+func (d *Database) MarkMFAStepUsed(ctx context.Context, userID string, step int64) (bool, error) {
+	result, err := d.conn(ctx).ExecContext(
+		ctx,
+		`UPDATE user_mfa SET last_used_step = $2 WHERE user_id = $1 AND last_used_step < $2`,
+		userID,
+		step,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to mark totp step as used: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to mark totp step as used: %w", err)
+	}
+
+	return affected == 1, nil
+}
+
+// RemoveMFARecoveryCode - cuts the hash out of the comma separated list in the row itself, so that of two
+// logins with the same recovery code only the first one finds it there
+func (d *Database) RemoveMFARecoveryCode(ctx context.Context, userID string, hash string) (bool, error) {
+	result, err := d.conn(ctx).ExecContext(
+		ctx,
+		`UPDATE user_mfa SET recovery_code_hashes = CASE
+			WHEN recovery_code_hashes = $2 THEN ''
+			ELSE substr(
+				replace(',' || recovery_code_hashes || ',', ',' || $2 || ',', ','),
+				2,
+				length(recovery_code_hashes) - length($2) - 1
+			)
+		END
+		WHERE user_id = $1 AND ',' || recovery_code_hashes || ',' LIKE '%,' || $2 || ',%'`,
+		userID,
+		hash,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to remove recovery code: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to remove recovery code: %w", err)
+	}
+
+	return affected == 1, nil
+}
+
+func (d *Database) DeleteMFA(ctx context.Context, userID string) error {
+	_, err := d.conn(ctx).ExecContext(
+		ctx,
+		`DELETE FROM user_mfa WHERE user_id = $1`,
+		userID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to delete mfa enrollment from database: %w", err)
+	}
+
+	return nil
+}
@@ -3,9 +3,12 @@ package db
 import (
 	"errors"
 	"fmt"
+	"os"
 
 	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database"
 	"github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/database/sqlite"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 	_ "github.com/lib/pq"
 )
@@ -13,16 +16,7 @@ import (
 func (d *Database) MigrateDB() error {
 	fmt.Println(("migrating our database"))
 
-	driver, err := postgres.WithInstance(d.Client.DB, &postgres.Config{})
-	if err != nil {
-		return fmt.Errorf("could not create the postgres drive: %w", err)
-	}
-
-	m, err := migrate.NewWithDatabaseInstance(
-		"file:///migrations",
-		"postgres",
-		driver,
-	)
+	m, err := d.newMigrate()
 
 	if err != nil {
 		fmt.Println(err)
@@ -39,3 +33,32 @@ func (d *Database) MigrateDB() error {
 
 	return nil
 }
+
+// newMigrate - sqlite has its own migration set in migrations/sqlite, as its DDL differs from Postgres
+func (d *Database) newMigrate() (*migrate.Migrate, error) {
+	var driver database.Driver
+	var err error
+	sourceURL := "file://" + migrationsDir()
+
+	switch d.Client.DriverName() {
+	case "sqlite":
+		driver, err = sqlite.WithInstance(d.Client.DB, &sqlite.Config{})
+		sourceURL += "/sqlite"
+	default:
+		driver, err = postgres.WithInstance(d.Client.DB, &postgres.Config{})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not create the %s drive: %w", d.Client.DriverName(), err)
+	}
+
+	return migrate.NewWithDatabaseInstance(sourceURL, d.Client.DriverName(), driver)
+}
+
+// migrationsDir - where the migration files are, /migrations in the container unless MIGRATIONS_DIR says otherwise
+func migrationsDir() string {
+	if dir := os.Getenv("MIGRATIONS_DIR"); dir != "" {
+		return dir
+	}
+
+	return "/migrations"
+}
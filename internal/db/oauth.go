@@ -0,0 +1,336 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	uuid "github.com/satori/go.uuid"
+	"github.com/yuchida-tamu/git-workout-api/internal/auth"
+)
+
+type OAuthClientRow struct {
+	ID           string
+	OwnerID      string
+	Name         string
+	SecretHash   string
+	RedirectURIs string
+	Scopes       string
+	DateCreated  time.Time
+}
+
+type AuthorizationCodeRow struct {
+	ID            string
+	CodeHash      string
+	ClientID      string
+	UserID        string
+	RedirectURI   string
+	Scopes        string
+	CodeChallenge string
+	Used          bool
+	SessionID     sql.NullString
+	ExpiresAt     time.Time
+	DateCreated   time.Time
+}
+
+type ConsentRow struct {
+	UserID      string
+	ClientID    string
+	Scopes      string
+	DateCreated time.Time
+}
+
+func convertOAuthClientRowToOAuthClient(row OAuthClientRow) auth.OAuthClient {
+	return auth.OAuthClient{
+		ID:           row.ID,
+		OwnerID:      row.OwnerID,
+		Name:         row.Name,
+		SecretHash:   row.SecretHash,
+		RedirectURIs: strings.Fields(row.RedirectURIs),
+		Scopes:       splitAuthScopes(row.Scopes),
+		DateCreated:  row.DateCreated,
+	}
+}
+
+func (d *Database) PostOAuthClient(ctx context.Context, client auth.OAuthClient) (auth.OAuthClient, error) {
+	client.ID = uuid.NewV4().String()
+	postRow := OAuthClientRow{
+		ID:         client.ID,
+		OwnerID:    client.OwnerID,
+		Name:       client.Name,
+		SecretHash: client.SecretHash,
+		// redirect uris cannot contain spaces, so they are stored space separated
+		RedirectURIs: strings.Join(client.RedirectURIs, " "),
+		Scopes:       joinAuthScopes(client.Scopes),
+		DateCreated:  client.DateCreated,
+	}
+
+	row, err := sqlx.NamedQueryContext(
+		ctx,
+		d.conn(ctx),
+		`INSERT INTO oauth_clients
+		(id, owner_id, name, secret_hash, redirect_uris, scopes, date_created)
+		VALUES
+		(:id, :ownerid, :name, :secrethash, :redirecturis, :scopes, :datecreated)`,
+		postRow,
+	)
+	if err != nil {
+		return auth.OAuthClient{}, fmt.Errorf("failed to insert oauth client: %w", err)
+	}
+
+	if err := row.Close(); err != nil {
+		return auth.OAuthClient{}, fmt.Errorf("failed to insert oauth client: %w", err)
+	}
+
+	return client, nil
+}
+
+func (d *Database) GetOAuthClient(ctx context.Context, ID string) (auth.OAuthClient, error) {
+	var clientRow OAuthClientRow
+
+	row := d.conn(ctx).QueryRowContext(
+		ctx,
+		`SELECT id, owner_id, name, secret_hash, redirect_uris, scopes, date_created
+		FROM oauth_clients
+		WHERE id = $1`,
+		ID,
+	)
+
+	err := row.Scan(
+		&clientRow.ID,
+		&clientRow.OwnerID,
+		&clientRow.Name,
+		&clientRow.SecretHash,
+		&clientRow.RedirectURIs,
+		&clientRow.Scopes,
+		&clientRow.DateCreated,
+	)
+	if err != nil {
+		return auth.OAuthClient{}, fmt.Errorf("error fetching the oauth client by id: %w", notFound(err, auth.ErrNotFound))
+	}
+
+	return convertOAuthClientRowToOAuthClient(clientRow), nil
+}
+
+func (d *Database) GetOAuthClientsByOwner(ctx context.Context, ownerID string) ([]auth.OAuthClient, error) {
+	clients := []auth.OAuthClient{}
+	rows, err := d.conn(ctx).QueryContext(
+		ctx,
+		`SELECT id, owner_id, name, secret_hash, redirect_uris, scopes, date_created
+		FROM oauth_clients
+		WHERE owner_id = $1
+		ORDER BY date_created`,
+		ownerID,
+	)
+	if err != nil {
+		return []auth.OAuthClient{}, fmt.Errorf("error fetching oauth clients by owner id: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var clientRow OAuthClientRow
+		err := rows.Scan(
+			&clientRow.ID,
+			&clientRow.OwnerID,
+			&clientRow.Name,
+			&clientRow.SecretHash,
+			&clientRow.RedirectURIs,
+			&clientRow.Scopes,
+			&clientRow.DateCreated,
+		)
+		if err != nil {
+			return []auth.OAuthClient{}, fmt.Errorf("error fetching oauth clients by owner id: %w", err)
+		}
+
+		clients = append(clients, convertOAuthClientRowToOAuthClient(clientRow))
+	}
+
+	return clients, nil
+}
+
+func (d *Database) DeleteOAuthClient(ctx context.Context, ID string) error {
+	_, err := d.conn(ctx).ExecContext(
+		ctx,
+		`DELETE FROM oauth_clients WHERE id = $1`,
+		ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to delete oauth client: %w", err)
+	}
+
+	return nil
+}
+
+func (d *Database) PostAuthorizationCode(ctx context.Context, code auth.AuthorizationCode) (auth.AuthorizationCode, error) {
+	code.ID = uuid.NewV4().String()
+	postRow := AuthorizationCodeRow{
+		ID:            code.ID,
+		CodeHash:      code.CodeHash,
+		ClientID:      code.ClientID,
+		UserID:        code.UserID,
+		RedirectURI:   code.RedirectURI,
+		Scopes:        joinAuthScopes(code.Scopes),
+		CodeChallenge: code.CodeChallenge,
+		Used:          code.Used,
+		ExpiresAt:     code.ExpiresAt,
+		DateCreated:   code.DateCreated,
+	}
+
+	row, err := sqlx.NamedQueryContext(
+		ctx,
+		d.conn(ctx),
+		`INSERT INTO oauth_authorization_codes
+		(id, code_hash, client_id, user_id, redirect_uri, scopes, code_challenge, used, expires_at, date_created)
+		VALUES
+		(:id, :codehash, :clientid, :userid, :redirecturi, :scopes, :codechallenge, :used, :expiresat, :datecreated)`,
+		postRow,
+	)
+	if err != nil {
+		return auth.AuthorizationCode{}, fmt.Errorf("failed to insert authorization code: %w", err)
+	}
+
+	if err := row.Close(); err != nil {
+		return auth.AuthorizationCode{}, fmt.Errorf("failed to insert authorization code: %w", err)
+	}
+
+	return code, nil
+}
+
+func (d *Database) GetAuthorizationCodeByHash(ctx context.Context, hash string) (auth.AuthorizationCode, error) {
+	var codeRow AuthorizationCodeRow
+
+	row := d.conn(ctx).QueryRowContext(
+		ctx,
+		`SELECT id, code_hash, client_id, user_id, redirect_uri, scopes, code_challenge, used, session_id, expires_at, date_created
+		FROM oauth_authorization_codes
+		WHERE code_hash = $1`,
+		hash,
+	)
+
+	err := row.Scan(
+		&codeRow.ID,
+		&codeRow.CodeHash,
+		&codeRow.ClientID,
+		&codeRow.UserID,
+		&codeRow.RedirectURI,
+		&codeRow.Scopes,
+		&codeRow.CodeChallenge,
+		&codeRow.Used,
+		&codeRow.SessionID,
+		&codeRow.ExpiresAt,
+		&codeRow.DateCreated,
+	)
+	if err != nil {
+		return auth.AuthorizationCode{}, fmt.Errorf("error fetching the authorization code by hash: %w", notFound(err, auth.ErrNotFound))
+	}
+
+	return auth.AuthorizationCode{
+		ID:            codeRow.ID,
+		CodeHash:      codeRow.CodeHash,
+		ClientID:      codeRow.ClientID,
+		UserID:        codeRow.UserID,
+		RedirectURI:   codeRow.RedirectURI,
+		Scopes:        splitAuthScopes(codeRow.Scopes),
+		CodeChallenge: codeRow.CodeChallenge,
+		Used:          codeRow.Used,
+		SessionID:     codeRow.SessionID.String,
+		ExpiresAt:     codeRow.ExpiresAt,
+		DateCreated:   codeRow.DateCreated,
+	}, nil
+}
+
+func (d *Database) MarkAuthorizationCodeUsed(ctx context.Context, ID string) (bool, error) {
+	result, err := d.conn(ctx).ExecContext(
+		ctx,
+		`UPDATE oauth_authorization_codes SET used = true WHERE id = $1 AND used = false`,
+		ID,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to mark authorization code as used: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to mark authorization code as used: %w", err)
+	}
+
+	return affected == 1, nil
+}
+
+func (d *Database) SetAuthorizationCodeSession(ctx context.Context, ID string, sessionID string) error {
+	_, err := d.conn(ctx).ExecContext(
+		ctx,
+		`UPDATE oauth_authorization_codes SET session_id = $1 WHERE id = $2`,
+		sessionID,
+		ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to set the session of the authorization code: %w", err)
+	}
+
+	return nil
+}
+
+func (d *Database) GetConsent(ctx context.Context, userID string, clientID string) (auth.Consent, error) {
+	var consentRow ConsentRow
+
+	row := d.conn(ctx).QueryRowContext(
+		ctx,
+		`SELECT user_id, client_id, scopes, date_created
+		FROM oauth_consents
+		WHERE user_id = $1 AND client_id = $2`,
+		userID,
+		clientID,
+	)
+
+	err := row.Scan(
+		&consentRow.UserID,
+		&consentRow.ClientID,
+		&consentRow.Scopes,
+		&consentRow.DateCreated,
+	)
+	if err != nil {
+		return auth.Consent{}, fmt.Errorf("error fetching the consent: %w", notFound(err, auth.ErrNotFound))
+	}
+
+	return auth.Consent{
+		UserID:      consentRow.UserID,
+		ClientID:    consentRow.ClientID,
+		Scopes:      splitAuthScopes(consentRow.Scopes),
+		DateCreated: consentRow.DateCreated,
+	}, nil
+}
+
+func (d *Database) PutConsent(ctx context.Context, consent auth.Consent) error {
+	putRow := ConsentRow{
+		UserID:      consent.UserID,
+		ClientID:    consent.ClientID,
+		Scopes:      joinAuthScopes(consent.Scopes),
+		DateCreated: consent.DateCreated,
+	}
+
+	row, err := sqlx.NamedQueryContext(
+		ctx,
+		d.conn(ctx),
+		`INSERT INTO oauth_consents
+		(user_id, client_id, scopes, date_created)
+		VALUES
+		(:userid, :clientid, :scopes, :datecreated)
+		ON CONFLICT (user_id, client_id) DO UPDATE SET
+		scopes = EXCLUDED.scopes,
+		date_created = EXCLUDED.date_created`,
+		putRow,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save consent: %w", err)
+	}
+
+	if err := row.Close(); err != nil {
+		return fmt.Errorf("failed to save consent: %w", err)
+	}
+
+	return nil
+}
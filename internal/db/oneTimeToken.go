@@ -0,0 +1,124 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	uuid "github.com/satori/go.uuid"
+	"github.com/yuchida-tamu/git-workout-api/internal/auth"
+)
+
+type OneTimeTokenRow struct {
+	ID          string
+	UserID      string
+	Purpose     string
+	TokenHash   string
+	Used        bool
+	ExpiresAt   time.Time
+	DateCreated time.Time
+}
+
+func convertOneTimeTokenRowToOneTimeToken(row OneTimeTokenRow) auth.OneTimeToken {
+	return auth.OneTimeToken{
+		ID:          row.ID,
+		UserID:      row.UserID,
+		Purpose:     auth.Purpose(row.Purpose),
+		TokenHash:   row.TokenHash,
+		Used:        row.Used,
+		ExpiresAt:   row.ExpiresAt,
+		DateCreated: row.DateCreated,
+	}
+}
+
+func (d *Database) PostOneTimeToken(ctx context.Context, token auth.OneTimeToken) (auth.OneTimeToken, error) {
+	token.ID = uuid.NewV4().String()
+	postRow := OneTimeTokenRow{
+		ID:          token.ID,
+		UserID:      token.UserID,
+		Purpose:     string(token.Purpose),
+		TokenHash:   token.TokenHash,
+		Used:        token.Used,
+		ExpiresAt:   token.ExpiresAt,
+		DateCreated: token.DateCreated,
+	}
+
+	row, err := sqlx.NamedQueryContext(
+		ctx,
+		d.conn(ctx),
+		`INSERT INTO one_time_tokens
+		(id, user_id, purpose, token_hash, used, expires_at, date_created)
+		VALUES
+		(:id, :userid, :purpose, :tokenhash, :used, :expiresat, :datecreated)`,
+		postRow,
+	)
+	if err != nil {
+		return auth.OneTimeToken{}, fmt.Errorf("failed to insert one-time token: %w", err)
+	}
+
+	if err := row.Close(); err != nil {
+		return auth.OneTimeToken{}, fmt.Errorf("failed to insert one-time token: %w", err)
+	}
+
+	return token, nil
+}
+
+func (d *Database) GetOneTimeTokenByHash(ctx context.Context, hash string) (auth.OneTimeToken, error) {
+	var tokenRow OneTimeTokenRow
+
+	row := d.conn(ctx).QueryRowContext(
+		ctx,
+		`SELECT id, user_id, purpose, token_hash, used, expires_at, date_created
+		FROM one_time_tokens
+		WHERE token_hash = $1`,
+		hash,
+	)
+
+	err := row.Scan(
+		&tokenRow.ID,
+		&tokenRow.UserID,
+		&tokenRow.Purpose,
+		&tokenRow.TokenHash,
+		&tokenRow.Used,
+		&tokenRow.ExpiresAt,
+		&tokenRow.DateCreated,
+	)
+	if err != nil {
+		return auth.OneTimeToken{}, fmt.Errorf("error fetching the one-time token by hash: %w", notFound(err, auth.ErrNotFound))
+	}
+
+	return convertOneTimeTokenRowToOneTimeToken(tokenRow), nil
+}
+
+func (d *Database) MarkOneTimeTokenUsed(ctx context.Context, ID string) (bool, error) {
+	result, err := d.conn(ctx).ExecContext(
+		ctx,
+		`UPDATE one_time_tokens SET used = true WHERE id = $1 AND used = false`,
+		ID,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to mark one-time token as used: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to mark one-time token as used: %w", err)
+	}
+
+	return affected == 1, nil
+}
+
+func (d *Database) DeleteOneTimeTokensByUser(ctx context.Context, userID string, purpose auth.Purpose) error {
+	_, err := d.conn(ctx).ExecContext(
+		ctx,
+		`DELETE FROM one_time_tokens WHERE user_id = $1 AND purpose = $2`,
+		userID,
+		string(purpose),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to delete one-time tokens from database: %w", err)
+	}
+
+	return nil
+}
@@ -4,31 +4,39 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/jmoiron/sqlx"
 	uuid "github.com/satori/go.uuid"
 	"github.com/yuchida-tamu/git-workout-api/internal/record"
 )
 
 type RecordRow struct {
-	ID          string
-	DateCreated string
-	MessageBody string
-	Author      string
+	ID          string `db:"id"`
+	DateCreated string `db:"date_created"`
+	MessageBody string `db:"message_body"`
+	Author      string `db:"author"`
+	Version     int    `db:"version"`
 }
 
+// recordColumns - the columns a RecordRow is read from, aliased like userColumns
+const recordColumns = `id AS id, date_created AS date_created, message_body AS message_body, author AS author,
+	version AS version`
+
 func convertRecordRowToRecord(row RecordRow) record.Record {
 	return record.Record{
 		ID:          row.ID,
 		DateCreated: row.DateCreated,
 		MessageBody: row.MessageBody,
 		Author:      row.Author,
+		Version:     row.Version,
 	}
 }
 
 func (d *Database) GetRecordsByAuthor(ctx context.Context, ID string) ([]record.Record, error) {
-	var records []record.Record
-	rows, err := d.Client.QueryContext(
+	var recordRows []RecordRow
+	err := d.conn(ctx).SelectContext(
 		ctx,
-		`SELECT *
+		&recordRows,
+		`SELECT `+recordColumns+`
 		FROM records
 		WHERE author = $1`,
 		ID,
@@ -37,20 +45,9 @@ func (d *Database) GetRecordsByAuthor(ctx context.Context, ID string) ([]record.
 		return []record.Record{}, fmt.Errorf("error fetching records by author id: %w", err)
 	}
 
-	for rows.Next() {
-		var ID, dateCreated, messageBody, author string
-		err := rows.Scan(&ID, &dateCreated, &messageBody, &author)
-		if err != nil {
-			return []record.Record{}, fmt.Errorf("error fetching records by author id: %w", err)
-		}
-
-		records = append(records,
-			record.Record{
-				ID:          ID,
-				DateCreated: dateCreated,
-				MessageBody: messageBody,
-				Author:      author,
-			})
+	var records []record.Record
+	for _, recordRow := range recordRows {
+		records = append(records, convertRecordRowToRecord(recordRow))
 	}
 
 	return records, nil
@@ -59,15 +56,14 @@ func (d *Database) GetRecordsByAuthor(ctx context.Context, ID string) ([]record.
 func (d *Database) GetRecordById(ctx context.Context, ID string) (record.Record, error) {
 	var recordRow RecordRow
 
-	row := d.Client.QueryRowContext(
+	err := d.conn(ctx).GetContext(
 		ctx,
-		`SELECT * FROM records WHERE id = $1`,
+		&recordRow,
+		`SELECT `+recordColumns+` FROM records WHERE id = $1`,
 		ID,
 	)
-
-	err := row.Scan(&recordRow.ID, &recordRow.DateCreated, &recordRow.MessageBody, &recordRow.Author)
 	if err != nil {
-		return record.Record{}, fmt.Errorf("error fetching the record by id: %w", err)
+		return record.Record{}, fmt.Errorf("error fetching the record by id: %w", notFound(err, record.ErrNotFound))
 	}
 
 	return convertRecordRowToRecord(recordRow), nil
@@ -75,28 +71,31 @@ func (d *Database) GetRecordById(ctx context.Context, ID string) (record.Record,
 
 func (d *Database) PostRecord(ctx context.Context, rcd record.Record) (record.Record, error) {
 	rcd.ID = uuid.NewV4().String()
+	rcd.Version = 1
 	postRow := RecordRow{
 		ID:          rcd.ID,
 		DateCreated: rcd.DateCreated,
 		MessageBody: rcd.MessageBody,
 		Author:      rcd.Author,
+		Version:     rcd.Version,
 	}
 
-	row, err := d.Client.NamedQueryContext(
+	row, err := sqlx.NamedQueryContext(
 		ctx,
+		d.conn(ctx),
 		`INSERT INTO records
-		(id, date_created, message_body, author)
+		(id, date_created, message_body, author, version)
 		VALUES
-		(:id, :datecreated, :messagebody, :author)`,
+		(:id, :date_created, :message_body, :author, :version)`,
 		postRow,
 	)
 
 	if err != nil {
-		return record.Record{}, fmt.Errorf("failed to insert record: %w", err)
+		return record.Record{}, fmt.Errorf("failed to insert record: %w", invalidReference(err, record.ErrValidation))
 	}
 
 	if err := row.Close(); err != nil {
-		return record.Record{}, fmt.Errorf("failed to insert record: %w", err)
+		return record.Record{}, fmt.Errorf("failed to insert record: %w", invalidReference(err, record.ErrValidation))
 	}
 
 	return rcd, nil
@@ -108,35 +107,73 @@ func (d *Database) UpdateRecord(ctx context.Context, ID string, rcd record.Recor
 		DateCreated: rcd.DateCreated,
 		MessageBody: rcd.MessageBody,
 		Author:      rcd.Author,
+		Version:     rcd.Version,
 	}
 
-	row, err := d.Client.NamedQueryContext(
+	rows, err := sqlx.NamedQueryContext(
 		ctx,
+		d.conn(ctx),
 		`UPDATE records SET
-		date_created = :datecreated,
-		message_body = :messagebody,
-		WHERE id = :id`,
+		date_created = :date_created,
+		message_body = :message_body,
+		version = version + 1
+		WHERE id = :id AND (:version = 0 OR version = :version)
+		RETURNING `+recordColumns,
 		recordRow,
 	)
 	if err != nil {
 		return record.Record{}, fmt.Errorf("failed to update record: %w", err)
 	}
-	if err := row.Close(); err != nil {
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return record.Record{}, fmt.Errorf("failed to update record: %w", err)
+		}
+		rows.Close()
+		// nothing was updated: the record is gone or at another version
+		current, err := d.GetRecordById(ctx, ID)
+		if err != nil {
+			return record.Record{}, fmt.Errorf("failed to update record: %w", err)
+		}
+		return record.Record{}, fmt.Errorf("failed to update record: %w: it is at version %d", record.ErrVersionMismatch, current.Version)
+	}
+
+	var updatedRow RecordRow
+	if err := rows.StructScan(&updatedRow); err != nil {
+		return record.Record{}, fmt.Errorf("failed to update record: %w", err)
+	}
+	if err := rows.Close(); err != nil {
 		return record.Record{}, fmt.Errorf("failed to update record: %w", err)
 	}
 
-	return convertRecordRowToRecord(recordRow), nil
+	return convertRecordRowToRecord(updatedRow), nil
 }
 
-func (d *Database) DeleteRecord(ctx context.Context, ID string) error {
-	_, err := d.Client.ExecContext(
+// DeleteRecord - deletes the record if it is still at version, any version when that is 0
+func (d *Database) DeleteRecord(ctx context.Context, ID string, version int) error {
+	result, err := d.conn(ctx).ExecContext(
 		ctx,
-		`DELETE FROM records WHERE id = $1`,
+		`DELETE FROM records WHERE id = $1 AND ($2 = 0 OR version = $2)`,
 		ID,
+		version,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to delete record from database: %w", err)
 	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to delete record from database: %w", err)
+	}
+
+	if deleted == 0 {
+		// nothing was deleted: the record is gone or at another version
+		current, err := d.GetRecordById(ctx, ID)
+		if err != nil {
+			return fmt.Errorf("failed to delete record from database: %w", err)
+		}
+		return fmt.Errorf("failed to delete record from database: %w: it is at version %d", record.ErrVersionMismatch, current.Version)
+	}
 
 	return nil
 }
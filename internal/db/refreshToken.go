@@ -0,0 +1,128 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	uuid "github.com/satori/go.uuid"
+	"github.com/yuchida-tamu/git-workout-api/internal/auth"
+)
+
+type RefreshTokenRow struct {
+	ID          string
+	UserID      string
+	FamilyID    string
+	TokenHash   string
+	Used        bool
+	Revoked     bool
+	ExpiresAt   time.Time
+	DateCreated time.Time
+}
+
+func convertRefreshTokenRowToRefreshToken(row RefreshTokenRow) auth.RefreshToken {
+	return auth.RefreshToken{
+		ID:          row.ID,
+		UserID:      row.UserID,
+		FamilyID:    row.FamilyID,
+		TokenHash:   row.TokenHash,
+		Used:        row.Used,
+		Revoked:     row.Revoked,
+		ExpiresAt:   row.ExpiresAt,
+		DateCreated: row.DateCreated,
+	}
+}
+
+func (d *Database) PostRefreshToken(ctx context.Context, token auth.RefreshToken) (auth.RefreshToken, error) {
+	token.ID = uuid.NewV4().String()
+	postRow := RefreshTokenRow{
+		ID:          token.ID,
+		UserID:      token.UserID,
+		FamilyID:    token.FamilyID,
+		TokenHash:   token.TokenHash,
+		Used:        token.Used,
+		Revoked:     token.Revoked,
+		ExpiresAt:   token.ExpiresAt,
+		DateCreated: token.DateCreated,
+	}
+
+	row, err := sqlx.NamedQueryContext(
+		ctx,
+		d.conn(ctx),
+		`INSERT INTO refresh_tokens
+		(id, user_id, family_id, token_hash, used, revoked, expires_at, date_created)
+		VALUES
+		(:id, :userid, :familyid, :tokenhash, :used, :revoked, :expiresat, :datecreated)`,
+		postRow,
+	)
+	if err != nil {
+		return auth.RefreshToken{}, fmt.Errorf("failed to insert refresh token: %w", err)
+	}
+
+	if err := row.Close(); err != nil {
+		return auth.RefreshToken{}, fmt.Errorf("failed to insert refresh token: %w", err)
+	}
+
+	return token, nil
+}
+
+func (d *Database) GetRefreshTokenByHash(ctx context.Context, hash string) (auth.RefreshToken, error) {
+	var tokenRow RefreshTokenRow
+
+	row := d.conn(ctx).QueryRowContext(
+		ctx,
+		`SELECT id, user_id, family_id, token_hash, used, revoked, expires_at, date_created
+		FROM refresh_tokens
+		WHERE token_hash = $1`,
+		hash,
+	)
+
+	err := row.Scan(
+		&tokenRow.ID,
+		&tokenRow.UserID,
+		&tokenRow.FamilyID,
+		&tokenRow.TokenHash,
+		&tokenRow.Used,
+		&tokenRow.Revoked,
+		&tokenRow.ExpiresAt,
+		&tokenRow.DateCreated,
+	)
+	if err != nil {
+		return auth.RefreshToken{}, fmt.Errorf("error fetching the refresh token by hash: %w", notFound(err, auth.ErrNotFound))
+	}
+
+	return convertRefreshTokenRowToRefreshToken(tokenRow), nil
+}
+
+func (d *Database) MarkRefreshTokenUsed(ctx context.Context, ID string) (bool, error) {
+	// the used = false condition makes concurrent rotations of the same token race safely
+	result, err := d.conn(ctx).ExecContext(
+		ctx,
+		`UPDATE refresh_tokens SET used = true WHERE id = $1 AND used = false`,
+		ID,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to mark refresh token as used: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to mark refresh token as used: %w", err)
+	}
+
+	return affected == 1, nil
+}
+
+func (d *Database) RevokeRefreshTokenFamily(ctx context.Context, familyID string) error {
+	_, err := d.conn(ctx).ExecContext(
+		ctx,
+		`UPDATE refresh_tokens SET revoked = true WHERE family_id = $1`,
+		familyID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to revoke refresh token family: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,189 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	uuid "github.com/satori/go.uuid"
+	"github.com/yuchida-tamu/git-workout-api/internal/auth"
+)
+
+type SessionRow struct {
+	ID          string
+	UserID      string
+	ClientID    string
+	Scopes      string
+	Device      string
+	IP          string
+	UserAgent   string
+	Revoked     bool
+	LastUsed    time.Time
+	DateCreated time.Time
+}
+
+func convertSessionRowToSession(row SessionRow) auth.Session {
+	return auth.Session{
+		ID:          row.ID,
+		UserID:      row.UserID,
+		ClientID:    row.ClientID,
+		Scopes:      splitAuthScopes(row.Scopes),
+		Device:      row.Device,
+		IP:          row.IP,
+		UserAgent:   row.UserAgent,
+		Revoked:     row.Revoked,
+		LastUsed:    row.LastUsed,
+		DateCreated: row.DateCreated,
+	}
+}
+
+func (d *Database) PostSession(ctx context.Context, session auth.Session) (auth.Session, error) {
+	session.ID = uuid.NewV4().String()
+	postRow := SessionRow{
+		ID:          session.ID,
+		UserID:      session.UserID,
+		ClientID:    session.ClientID,
+		Scopes:      joinAuthScopes(session.Scopes),
+		Device:      session.Device,
+		IP:          session.IP,
+		UserAgent:   session.UserAgent,
+		Revoked:     session.Revoked,
+		LastUsed:    session.LastUsed,
+		DateCreated: session.DateCreated,
+	}
+
+	row, err := sqlx.NamedQueryContext(
+		ctx,
+		d.conn(ctx),
+		`INSERT INTO sessions
+		(id, user_id, client_id, scopes, device, ip, user_agent, revoked, last_used, date_created)
+		VALUES
+		(:id, :userid, :clientid, :scopes, :device, :ip, :useragent, :revoked, :lastused, :datecreated)`,
+		postRow,
+	)
+	if err != nil {
+		return auth.Session{}, fmt.Errorf("failed to insert session: %w", err)
+	}
+
+	if err := row.Close(); err != nil {
+		return auth.Session{}, fmt.Errorf("failed to insert session: %w", err)
+	}
+
+	return session, nil
+}
+
+func (d *Database) GetSession(ctx context.Context, ID string) (auth.Session, error) {
+	var sessionRow SessionRow
+
+	row := d.conn(ctx).QueryRowContext(
+		ctx,
+		`SELECT id, user_id, client_id, scopes, device, ip, user_agent, revoked, last_used, date_created
+		FROM sessions
+		WHERE id = $1`,
+		ID,
+	)
+
+	err := row.Scan(
+		&sessionRow.ID,
+		&sessionRow.UserID,
+		&sessionRow.ClientID,
+		&sessionRow.Scopes,
+		&sessionRow.Device,
+		&sessionRow.IP,
+		&sessionRow.UserAgent,
+		&sessionRow.Revoked,
+		&sessionRow.LastUsed,
+		&sessionRow.DateCreated,
+	)
+	if err != nil {
+		return auth.Session{}, fmt.Errorf("error fetching the session by id: %w", notFound(err, auth.ErrNotFound))
+	}
+
+	return convertSessionRowToSession(sessionRow), nil
+}
+
+func (d *Database) GetSessionsByUser(ctx context.Context, userID string) ([]auth.Session, error) {
+	sessions := []auth.Session{}
+	rows, err := d.conn(ctx).QueryContext(
+		ctx,
+		`SELECT id, user_id, client_id, scopes, device, ip, user_agent, revoked, last_used, date_created
+		FROM sessions
+		WHERE user_id = $1
+		ORDER BY last_used DESC`,
+		userID,
+	)
+	if err != nil {
+		return []auth.Session{}, fmt.Errorf("error fetching sessions by user id: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var sessionRow SessionRow
+		err := rows.Scan(
+			&sessionRow.ID,
+			&sessionRow.UserID,
+			&sessionRow.ClientID,
+			&sessionRow.Scopes,
+			&sessionRow.Device,
+			&sessionRow.IP,
+			&sessionRow.UserAgent,
+			&sessionRow.Revoked,
+			&sessionRow.LastUsed,
+			&sessionRow.DateCreated,
+		)
+		if err != nil {
+			return []auth.Session{}, fmt.Errorf("error fetching sessions by user id: %w", err)
+		}
+
+		sessions = append(sessions, convertSessionRowToSession(sessionRow))
+	}
+
+	return sessions, nil
+}
+
+func (d *Database) TouchSession(ctx context.Context, ID string, client auth.ClientInfo, lastUsed time.Time) error {
+	_, err := d.conn(ctx).ExecContext(
+		ctx,
+		`UPDATE sessions SET
+		ip = $1,
+		user_agent = $2,
+		last_used = $3
+		WHERE id = $4`,
+		client.IP,
+		client.UserAgent,
+		lastUsed,
+		ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update session: %w", err)
+	}
+
+	return nil
+}
+
+func (d *Database) RevokeSession(ctx context.Context, ID string) error {
+	_, err := d.conn(ctx).ExecContext(
+		ctx,
+		`UPDATE sessions SET revoked = true WHERE id = $1`,
+		ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+
+	return nil
+}
+
+func (d *Database) RevokeSessionsByClient(ctx context.Context, clientID string) error {
+	_, err := d.conn(ctx).ExecContext(
+		ctx,
+		`UPDATE sessions SET revoked = true WHERE client_id = $1`,
+		clientID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to revoke sessions of client: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,29 @@
+package db
+
+import (
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	_ "modernc.org/sqlite"
+)
+
+// NewSQLiteDatabase - opens, or creates, the single-file database at path; the queries are shared with
+// Postgres, only the migrations differ
+func NewSQLiteDatabase(path string) (*Database, error) {
+	connectionString := fmt.Sprintf(
+		"file:%s?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_time_format=sqlite",
+		path,
+	)
+
+	dbConn, err := sqlx.Connect("sqlite", connectionString)
+	if err != nil {
+		return &Database{}, fmt.Errorf("could not open the sqlite database: %w", err)
+	}
+	// sqlite allows a single writer, so one connection avoids "database is locked" errors under load and
+	// keeps an in-memory database from being split across connections
+	dbConn.SetMaxOpenConns(1)
+
+	return &Database{
+		Client: dbConn,
+	}, nil
+}
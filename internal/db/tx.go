@@ -0,0 +1,63 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// queryer - what a query runs on: the transaction of the context when there is one, the database otherwise
+type queryer interface {
+	sqlx.ExtContext
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+}
+
+type txKey struct{}
+
+// txInContext - the transaction ctx belongs to, as long as it was begun on this database
+type txInContext struct {
+	database *Database
+	tx       *sqlx.Tx
+}
+
+func (d *Database) conn(ctx context.Context) queryer {
+	if t, ok := ctx.Value(txKey{}).(txInContext); ok && t.database == d {
+		return t.tx
+	}
+
+	return d.Client
+}
+
+// WithTx - runs fn in a transaction, which every call passing on the context fn gets takes part in. It commits
+// when fn returns nil and rolls back when it returns an error or panics; a WithTx inside fn joins the
+// transaction already in progress. sqlite has a single connection, so calls with another context block until
+// the transaction is over.
+func (d *Database) WithTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if t, ok := ctx.Value(txKey{}).(txInContext); ok && t.database == d {
+		return fn(ctx)
+	}
+
+	tx, err := d.Client.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	// leaving fn by a panic neither commits nor rolls back; after either of them this is a no-op
+	defer func() { _ = tx.Rollback() }()
+
+	if err := fn(context.WithValue(ctx, txKey{}, txInContext{database: d, tx: tx})); err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			return fmt.Errorf("failed to roll back transaction after %v: %w", err, rollbackErr)
+		}
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
@@ -2,57 +2,66 @@ package db
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
+	"time"
 
+	"github.com/jmoiron/sqlx"
 	uuid "github.com/satori/go.uuid"
 	appUser "github.com/yuchida-tamu/git-workout-api/internal/user"
-	"golang.org/x/crypto/bcrypt"
 )
 
 type UserService interface {
 }
 
 type UserRow struct {
-	ID       string
-	Username string
-	Password string
+	ID       string `db:"id"`
+	Username string `db:"username"`
+	Password string `db:"password"`
+	Role     string `db:"role"`
+	Disabled bool   `db:"disabled"`
+	// Email is NULL for accounts created before email addresses existed, so it is read with COALESCE
+	Email         string       `db:"email"`
+	EmailVerified bool         `db:"email_verified"`
+	DeleteAfter   sql.NullTime `db:"delete_after"`
+	Version       int          `db:"version"`
 }
 
+// userColumns - the columns a UserRow is read from; naming them keeps reads working when a migration adds one.
+// They are aliased because sqlite names result columns in the case the schema declared them, Postgres in
+// lower case.
+const userColumns = `id AS id, username AS username, password AS password, role AS role, disabled AS disabled,
+	COALESCE(email, '') AS email, email_verified AS email_verified, delete_after AS delete_after,
+	version AS version`
+
 func convertUserRowToUser(row UserRow) appUser.User {
 	return appUser.User{
-		ID:       row.ID,
-		Username: row.Username,
-		Password: row.Password,
+		ID:            row.ID,
+		Username:      row.Username,
+		Password:      row.Password,
+		Role:          appUser.Role(row.Role),
+		Disabled:      row.Disabled,
+		Email:         row.Email,
+		EmailVerified: row.EmailVerified,
+		DeleteAfter:   row.DeleteAfter.Time,
+		Version:       row.Version,
 	}
 }
 
-func hashPassword(password string) (string, error) {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), 14)
-	return string(bytes), err
-}
-
 func (d *Database) GetUsers(ctx context.Context) ([]appUser.User, error) {
-	var users []appUser.User
-	rows, err := d.Client.QueryContext(
+	var userRows []UserRow
+	err := d.conn(ctx).SelectContext(
 		ctx,
-		`SELECT * 
-		FROM users`,
+		&userRows,
+		`SELECT `+userColumns+` FROM users`,
 	)
-
 	if err != nil {
 		return []appUser.User{}, fmt.Errorf("error fetching the user: %w", err)
 	}
 
-	for rows.Next() {
-		var ID, Username, Password string
-
-		err := rows.Scan(&ID, &Username, &Password)
-		if err != nil {
-			return []appUser.User{}, fmt.Errorf("error fetching the user: %w", err)
-		}
-
-		users = append(users, convertUserRowToUser(UserRow{ID: ID, Username: Username, Password: Password}))
-
+	var users []appUser.User
+	for _, userRow := range userRows {
+		users = append(users, convertUserRowToUser(userRow))
 	}
 
 	return users, nil
@@ -61,14 +70,14 @@ func (d *Database) GetUsers(ctx context.Context) ([]appUser.User, error) {
 func (d *Database) GetUser(ctx context.Context, uuid string) (appUser.User, error) {
 	var userRow UserRow
 
-	row := d.Client.QueryRowContext(
+	err := d.conn(ctx).GetContext(
 		ctx,
-		`SELECT * FROM users WHERE id = $1`,
+		&userRow,
+		`SELECT `+userColumns+` FROM users WHERE id = $1`,
 		uuid,
 	)
-	err := row.Scan(&userRow.ID, &userRow.Username, &userRow.Password)
 	if err != nil {
-		return appUser.User{}, fmt.Errorf("error fetching the user by uuid: %w", err)
+		return appUser.User{}, fmt.Errorf("error fetching the user by uuid: %w", notFound(err, appUser.ErrNotFound))
 	}
 
 	return convertUserRowToUser(userRow), nil
@@ -77,15 +86,14 @@ func (d *Database) GetUser(ctx context.Context, uuid string) (appUser.User, erro
 func (d *Database) GetUserByUsername(ctx context.Context, username string) (appUser.User, error) {
 	var userRow UserRow
 
-	row := d.Client.QueryRowContext(
+	err := d.conn(ctx).GetContext(
 		ctx,
-		`SELECT * FROM users WHERE username = $1`,
+		&userRow,
+		`SELECT `+userColumns+` FROM users WHERE lower(username) = lower($1)`,
 		username,
 	)
-
-	err := row.Scan(&userRow.ID, &userRow.Username, &userRow.Password)
 	if err != nil {
-		return appUser.User{}, fmt.Errorf("error fetching the user by username: %w", err)
+		return appUser.User{}, fmt.Errorf("error fetching the user by username: %w", notFound(err, appUser.ErrNotFound))
 	}
 
 	return convertUserRowToUser(userRow), nil
@@ -93,33 +101,39 @@ func (d *Database) GetUserByUsername(ctx context.Context, username string) (appU
 
 func (d *Database) PostUser(ctx context.Context, user appUser.User) (appUser.User, error) {
 	user.ID = uuid.NewV4().String()
-	hash, err := hashPassword(user.Password)
-	if err != nil {
-		return appUser.User{}, err
+
+	if user.Role == "" {
+		user.Role = appUser.RoleUser
 	}
-	user.Password = hash
+	user.Version = 1
 
 	postRow := UserRow{
-		ID:       user.ID,
-		Username: user.Username,
-		Password: user.Password,
+		ID:            user.ID,
+		Username:      user.Username,
+		Password:      user.Password,
+		Role:          string(user.Role),
+		Disabled:      user.Disabled,
+		Email:         user.Email,
+		EmailVerified: user.EmailVerified,
+		Version:       user.Version,
 	}
 
-	row, err := d.Client.NamedQueryContext(
+	row, err := sqlx.NamedQueryContext(
 		ctx,
+		d.conn(ctx),
 		`INSERT INTO users
-		(id, username, password)
+		(id, username, password, role, disabled, email, email_verified, version)
 		VALUES
-		(:id, :username, :password)`,
+		(:id, :username, :password, :role, :disabled, NULLIF(:email, ''), :email_verified, :version)`,
 		postRow,
 	)
 
 	if err != nil {
-		return appUser.User{}, fmt.Errorf("failed to insert user: %w", err)
+		return appUser.User{}, fmt.Errorf("failed to insert user: %w", conflict(err, appUser.ErrConflict))
 	}
 
 	if err := row.Close(); err != nil {
-		return appUser.User{}, fmt.Errorf("failed to close row: %w", err)
+		return appUser.User{}, fmt.Errorf("failed to close row: %w", conflict(err, appUser.ErrConflict))
 	}
 
 	return user, nil
@@ -130,29 +144,51 @@ func (d *Database) UpdateUser(ctx context.Context, uuid string, user appUser.Use
 		ID:       uuid,
 		Username: user.Username,
 		Password: user.Password,
+		Version:  user.Version,
 	}
 
-	row, err := d.Client.NamedQueryContext(
+	rows, err := sqlx.NamedQueryContext(
 		ctx,
+		d.conn(ctx),
 		`UPDATE users SET
 		username = :username,
-		password = :password
-		WHERE id = :id`,
+		password = :password,
+		version = version + 1
+		WHERE id = :id AND (:version = 0 OR version = :version)
+		RETURNING `+userColumns,
 		userRow,
 	)
-
 	if err != nil {
+		return appUser.User{}, fmt.Errorf("failed to update user: %w", conflict(err, appUser.ErrConflict))
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return appUser.User{}, fmt.Errorf("failed to update user: %w", conflict(err, appUser.ErrConflict))
+		}
+		rows.Close()
+		// nothing was updated: the user is gone or at another version
+		current, err := d.GetUser(ctx, uuid)
+		if err != nil {
+			return appUser.User{}, fmt.Errorf("failed to update user: %w", err)
+		}
+		return appUser.User{}, fmt.Errorf("failed to update user: %w: they are at version %d", appUser.ErrVersionMismatch, current.Version)
+	}
+
+	var updatedRow UserRow
+	if err := rows.StructScan(&updatedRow); err != nil {
 		return appUser.User{}, fmt.Errorf("failed to update user: %w", err)
 	}
-	if err := row.Close(); err != nil {
-		return appUser.User{}, fmt.Errorf("failed to close row: %w", err)
+	if err := rows.Close(); err != nil {
+		return appUser.User{}, fmt.Errorf("failed to close row: %w", conflict(err, appUser.ErrConflict))
 	}
 
-	return convertUserRowToUser(userRow), nil
+	return convertUserRowToUser(updatedRow), nil
 }
 
 func (d *Database) DeleteUser(ctx context.Context, uuid string) error {
-	_, err := d.Client.ExecContext(
+	_, err := d.conn(ctx).ExecContext(
 		ctx,
 		`DELETE FROM users WHERE id = $1`,
 		uuid,
@@ -163,3 +199,194 @@ func (d *Database) DeleteUser(ctx context.Context, uuid string) error {
 
 	return nil
 }
+
+func (d *Database) UpdateUserRole(ctx context.Context, uuid string, role appUser.Role) error {
+	_, err := d.conn(ctx).ExecContext(
+		ctx,
+		`UPDATE users SET role = $1, version = version + 1 WHERE id = $2`,
+		string(role),
+		uuid,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update user role: %w", err)
+	}
+
+	return nil
+}
+
+func (d *Database) SetUserDisabled(ctx context.Context, uuid string, disabled bool) error {
+	_, err := d.conn(ctx).ExecContext(
+		ctx,
+		`UPDATE users SET disabled = $1, version = version + 1 WHERE id = $2`,
+		disabled,
+		uuid,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update user disabled flag: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateUserPassword - stores an already hashed password
+func (d *Database) UpdateUserPassword(ctx context.Context, uuid string, hash string) error {
+	_, err := d.conn(ctx).ExecContext(
+		ctx,
+		`UPDATE users SET password = $1, version = version + 1 WHERE id = $2`,
+		hash,
+		uuid,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update user password: %w", err)
+	}
+
+	return nil
+}
+
+func (d *Database) GetUserByEmail(ctx context.Context, email string) (appUser.User, error) {
+	var userRow UserRow
+
+	err := d.conn(ctx).GetContext(
+		ctx,
+		&userRow,
+		`SELECT `+userColumns+` FROM users WHERE lower(email) = lower($1)`,
+		email,
+	)
+	if err != nil {
+		return appUser.User{}, fmt.Errorf("error fetching the user by email: %w", notFound(err, appUser.ErrNotFound))
+	}
+
+	return convertUserRowToUser(userRow), nil
+}
+
+// UpdateUserEmail - changes the email address, which has to be verified again
+func (d *Database) UpdateUserEmail(ctx context.Context, uuid string, email string) error {
+	_, err := d.conn(ctx).ExecContext(
+		ctx,
+		`UPDATE users SET email = NULLIF($1, ''), email_verified = false, version = version + 1 WHERE id = $2`,
+		email,
+		uuid,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update user email: %w", conflict(err, appUser.ErrConflict))
+	}
+
+	return nil
+}
+
+func (d *Database) SetUserEmailVerified(ctx context.Context, uuid string, verified bool) error {
+	_, err := d.conn(ctx).ExecContext(
+		ctx,
+		`UPDATE users SET email_verified = $1, version = version + 1 WHERE id = $2`,
+		verified,
+		uuid,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update user email verification: %w", err)
+	}
+
+	return nil
+}
+
+// SetUserDeleteAfter - schedules the purge of the account if the user is still at version, any version when
+// that is 0; a zero time cancels it
+func (d *Database) SetUserDeleteAfter(ctx context.Context, uuid string, deleteAfter time.Time, version int) error {
+	result, err := d.conn(ctx).ExecContext(
+		ctx,
+		`UPDATE users SET delete_after = $1, version = version + 1 WHERE id = $2 AND ($3 = 0 OR version = $3)`,
+		nullTime(deleteAfter),
+		uuid,
+		version,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update user deletion time: %w", err)
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to update user deletion time: %w", err)
+	}
+
+	if updated == 0 {
+		// nothing was updated: the user is gone or at another version
+		current, err := d.GetUser(ctx, uuid)
+		if err != nil {
+			return fmt.Errorf("failed to update user deletion time: %w", err)
+		}
+		return fmt.Errorf("failed to update user deletion time: %w: they are at version %d", appUser.ErrVersionMismatch, current.Version)
+	}
+
+	return nil
+}
+
+// GetUsersDueForDeletion - the users whose deletion was scheduled for now or earlier
+func (d *Database) GetUsersDueForDeletion(ctx context.Context, now time.Time) ([]appUser.User, error) {
+	var userRows []UserRow
+	err := d.conn(ctx).SelectContext(
+		ctx,
+		&userRows,
+		`SELECT `+userColumns+` FROM users WHERE delete_after IS NOT NULL AND delete_after <= $1`,
+		now,
+	)
+	if err != nil {
+		return []appUser.User{}, fmt.Errorf("error fetching the users due for deletion: %w", err)
+	}
+
+	users := []appUser.User{}
+	for _, userRow := range userRows {
+		users = append(users, convertUserRowToUser(userRow))
+	}
+
+	return users, nil
+}
+
+// purgeUserStatements - what PurgeUser deletes before the user, in order; $1 is the user's id. Records go
+// with the user by the foreign key, but are deleted explicitly along with their comments. Sessions and tokens
+// issued to the user's OAuth clients go with the clients.
+var purgeUserStatements = []string{
+	`DELETE FROM record_comments WHERE author = $1 OR record_id IN (SELECT id FROM records WHERE author = $1)`,
+	`DELETE FROM records WHERE author = $1`,
+	`DELETE FROM coach_relationships WHERE coach_id = $1 OR athlete_id = $1`,
+	`DELETE FROM refresh_tokens WHERE user_id = $1 OR family_id IN (
+		SELECT id FROM sessions WHERE client_id IN (SELECT CAST(id AS text) FROM oauth_clients WHERE owner_id = $1))`,
+	`DELETE FROM sessions WHERE user_id = $1 OR client_id IN (SELECT CAST(id AS text) FROM oauth_clients WHERE owner_id = $1)`,
+	`DELETE FROM user_mfa WHERE user_id = $1`,
+	`DELETE FROM one_time_tokens WHERE user_id = $1`,
+	`DELETE FROM lockout_events WHERE user_id = $1`,
+	`DELETE FROM api_keys WHERE user_id = $1`,
+	`DELETE FROM oidc_logins WHERE link_user_id = $1`,
+	`DELETE FROM user_identities WHERE user_id = $1`,
+	`DELETE FROM oauth_authorization_codes WHERE user_id = $1 OR client_id IN (SELECT id FROM oauth_clients WHERE owner_id = $1)`,
+	`DELETE FROM oauth_consents WHERE user_id = $1 OR client_id IN (SELECT id FROM oauth_clients WHERE owner_id = $1)`,
+	`DELETE FROM oauth_clients WHERE owner_id = $1`,
+}
+
+// PurgeUser - deletes the user and everything stored about them but the audit events; it is meant to run in
+// a transaction, so that it never stops half way. The user goes last and only while their deletion is still
+// due: a login cancelling it in the meantime either commits first, and the purge finds nothing to delete and
+// fails, or has to wait for the purge to commit.
+func (d *Database) PurgeUser(ctx context.Context, uuid string, now time.Time) error {
+	for _, statement := range purgeUserStatements {
+		if _, err := d.conn(ctx).ExecContext(ctx, statement, uuid); err != nil {
+			return fmt.Errorf("failed to purge user from database: %w", err)
+		}
+	}
+
+	result, err := d.conn(ctx).ExecContext(
+		ctx,
+		`DELETE FROM users WHERE id = $1 AND delete_after IS NOT NULL AND delete_after <= $2`,
+		uuid,
+		now,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to purge user from database: %w", err)
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to purge user from database: %w", err)
+	}
+	if deleted == 0 {
+		return fmt.Errorf("failed to purge user from database: %w", appUser.ErrDeletionNotDue)
+	}
+
+	return nil
+}
@@ -0,0 +1,52 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// LogMailer - writes messages to a file, or to the log when no file is given, instead of sending them
+type LogMailer struct {
+	Path string
+
+	mu sync.Mutex
+}
+
+func NewLogMailer(path string) *LogMailer {
+	return &LogMailer{
+		Path: path,
+	}
+}
+
+func (m *LogMailer) Send(ctx context.Context, msg Message) error {
+	if m.Path == "" {
+		log.WithFields(
+			log.Fields{
+				"to":      msg.To,
+				"subject": msg.Subject,
+			},
+		).Info(msg.Body)
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	f, err := os.OpenFile(m.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open the mail log: %w", err)
+	}
+	defer f.Close()
+
+	_, err = fmt.Fprintf(f, "Date: %s\nTo: %s\nSubject: %s\n\n%s\n\n", time.Now().UTC().Format(time.RFC1123Z), msg.To, msg.Subject, msg.Body)
+	if err != nil {
+		return fmt.Errorf("failed to write the mail log: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,39 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+// Message - a plain text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer - sends email; SMTPMailer delivers it, LogMailer keeps it local for development and tests
+type Mailer interface {
+	Send(context.Context, Message) error
+}
+
+// NewMailerFromEnv - picks the mailer from MAILER ("smtp" or "log", the default).
+// The SMTP mailer reads SMTP_HOST, SMTP_PORT, SMTP_USERNAME, SMTP_PASSWORD and MAIL_FROM;
+// the log mailer appends to MAILER_LOG_FILE when it is set and writes to the log otherwise.
+func NewMailerFromEnv() (Mailer, error) {
+	switch os.Getenv("MAILER") {
+	case "smtp":
+		return NewSMTPMailer(
+			os.Getenv("SMTP_HOST"),
+			os.Getenv("SMTP_PORT"),
+			os.Getenv("SMTP_USERNAME"),
+			os.Getenv("SMTP_PASSWORD"),
+			os.Getenv("MAIL_FROM"),
+		)
+	case "", "log":
+		return NewLogMailer(os.Getenv("MAILER_LOG_FILE")), nil
+	default:
+		return nil, fmt.Errorf("unknown mailer: %q", os.Getenv("MAILER"))
+	}
+}
@@ -0,0 +1,62 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+)
+
+type SMTPMailer struct {
+	Addr string
+	From string
+	Auth smtp.Auth
+}
+
+func NewSMTPMailer(host string, port string, username string, password string, from string) (*SMTPMailer, error) {
+	if host == "" || from == "" {
+		return nil, errors.New("the smtp mailer needs SMTP_HOST and MAIL_FROM")
+	}
+	if port == "" {
+		port = "587"
+	}
+
+	var auth smtp.Auth
+	if username != "" {
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+
+	return &SMTPMailer{
+		Addr: net.JoinHostPort(host, port),
+		From: from,
+		Auth: auth,
+	}, nil
+}
+
+// Send - delivers the message; net/smtp upgrades to TLS when the server offers STARTTLS
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if strings.ContainsAny(msg.To, "\r\n") || strings.ContainsAny(msg.Subject, "\r\n") {
+		return errors.New("mail headers must not contain line breaks")
+	}
+
+	body := strings.Join([]string{
+		"From: " + m.From,
+		"To: " + msg.To,
+		"Subject: " + msg.Subject,
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=UTF-8",
+		"",
+		msg.Body,
+	}, "\r\n")
+
+	if err := smtp.SendMail(m.Addr, m.Auth, m.From, []string{msg.To}, []byte(body)); err != nil {
+		return fmt.Errorf("failed to send mail: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,93 @@
+package memstore
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/yuchida-tamu/git-workout-api/internal/auth"
+)
+
+func cloneAPIKey(key auth.APIKey) auth.APIKey {
+	key.Scopes = cloneScopes(key.Scopes)
+	return key
+}
+
+func (s *Store) PostAPIKey(ctx context.Context, key auth.APIKey) (auth.APIKey, error) {
+	defer s.lock(ctx)()
+
+	for _, k := range s.apiKeys {
+		if k.KeyHash == key.KeyHash {
+			return auth.APIKey{}, duplicate("failed to insert api key", "api_keys_key_hash_key")
+		}
+	}
+
+	key.ID = uuid.NewV4().String()
+	s.apiKeys = append(s.apiKeys, cloneAPIKey(key))
+	return key, nil
+}
+
+func (s *Store) getAPIKey(ctx context.Context, match func(auth.APIKey) bool) (auth.APIKey, bool) {
+	defer s.rlock(ctx)()
+
+	for _, key := range s.apiKeys {
+		if match(key) {
+			return cloneAPIKey(key), true
+		}
+	}
+	return auth.APIKey{}, false
+}
+
+func (s *Store) GetAPIKey(ctx context.Context, ID string) (auth.APIKey, error) {
+	key, ok := s.getAPIKey(ctx, func(key auth.APIKey) bool { return key.ID == ID })
+	if !ok {
+		return auth.APIKey{}, notFound("error fetching the api key by id", auth.ErrNotFound)
+	}
+	return key, nil
+}
+
+func (s *Store) GetAPIKeyByHash(ctx context.Context, hash string) (auth.APIKey, error) {
+	key, ok := s.getAPIKey(ctx, func(key auth.APIKey) bool { return key.KeyHash == hash })
+	if !ok {
+		return auth.APIKey{}, notFound("error fetching the api key by hash", auth.ErrNotFound)
+	}
+	return key, nil
+}
+
+func (s *Store) GetAPIKeysByUser(ctx context.Context, userID string) ([]auth.APIKey, error) {
+	defer s.rlock(ctx)()
+
+	keys := []auth.APIKey{}
+	for _, key := range s.apiKeys {
+		if key.UserID == userID {
+			keys = append(keys, cloneAPIKey(key))
+		}
+	}
+	sort.SliceStable(keys, func(i, j int) bool {
+		return keys[i].DateCreated.Before(keys[j].DateCreated)
+	})
+	return keys, nil
+}
+
+func (s *Store) TouchAPIKey(ctx context.Context, ID string, lastUsed time.Time) error {
+	defer s.lock(ctx)()
+
+	for i := range s.apiKeys {
+		if s.apiKeys[i].ID == ID {
+			s.apiKeys[i].LastUsed = lastUsed
+		}
+	}
+	return nil
+}
+
+func (s *Store) RevokeAPIKey(ctx context.Context, ID string) error {
+	defer s.lock(ctx)()
+
+	for i := range s.apiKeys {
+		if s.apiKeys[i].ID == ID {
+			s.apiKeys[i].Revoked = true
+		}
+	}
+	return nil
+}
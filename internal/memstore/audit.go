@@ -0,0 +1,32 @@
+package memstore
+
+import (
+	"context"
+	"sort"
+
+	uuid "github.com/satori/go.uuid"
+	appUser "github.com/yuchida-tamu/git-workout-api/internal/user"
+)
+
+func (s *Store) PostAuditEvent(ctx context.Context, event appUser.AuditEvent) (appUser.AuditEvent, error) {
+	defer s.lock(ctx)()
+
+	event.ID = uuid.NewV4().String()
+	s.auditEvents = append(s.auditEvents, event)
+	return event, nil
+}
+
+func (s *Store) GetAuditEventsByUser(ctx context.Context, userID string) ([]appUser.AuditEvent, error) {
+	defer s.rlock(ctx)()
+
+	events := []appUser.AuditEvent{}
+	for _, event := range s.auditEvents {
+		if event.UserID == userID {
+			events = append(events, event)
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].DateCreated.After(events[j].DateCreated)
+	})
+	return events, nil
+}
@@ -0,0 +1,79 @@
+package memstore
+
+import (
+	"context"
+	"sort"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/yuchida-tamu/git-workout-api/internal/coach"
+)
+
+func cloneRelationship(rel coach.Relationship) coach.Relationship {
+	rel.Scopes = append([]coach.Scope{}, rel.Scopes...)
+	return rel
+}
+
+func (s *Store) getRelationshipsWhere(ctx context.Context, match func(coach.Relationship) bool) []coach.Relationship {
+	defer s.rlock(ctx)()
+
+	rels := []coach.Relationship{}
+	for _, rel := range s.relationships {
+		if match(rel) {
+			rels = append(rels, cloneRelationship(rel))
+		}
+	}
+	sort.SliceStable(rels, func(i, j int) bool {
+		return rels[i].DateCreated.Before(rels[j].DateCreated)
+	})
+	return rels
+}
+
+func (s *Store) GetRelationshipsByCoach(ctx context.Context, coachID string) ([]coach.Relationship, error) {
+	return s.getRelationshipsWhere(ctx, func(rel coach.Relationship) bool { return rel.CoachID == coachID }), nil
+}
+
+func (s *Store) GetRelationshipsByAthlete(ctx context.Context, athleteID string) ([]coach.Relationship, error) {
+	return s.getRelationshipsWhere(ctx, func(rel coach.Relationship) bool { return rel.AthleteID == athleteID }), nil
+}
+
+func (s *Store) GetRelationship(ctx context.Context, ID string) (coach.Relationship, error) {
+	defer s.rlock(ctx)()
+
+	for _, rel := range s.relationships {
+		if rel.ID == ID {
+			return cloneRelationship(rel), nil
+		}
+	}
+	return coach.Relationship{}, notFound("error fetching the relationship by id", coach.ErrNotFound)
+}
+
+func (s *Store) PostRelationship(ctx context.Context, rel coach.Relationship) (coach.Relationship, error) {
+	defer s.lock(ctx)()
+
+	rel.ID = uuid.NewV4().String()
+	s.relationships = append(s.relationships, cloneRelationship(rel))
+	return rel, nil
+}
+
+func (s *Store) UpdateRelationshipStatus(ctx context.Context, ID string, status coach.Status) error {
+	defer s.lock(ctx)()
+
+	for i := range s.relationships {
+		if s.relationships[i].ID == ID {
+			s.relationships[i].Status = status
+		}
+	}
+	return nil
+}
+
+func (s *Store) DeleteRelationship(ctx context.Context, ID string) error {
+	defer s.lock(ctx)()
+
+	for i, rel := range s.relationships {
+		if rel.ID == ID {
+			s.relationships = append(s.relationships[:i], s.relationships[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+package memstore
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/yuchida-tamu/git-workout-api/internal/auth"
+	appUser "github.com/yuchida-tamu/git-workout-api/internal/user"
+)
+
+func (s *Store) SetUserDeleteAfter(ctx context.Context, uuid string, deleteAfter time.Time, version int) error {
+	defer s.lock(ctx)()
+
+	i := s.findUserByID(uuid)
+	if i == -1 {
+		return notFound("failed to update user deletion time", appUser.ErrNotFound)
+	}
+	if version != 0 && s.users[i].Version != version {
+		return fmt.Errorf("failed to update user deletion time: %w: they are at version %d", appUser.ErrVersionMismatch, s.users[i].Version)
+	}
+
+	s.users[i].DeleteAfter = deleteAfter
+	s.users[i].Version++
+	return nil
+}
+
+// GetUsersDueForDeletion - the users whose deletion was scheduled for now or earlier
+func (s *Store) GetUsersDueForDeletion(ctx context.Context, now time.Time) ([]appUser.User, error) {
+	defer s.rlock(ctx)()
+
+	users := []appUser.User{}
+	for _, u := range s.users {
+		if !u.DeleteAfter.IsZero() && !u.DeleteAfter.After(now) {
+			users = append(users, u)
+		}
+	}
+	return users, nil
+}
+
+// PurgeUser - deletes the user and everything stored about them but the audit events, like the database does.
+// The tables are filtered into new backing arrays, as a transaction's snapshot shares the old ones.
+func (s *Store) PurgeUser(ctx context.Context, uuid string, now time.Time) error {
+	defer s.lock(ctx)()
+
+	i := s.findUserByID(uuid)
+	if i == -1 || s.users[i].DeleteAfter.IsZero() || s.users[i].DeleteAfter.After(now) {
+		return fmt.Errorf("failed to purge user: %w", appUser.ErrDeletionNotDue)
+	}
+
+	ownedClients := map[string]bool{}
+	for _, client := range s.oauthClients {
+		if client.OwnerID == uuid {
+			ownedClients[client.ID] = true
+		}
+	}
+	purgedRecords := map[string]bool{}
+	for _, rcd := range s.records {
+		if rcd.Author == uuid {
+			purgedRecords[rcd.ID] = true
+		}
+	}
+	purgedSessions := map[string]bool{}
+	for _, session := range s.sessions {
+		if session.UserID == uuid || ownedClients[session.ClientID] {
+			purgedSessions[session.ID] = true
+		}
+	}
+
+	comments := s.comments[:0:0]
+	for _, comment := range s.comments {
+		if comment.Author != uuid && !purgedRecords[comment.RecordID] {
+			comments = append(comments, comment)
+		}
+	}
+	s.comments = comments
+
+	records := s.records[:0:0]
+	for _, rcd := range s.records {
+		if !purgedRecords[rcd.ID] {
+			records = append(records, rcd)
+		}
+	}
+	s.records = records
+
+	relationships := s.relationships[:0:0]
+	for _, relationship := range s.relationships {
+		if relationship.CoachID != uuid && relationship.AthleteID != uuid {
+			relationships = append(relationships, relationship)
+		}
+	}
+	s.relationships = relationships
+
+	refreshTokens := s.refreshTokens[:0:0]
+	for _, token := range s.refreshTokens {
+		if token.UserID != uuid && !purgedSessions[token.FamilyID] {
+			refreshTokens = append(refreshTokens, token)
+		}
+	}
+	s.refreshTokens = refreshTokens
+
+	sessions := s.sessions[:0:0]
+	for _, session := range s.sessions {
+		if !purgedSessions[session.ID] {
+			sessions = append(sessions, session)
+		}
+	}
+	s.sessions = sessions
+
+	mfa := make(map[string]auth.MFA, len(s.mfa))
+	for userID, enrollment := range s.mfa {
+		if userID != uuid {
+			mfa[userID] = enrollment
+		}
+	}
+	s.mfa = mfa
+
+	oneTimeTokens := s.oneTimeTokens[:0:0]
+	for _, token := range s.oneTimeTokens {
+		if token.UserID != uuid {
+			oneTimeTokens = append(oneTimeTokens, token)
+		}
+	}
+	s.oneTimeTokens = oneTimeTokens
+
+	lockoutEvents := s.lockoutEvents[:0:0]
+	for _, event := range s.lockoutEvents {
+		if event.UserID != uuid {
+			lockoutEvents = append(lockoutEvents, event)
+		}
+	}
+	s.lockoutEvents = lockoutEvents
+
+	apiKeys := s.apiKeys[:0:0]
+	for _, key := range s.apiKeys {
+		if key.UserID != uuid {
+			apiKeys = append(apiKeys, key)
+		}
+	}
+	s.apiKeys = apiKeys
+
+	oidcLogins := s.oidcLogins[:0:0]
+	for _, login := range s.oidcLogins {
+		if login.LinkUserID != uuid {
+			oidcLogins = append(oidcLogins, login)
+		}
+	}
+	s.oidcLogins = oidcLogins
+
+	identities := s.identities[:0:0]
+	for _, identity := range s.identities {
+		if identity.UserID != uuid {
+			identities = append(identities, identity)
+		}
+	}
+	s.identities = identities
+
+	oauthCodes := s.oauthCodes[:0:0]
+	for _, code := range s.oauthCodes {
+		if code.UserID != uuid && !ownedClients[code.ClientID] {
+			oauthCodes = append(oauthCodes, code)
+		}
+	}
+	s.oauthCodes = oauthCodes
+
+	oauthConsents := s.oauthConsents[:0:0]
+	for _, consent := range s.oauthConsents {
+		if consent.UserID != uuid && !ownedClients[consent.ClientID] {
+			oauthConsents = append(oauthConsents, consent)
+		}
+	}
+	s.oauthConsents = oauthConsents
+
+	oauthClients := s.oauthClients[:0:0]
+	for _, client := range s.oauthClients {
+		if !ownedClients[client.ID] {
+			oauthClients = append(oauthClients, client)
+		}
+	}
+	s.oauthClients = oauthClients
+
+	users := s.users[:0:0]
+	for _, u := range s.users {
+		if u.ID != uuid {
+			users = append(users, u)
+		}
+	}
+	s.users = users
+
+	return nil
+}
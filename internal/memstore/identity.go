@@ -0,0 +1,98 @@
+package memstore
+
+import (
+	"context"
+	"sort"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/yuchida-tamu/git-workout-api/internal/auth"
+)
+
+func (s *Store) PostOIDCLogin(ctx context.Context, login auth.OIDCLogin) (auth.OIDCLogin, error) {
+	defer s.lock(ctx)()
+
+	for _, l := range s.oidcLogins {
+		if l.StateHash == login.StateHash {
+			return auth.OIDCLogin{}, duplicate("failed to insert oidc login", "oidc_logins_state_hash_key")
+		}
+	}
+
+	login.ID = uuid.NewV4().String()
+	s.oidcLogins = append(s.oidcLogins, login)
+	return login, nil
+}
+
+func (s *Store) GetOIDCLoginByStateHash(ctx context.Context, hash string) (auth.OIDCLogin, error) {
+	defer s.rlock(ctx)()
+
+	for _, login := range s.oidcLogins {
+		if login.StateHash == hash {
+			return login, nil
+		}
+	}
+	return auth.OIDCLogin{}, notFound("error fetching the oidc login by state", auth.ErrNotFound)
+}
+
+func (s *Store) DeleteOIDCLogin(ctx context.Context, ID string) (bool, error) {
+	defer s.lock(ctx)()
+
+	for i, login := range s.oidcLogins {
+		if login.ID == ID {
+			s.oidcLogins = append(s.oidcLogins[:i], s.oidcLogins[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *Store) PostIdentity(ctx context.Context, identity auth.Identity) (auth.Identity, error) {
+	defer s.lock(ctx)()
+
+	for _, i := range s.identities {
+		if i.Provider == identity.Provider && i.Subject == identity.Subject {
+			return auth.Identity{}, duplicate("failed to insert identity", "user_identities_provider_subject_key")
+		}
+	}
+
+	identity.ID = uuid.NewV4().String()
+	s.identities = append(s.identities, identity)
+	return identity, nil
+}
+
+func (s *Store) GetIdentity(ctx context.Context, provider string, subject string) (auth.Identity, error) {
+	defer s.rlock(ctx)()
+
+	for _, identity := range s.identities {
+		if identity.Provider == provider && identity.Subject == subject {
+			return identity, nil
+		}
+	}
+	return auth.Identity{}, notFound("error fetching the identity", auth.ErrNotFound)
+}
+
+func (s *Store) GetIdentitiesByUser(ctx context.Context, userID string) ([]auth.Identity, error) {
+	defer s.rlock(ctx)()
+
+	identities := []auth.Identity{}
+	for _, identity := range s.identities {
+		if identity.UserID == userID {
+			identities = append(identities, identity)
+		}
+	}
+	sort.SliceStable(identities, func(i, j int) bool {
+		return identities[i].DateCreated.Before(identities[j].DateCreated)
+	})
+	return identities, nil
+}
+
+func (s *Store) DeleteIdentity(ctx context.Context, ID string) error {
+	defer s.lock(ctx)()
+
+	for i, identity := range s.identities {
+		if identity.ID == ID {
+			s.identities = append(s.identities[:i], s.identities[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
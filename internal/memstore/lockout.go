@@ -0,0 +1,33 @@
+package memstore
+
+import (
+	"context"
+	"sort"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/yuchida-tamu/git-workout-api/internal/auth"
+)
+
+func (s *Store) PostLockoutEvent(ctx context.Context, event auth.LockoutEvent) (auth.LockoutEvent, error) {
+	defer s.lock(ctx)()
+
+	event.ID = uuid.NewV4().String()
+	s.lockoutEvents = append(s.lockoutEvents, event)
+	return event, nil
+}
+
+func (s *Store) GetLockoutEventsByUser(ctx context.Context, userID string) ([]auth.LockoutEvent, error) {
+	defer s.rlock(ctx)()
+
+	events := []auth.LockoutEvent{}
+	for _, event := range s.lockoutEvents {
+		// events of unknown usernames have no user
+		if event.UserID != "" && event.UserID == userID {
+			events = append(events, event)
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].DateCreated.After(events[j].DateCreated)
+	})
+	return events, nil
+}
@@ -0,0 +1,67 @@
+package memstore
+
+import (
+	"context"
+
+	"github.com/yuchida-tamu/git-workout-api/internal/auth"
+)
+
+func cloneMFA(mfa auth.MFA) auth.MFA {
+	mfa.RecoveryCodeHashes = cloneStrings(mfa.RecoveryCodeHashes)
+	return mfa
+}
+
+func (s *Store) GetMFA(ctx context.Context, userID string) (auth.MFA, error) {
+	defer s.rlock(ctx)()
+
+	mfa, ok := s.mfa[userID]
+	if !ok {
+		return auth.MFA{}, notFound("error fetching the mfa enrollment by user id", auth.ErrNotFound)
+	}
+	return cloneMFA(mfa), nil
+}
+
+// PutMFA - creates or replaces the user's enrollment
+func (s *Store) PutMFA(ctx context.Context, mfa auth.MFA) error {
+	defer s.lock(ctx)()
+
+	s.mfa[mfa.UserID] = cloneMFA(mfa)
+	return nil
+}
+
+func (s *Store) MarkMFAStepUsed(ctx context.Context, userID string, step int64) (bool, error) {
+	defer s.lock(ctx)()
+
+	mfa, ok := s.mfa[userID]
+	if !ok || mfa.LastUsedStep >= step {
+		return false, nil
+	}
+	mfa.LastUsedStep = step
+	s.mfa[userID] = mfa
+	return true, nil
+}
+
+func (s *Store) RemoveMFARecoveryCode(ctx context.Context, userID string, hash string) (bool, error) {
+	defer s.lock(ctx)()
+
+	mfa, ok := s.mfa[userID]
+	if !ok {
+		return false, nil
+	}
+	for i, stored := range mfa.RecoveryCodeHashes {
+		if stored == hash {
+			// a new backing array, the old one may still be shared with a clone
+			mfa.RecoveryCodeHashes = append(mfa.RecoveryCodeHashes[:i:i], mfa.RecoveryCodeHashes[i+1:]...)
+			s.mfa[userID] = mfa
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *Store) DeleteMFA(ctx context.Context, userID string) error {
+	defer s.lock(ctx)()
+
+	delete(s.mfa, userID)
+	return nil
+}
@@ -0,0 +1,138 @@
+package memstore
+
+import (
+	"context"
+	"sort"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/yuchida-tamu/git-workout-api/internal/auth"
+)
+
+func cloneOAuthClient(client auth.OAuthClient) auth.OAuthClient {
+	client.RedirectURIs = cloneStrings(client.RedirectURIs)
+	client.Scopes = cloneScopes(client.Scopes)
+	return client
+}
+
+func (s *Store) PostOAuthClient(ctx context.Context, client auth.OAuthClient) (auth.OAuthClient, error) {
+	defer s.lock(ctx)()
+
+	client.ID = uuid.NewV4().String()
+	s.oauthClients = append(s.oauthClients, cloneOAuthClient(client))
+	return client, nil
+}
+
+func (s *Store) GetOAuthClient(ctx context.Context, ID string) (auth.OAuthClient, error) {
+	defer s.rlock(ctx)()
+
+	for _, client := range s.oauthClients {
+		if client.ID == ID {
+			return cloneOAuthClient(client), nil
+		}
+	}
+	return auth.OAuthClient{}, notFound("error fetching the oauth client by id", auth.ErrNotFound)
+}
+
+func (s *Store) GetOAuthClientsByOwner(ctx context.Context, ownerID string) ([]auth.OAuthClient, error) {
+	defer s.rlock(ctx)()
+
+	clients := []auth.OAuthClient{}
+	for _, client := range s.oauthClients {
+		if client.OwnerID == ownerID {
+			clients = append(clients, cloneOAuthClient(client))
+		}
+	}
+	sort.SliceStable(clients, func(i, j int) bool {
+		return clients[i].DateCreated.Before(clients[j].DateCreated)
+	})
+	return clients, nil
+}
+
+func (s *Store) DeleteOAuthClient(ctx context.Context, ID string) error {
+	defer s.lock(ctx)()
+
+	for i, client := range s.oauthClients {
+		if client.ID == ID {
+			s.oauthClients = append(s.oauthClients[:i], s.oauthClients[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func (s *Store) PostAuthorizationCode(ctx context.Context, code auth.AuthorizationCode) (auth.AuthorizationCode, error) {
+	defer s.lock(ctx)()
+
+	for _, c := range s.oauthCodes {
+		if c.CodeHash == code.CodeHash {
+			return auth.AuthorizationCode{}, duplicate("failed to insert authorization code", "oauth_authorization_codes_code_hash_key")
+		}
+	}
+
+	code.ID = uuid.NewV4().String()
+	code.Scopes = cloneScopes(code.Scopes)
+	s.oauthCodes = append(s.oauthCodes, code)
+	return code, nil
+}
+
+func (s *Store) GetAuthorizationCodeByHash(ctx context.Context, hash string) (auth.AuthorizationCode, error) {
+	defer s.rlock(ctx)()
+
+	for _, code := range s.oauthCodes {
+		if code.CodeHash == hash {
+			code.Scopes = cloneScopes(code.Scopes)
+			return code, nil
+		}
+	}
+	return auth.AuthorizationCode{}, notFound("error fetching the authorization code by hash", auth.ErrNotFound)
+}
+
+func (s *Store) MarkAuthorizationCodeUsed(ctx context.Context, ID string) (bool, error) {
+	defer s.lock(ctx)()
+
+	for i := range s.oauthCodes {
+		if s.oauthCodes[i].ID == ID && !s.oauthCodes[i].Used {
+			s.oauthCodes[i].Used = true
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *Store) SetAuthorizationCodeSession(ctx context.Context, ID string, sessionID string) error {
+	defer s.lock(ctx)()
+
+	for i := range s.oauthCodes {
+		if s.oauthCodes[i].ID == ID {
+			s.oauthCodes[i].SessionID = sessionID
+		}
+	}
+	return nil
+}
+
+func (s *Store) GetConsent(ctx context.Context, userID string, clientID string) (auth.Consent, error) {
+	defer s.rlock(ctx)()
+
+	for _, consent := range s.oauthConsents {
+		if consent.UserID == userID && consent.ClientID == clientID {
+			consent.Scopes = cloneScopes(consent.Scopes)
+			return consent, nil
+		}
+	}
+	return auth.Consent{}, notFound("error fetching the consent", auth.ErrNotFound)
+}
+
+// PutConsent - creates or replaces the user's consent for the client
+func (s *Store) PutConsent(ctx context.Context, consent auth.Consent) error {
+	defer s.lock(ctx)()
+
+	consent.Scopes = cloneScopes(consent.Scopes)
+	for i := range s.oauthConsents {
+		if s.oauthConsents[i].UserID == consent.UserID && s.oauthConsents[i].ClientID == consent.ClientID {
+			s.oauthConsents[i] = consent
+			return nil
+		}
+	}
+	s.oauthConsents = append(s.oauthConsents, consent)
+	return nil
+}
@@ -0,0 +1,58 @@
+package memstore
+
+import (
+	"context"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/yuchida-tamu/git-workout-api/internal/auth"
+)
+
+func (s *Store) PostOneTimeToken(ctx context.Context, token auth.OneTimeToken) (auth.OneTimeToken, error) {
+	defer s.lock(ctx)()
+
+	for _, t := range s.oneTimeTokens {
+		if t.TokenHash == token.TokenHash {
+			return auth.OneTimeToken{}, duplicate("failed to insert one-time token", "one_time_tokens_token_hash_key")
+		}
+	}
+
+	token.ID = uuid.NewV4().String()
+	s.oneTimeTokens = append(s.oneTimeTokens, token)
+	return token, nil
+}
+
+func (s *Store) GetOneTimeTokenByHash(ctx context.Context, hash string) (auth.OneTimeToken, error) {
+	defer s.rlock(ctx)()
+
+	for _, t := range s.oneTimeTokens {
+		if t.TokenHash == hash {
+			return t, nil
+		}
+	}
+	return auth.OneTimeToken{}, notFound("error fetching the one-time token by hash", auth.ErrNotFound)
+}
+
+func (s *Store) MarkOneTimeTokenUsed(ctx context.Context, ID string) (bool, error) {
+	defer s.lock(ctx)()
+
+	for i := range s.oneTimeTokens {
+		if s.oneTimeTokens[i].ID == ID && !s.oneTimeTokens[i].Used {
+			s.oneTimeTokens[i].Used = true
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *Store) DeleteOneTimeTokensByUser(ctx context.Context, userID string, purpose auth.Purpose) error {
+	defer s.lock(ctx)()
+
+	kept := s.oneTimeTokens[:0]
+	for _, t := range s.oneTimeTokens {
+		if t.UserID != userID || t.Purpose != purpose {
+			kept = append(kept, t)
+		}
+	}
+	s.oneTimeTokens = kept
+	return nil
+}
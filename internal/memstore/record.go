@@ -0,0 +1,110 @@
+package memstore
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/yuchida-tamu/git-workout-api/internal/record"
+)
+
+func (s *Store) findRecord(ID string) int {
+	for i, rcd := range s.records {
+		if rcd.ID == ID {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *Store) GetRecordsByAuthor(ctx context.Context, ID string) ([]record.Record, error) {
+	defer s.rlock(ctx)()
+
+	var records []record.Record
+	for _, rcd := range s.records {
+		if rcd.Author == ID {
+			records = append(records, rcd)
+		}
+	}
+	return records, nil
+}
+
+func (s *Store) GetRecordById(ctx context.Context, ID string) (record.Record, error) {
+	defer s.rlock(ctx)()
+
+	i := s.findRecord(ID)
+	if i == -1 {
+		return record.Record{}, notFound("error fetching the record by id", record.ErrNotFound)
+	}
+	return s.records[i], nil
+}
+
+func (s *Store) PostRecord(ctx context.Context, rcd record.Record) (record.Record, error) {
+	defer s.lock(ctx)()
+
+	// mirrors the foreign key from records to users
+	if s.findUserByID(rcd.Author) == -1 {
+		return record.Record{}, fmt.Errorf("failed to insert record: %w", record.ErrValidation)
+	}
+
+	rcd.ID = uuid.NewV4().String()
+	rcd.Version = 1
+	s.records = append(s.records, rcd)
+	return rcd, nil
+}
+
+func (s *Store) UpdateRecord(ctx context.Context, ID string, rcd record.Record) (record.Record, error) {
+	defer s.lock(ctx)()
+
+	i := s.findRecord(ID)
+	if i == -1 {
+		return record.Record{}, notFound("failed to update record", record.ErrNotFound)
+	}
+	if rcd.Version != 0 && s.records[i].Version != rcd.Version {
+		return record.Record{}, fmt.Errorf("failed to update record: %w: it is at version %d", record.ErrVersionMismatch, s.records[i].Version)
+	}
+
+	s.records[i].DateCreated = rcd.DateCreated
+	s.records[i].MessageBody = rcd.MessageBody
+	s.records[i].Version++
+	return s.records[i], nil
+}
+
+func (s *Store) DeleteRecord(ctx context.Context, ID string, version int) error {
+	defer s.lock(ctx)()
+
+	i := s.findRecord(ID)
+	if i == -1 {
+		return notFound("failed to delete record", record.ErrNotFound)
+	}
+	if version != 0 && s.records[i].Version != version {
+		return fmt.Errorf("failed to delete record: %w: it is at version %d", record.ErrVersionMismatch, s.records[i].Version)
+	}
+
+	s.records = append(s.records[:i], s.records[i+1:]...)
+	return nil
+}
+
+func (s *Store) GetCommentsByRecord(ctx context.Context, recordID string) ([]record.Comment, error) {
+	defer s.rlock(ctx)()
+
+	comments := []record.Comment{}
+	for _, comment := range s.comments {
+		if comment.RecordID == recordID {
+			comments = append(comments, comment)
+		}
+	}
+	sort.SliceStable(comments, func(i, j int) bool {
+		return comments[i].DateCreated.Before(comments[j].DateCreated)
+	})
+	return comments, nil
+}
+
+func (s *Store) PostComment(ctx context.Context, comment record.Comment) (record.Comment, error) {
+	defer s.lock(ctx)()
+
+	comment.ID = uuid.NewV4().String()
+	s.comments = append(s.comments, comment)
+	return comment, nil
+}
@@ -0,0 +1,56 @@
+package memstore
+
+import (
+	"context"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/yuchida-tamu/git-workout-api/internal/auth"
+)
+
+func (s *Store) PostRefreshToken(ctx context.Context, token auth.RefreshToken) (auth.RefreshToken, error) {
+	defer s.lock(ctx)()
+
+	for _, t := range s.refreshTokens {
+		if t.TokenHash == token.TokenHash {
+			return auth.RefreshToken{}, duplicate("failed to insert refresh token", "refresh_tokens_token_hash_key")
+		}
+	}
+
+	token.ID = uuid.NewV4().String()
+	s.refreshTokens = append(s.refreshTokens, token)
+	return token, nil
+}
+
+func (s *Store) GetRefreshTokenByHash(ctx context.Context, hash string) (auth.RefreshToken, error) {
+	defer s.rlock(ctx)()
+
+	for _, t := range s.refreshTokens {
+		if t.TokenHash == hash {
+			return t, nil
+		}
+	}
+	return auth.RefreshToken{}, notFound("error fetching the refresh token by hash", auth.ErrNotFound)
+}
+
+func (s *Store) MarkRefreshTokenUsed(ctx context.Context, ID string) (bool, error) {
+	defer s.lock(ctx)()
+
+	for i := range s.refreshTokens {
+		if s.refreshTokens[i].ID == ID && !s.refreshTokens[i].Used {
+			s.refreshTokens[i].Used = true
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *Store) RevokeRefreshTokenFamily(ctx context.Context, familyID string) error {
+	defer s.lock(ctx)()
+
+	for i := range s.refreshTokens {
+		if s.refreshTokens[i].FamilyID == familyID {
+			s.refreshTokens[i].Revoked = true
+		}
+	}
+	return nil
+}
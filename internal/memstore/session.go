@@ -0,0 +1,84 @@
+package memstore
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/yuchida-tamu/git-workout-api/internal/auth"
+)
+
+func cloneSession(session auth.Session) auth.Session {
+	session.Scopes = cloneScopes(session.Scopes)
+	return session
+}
+
+func (s *Store) PostSession(ctx context.Context, session auth.Session) (auth.Session, error) {
+	defer s.lock(ctx)()
+
+	session.ID = uuid.NewV4().String()
+	s.sessions = append(s.sessions, cloneSession(session))
+	return session, nil
+}
+
+func (s *Store) GetSession(ctx context.Context, ID string) (auth.Session, error) {
+	defer s.rlock(ctx)()
+
+	for _, session := range s.sessions {
+		if session.ID == ID {
+			return cloneSession(session), nil
+		}
+	}
+	return auth.Session{}, notFound("error fetching the session by id", auth.ErrNotFound)
+}
+
+func (s *Store) GetSessionsByUser(ctx context.Context, userID string) ([]auth.Session, error) {
+	defer s.rlock(ctx)()
+
+	sessions := []auth.Session{}
+	for _, session := range s.sessions {
+		if session.UserID == userID {
+			sessions = append(sessions, cloneSession(session))
+		}
+	}
+	sort.SliceStable(sessions, func(i, j int) bool {
+		return sessions[i].LastUsed.After(sessions[j].LastUsed)
+	})
+	return sessions, nil
+}
+
+func (s *Store) TouchSession(ctx context.Context, ID string, client auth.ClientInfo, lastUsed time.Time) error {
+	defer s.lock(ctx)()
+
+	for i := range s.sessions {
+		if s.sessions[i].ID == ID {
+			s.sessions[i].IP = client.IP
+			s.sessions[i].UserAgent = client.UserAgent
+			s.sessions[i].LastUsed = lastUsed
+		}
+	}
+	return nil
+}
+
+func (s *Store) RevokeSession(ctx context.Context, ID string) error {
+	defer s.lock(ctx)()
+
+	for i := range s.sessions {
+		if s.sessions[i].ID == ID {
+			s.sessions[i].Revoked = true
+		}
+	}
+	return nil
+}
+
+func (s *Store) RevokeSessionsByClient(ctx context.Context, clientID string) error {
+	defer s.lock(ctx)()
+
+	for i := range s.sessions {
+		if s.sessions[i].ClientID == clientID {
+			s.sessions[i].Revoked = true
+		}
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+// Package memstore keeps everything the API stores in memory. It implements the same store interfaces
+// as db.Database with the same semantics, so the server and tests can run without a database;
+// nothing survives a restart.
+package memstore
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/yuchida-tamu/git-workout-api/internal/auth"
+	"github.com/yuchida-tamu/git-workout-api/internal/coach"
+	"github.com/yuchida-tamu/git-workout-api/internal/record"
+	"github.com/yuchida-tamu/git-workout-api/internal/user"
+)
+
+// Store - a thread-safe in-memory store. Slices are kept in insertion order, which is the order rows
+// come back from the database when a query has no ORDER BY.
+type Store struct {
+	mu sync.RWMutex
+	tables
+}
+
+// tables - everything the store keeps, separate from the lock so that a transaction can save and restore it
+type tables struct {
+	users         []user.User
+	records       []record.Record
+	comments      []record.Comment
+	relationships []coach.Relationship
+	auditEvents   []user.AuditEvent
+
+	refreshTokens []auth.RefreshToken
+	sessions      []auth.Session
+	mfa           map[string]auth.MFA
+	oneTimeTokens []auth.OneTimeToken
+	lockoutEvents []auth.LockoutEvent
+	apiKeys       []auth.APIKey
+	oidcLogins    []auth.OIDCLogin
+	identities    []auth.Identity
+	oauthClients  []auth.OAuthClient
+	oauthCodes    []auth.AuthorizationCode
+	oauthConsents []auth.Consent
+}
+
+var (
+	_ user.Store   = (*Store)(nil)
+	_ record.Store = (*Store)(nil)
+	_ coach.Store  = (*Store)(nil)
+	_ auth.Store   = (*Store)(nil)
+)
+
+func NewStore() *Store {
+	return &Store{
+		tables: tables{
+			mfa: map[string]auth.MFA{},
+		},
+	}
+}
+
+// notFound - the domain's not found error, as the database returns it when a query finds no row
+func notFound(message string, domainErr error) error {
+	return fmt.Errorf("%s: %w", message, domainErr)
+}
+
+// duplicate - the error for a row violating a unique constraint
+func duplicate(message string, constraint string) error {
+	return fmt.Errorf("%s: duplicate key value violates unique constraint %q", message, constraint)
+}
+
+func cloneScopes(scopes []auth.Scope) []auth.Scope {
+	return append([]auth.Scope{}, scopes...)
+}
+
+func cloneStrings(strings []string) []string {
+	return append([]string{}, strings...)
+}
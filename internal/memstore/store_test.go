@@ -0,0 +1,13 @@
+package memstore
+
+import (
+	"testing"
+
+	"github.com/yuchida-tamu/git-workout-api/internal/storetest"
+)
+
+func TestStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) storetest.Store {
+		return NewStore()
+	})
+}
@@ -0,0 +1,88 @@
+package memstore
+
+import (
+	"context"
+
+	"github.com/yuchida-tamu/git-workout-api/internal/auth"
+)
+
+type txKey struct{}
+
+func (s *Store) inTx(ctx context.Context) bool {
+	tx, _ := ctx.Value(txKey{}).(*Store)
+	return tx == s
+}
+
+// lock - takes the write lock, unless ctx belongs to a transaction of s, which holds it already
+func (s *Store) lock(ctx context.Context) (unlock func()) {
+	if s.inTx(ctx) {
+		return func() {}
+	}
+	s.mu.Lock()
+	return s.mu.Unlock
+}
+
+// rlock - takes the read lock, unless ctx belongs to a transaction of s
+func (s *Store) rlock(ctx context.Context) (runlock func()) {
+	if s.inTx(ctx) {
+		return func() {}
+	}
+	s.mu.RLock()
+	return s.mu.RUnlock
+}
+
+// WithTx - runs fn holding the write lock, so that calls passing on the context fn gets see nothing but each
+// other, and restores every table when fn returns an error or panics. A WithTx inside fn joins the
+// transaction already in progress. Calls with another context block until the transaction is over, and fn
+// must not use its context from several goroutines at once.
+func (s *Store) WithTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if s.inTx(ctx) {
+		return fn(ctx)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	saved := s.tables.clone()
+	committed := false
+	defer func() {
+		if !committed {
+			s.tables = saved
+		}
+	}()
+
+	if err := fn(context.WithValue(ctx, txKey{}, s)); err != nil {
+		return err
+	}
+
+	committed = true
+	return nil
+}
+
+// clone - copies every table into its own backing array; rows are replaced rather than changed in place, so
+// they can be shared
+func (t tables) clone() tables {
+	mfa := make(map[string]auth.MFA, len(t.mfa))
+	for userID, enrollment := range t.mfa {
+		mfa[userID] = enrollment
+	}
+
+	return tables{
+		users:         append(t.users[:0:0], t.users...),
+		records:       append(t.records[:0:0], t.records...),
+		comments:      append(t.comments[:0:0], t.comments...),
+		relationships: append(t.relationships[:0:0], t.relationships...),
+		auditEvents:   append(t.auditEvents[:0:0], t.auditEvents...),
+		refreshTokens: append(t.refreshTokens[:0:0], t.refreshTokens...),
+		sessions:      append(t.sessions[:0:0], t.sessions...),
+		mfa:           mfa,
+		oneTimeTokens: append(t.oneTimeTokens[:0:0], t.oneTimeTokens...),
+		lockoutEvents: append(t.lockoutEvents[:0:0], t.lockoutEvents...),
+		apiKeys:       append(t.apiKeys[:0:0], t.apiKeys...),
+		oidcLogins:    append(t.oidcLogins[:0:0], t.oidcLogins...),
+		identities:    append(t.identities[:0:0], t.identities...),
+		oauthClients:  append(t.oauthClients[:0:0], t.oauthClients...),
+		oauthCodes:    append(t.oauthCodes[:0:0], t.oauthCodes...),
+		oauthConsents: append(t.oauthConsents[:0:0], t.oauthConsents...),
+	}
+}
@@ -0,0 +1,177 @@
+package memstore
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	uuid "github.com/satori/go.uuid"
+	appUser "github.com/yuchida-tamu/git-workout-api/internal/user"
+)
+
+func (s *Store) findUser(match func(appUser.User) bool) int {
+	for i, u := range s.users {
+		if match(u) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *Store) findUserByID(ID string) int {
+	return s.findUser(func(u appUser.User) bool { return u.ID == ID })
+}
+
+// emailTaken - mirrors the unique index on lower(email); empty emails are stored as NULL and never collide
+func (s *Store) emailTaken(email string, exceptID string) bool {
+	if email == "" {
+		return false
+	}
+	return s.findUser(func(u appUser.User) bool {
+		return u.ID != exceptID && strings.EqualFold(u.Email, email)
+	}) != -1
+}
+
+// usernameTaken - mirrors the unique index on lower(username)
+func (s *Store) usernameTaken(username string, exceptID string) bool {
+	return s.findUser(func(u appUser.User) bool {
+		return u.ID != exceptID && strings.EqualFold(u.Username, username)
+	}) != -1
+}
+
+func (s *Store) GetUsers(ctx context.Context) ([]appUser.User, error) {
+	defer s.rlock(ctx)()
+
+	var users []appUser.User
+	users = append(users, s.users...)
+	return users, nil
+}
+
+func (s *Store) GetUser(ctx context.Context, uuid string) (appUser.User, error) {
+	defer s.rlock(ctx)()
+
+	i := s.findUserByID(uuid)
+	if i == -1 {
+		return appUser.User{}, notFound("error fetching the user by uuid", appUser.ErrNotFound)
+	}
+	return s.users[i], nil
+}
+
+func (s *Store) GetUserByUsername(ctx context.Context, username string) (appUser.User, error) {
+	defer s.rlock(ctx)()
+
+	i := s.findUser(func(u appUser.User) bool { return strings.EqualFold(u.Username, username) })
+	if i == -1 {
+		return appUser.User{}, notFound("error fetching the user by username", appUser.ErrNotFound)
+	}
+	return s.users[i], nil
+}
+
+func (s *Store) PostUser(ctx context.Context, user appUser.User) (appUser.User, error) {
+	defer s.lock(ctx)()
+
+	user.ID = uuid.NewV4().String()
+	if user.Role == "" {
+		user.Role = appUser.RoleUser
+	}
+	user.Version = 1
+	if s.emailTaken(user.Email, "") || s.usernameTaken(user.Username, "") {
+		return appUser.User{}, fmt.Errorf("failed to insert user: %w", appUser.ErrConflict)
+	}
+
+	s.users = append(s.users, user)
+	return user, nil
+}
+
+func (s *Store) UpdateUser(ctx context.Context, uuid string, user appUser.User) (appUser.User, error) {
+	defer s.lock(ctx)()
+
+	if s.usernameTaken(user.Username, uuid) {
+		return appUser.User{}, fmt.Errorf("failed to update user: %w", appUser.ErrConflict)
+	}
+	i := s.findUserByID(uuid)
+	if i == -1 {
+		return appUser.User{}, notFound("failed to update user", appUser.ErrNotFound)
+	}
+	if user.Version != 0 && s.users[i].Version != user.Version {
+		return appUser.User{}, fmt.Errorf("failed to update user: %w: they are at version %d", appUser.ErrVersionMismatch, s.users[i].Version)
+	}
+
+	s.users[i].Username = user.Username
+	s.users[i].Password = user.Password
+	s.users[i].Version++
+	return s.users[i], nil
+}
+
+func (s *Store) DeleteUser(ctx context.Context, uuid string) error {
+	defer s.lock(ctx)()
+
+	if i := s.findUserByID(uuid); i != -1 {
+		s.users = append(s.users[:i], s.users[i+1:]...)
+	}
+
+	// mirrors the foreign key from records to users, which deletes a user's records with them
+	records := s.records[:0]
+	for _, rcd := range s.records {
+		if rcd.Author != uuid {
+			records = append(records, rcd)
+		}
+	}
+	s.records = records
+	return nil
+}
+
+func (s *Store) updateUser(ctx context.Context, uuid string, update func(*appUser.User)) {
+	defer s.lock(ctx)()
+
+	if i := s.findUserByID(uuid); i != -1 {
+		update(&s.users[i])
+		s.users[i].Version++
+	}
+}
+
+func (s *Store) UpdateUserRole(ctx context.Context, uuid string, role appUser.Role) error {
+	s.updateUser(ctx, uuid, func(u *appUser.User) { u.Role = role })
+	return nil
+}
+
+func (s *Store) SetUserDisabled(ctx context.Context, uuid string, disabled bool) error {
+	s.updateUser(ctx, uuid, func(u *appUser.User) { u.Disabled = disabled })
+	return nil
+}
+
+// UpdateUserPassword - stores an already hashed password
+func (s *Store) UpdateUserPassword(ctx context.Context, uuid string, hash string) error {
+	s.updateUser(ctx, uuid, func(u *appUser.User) { u.Password = hash })
+	return nil
+}
+
+func (s *Store) GetUserByEmail(ctx context.Context, email string) (appUser.User, error) {
+	defer s.rlock(ctx)()
+
+	i := s.findUser(func(u appUser.User) bool { return u.Email != "" && strings.EqualFold(u.Email, email) })
+	if i == -1 {
+		return appUser.User{}, notFound("error fetching the user by email", appUser.ErrNotFound)
+	}
+	return s.users[i], nil
+}
+
+// UpdateUserEmail - changes the email address, which has to be verified again
+func (s *Store) UpdateUserEmail(ctx context.Context, uuid string, email string) error {
+	defer s.lock(ctx)()
+
+	if s.emailTaken(email, uuid) {
+		return fmt.Errorf("failed to update user email: %w", appUser.ErrConflict)
+	}
+	if i := s.findUserByID(uuid); i != -1 {
+		s.users[i].Email = email
+		s.users[i].EmailVerified = false
+		s.users[i].Version++
+	}
+	return nil
+}
+
+func (s *Store) SetUserEmailVerified(ctx context.Context, uuid string, verified bool) error {
+	s.updateUser(ctx, uuid, func(u *appUser.User) { u.EmailVerified = verified })
+	return nil
+}
@@ -0,0 +1,76 @@
+package oidc
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// presets - well known providers, so that only the client credentials need configuring
+var presets = map[string]Provider{
+	"google": {
+		Issuer: "https://accounts.google.com",
+		Scopes: []string{"openid", "email", "profile"},
+	},
+	"github": {
+		AuthURL:     "https://github.com/login/oauth/authorize",
+		TokenURL:    "https://github.com/login/oauth/access_token",
+		UserInfoURL: "https://api.github.com/user",
+		Scopes:      []string{"read:user", "user:email"},
+	},
+}
+
+// LoadProvidersFromEnv - configures the providers listed in OIDC_PROVIDERS (comma separated names).
+// Each is read from OIDC_<NAME>_CLIENT_ID, _CLIENT_SECRET and _REDIRECT_URL, plus _ISSUER, _AUTH_URL,
+// _TOKEN_URL, _USERINFO_URL, _JWKS_URL and _SCOPES (space separated) which override the presets for
+// "google" and "github" and are required for other providers. OpenID Connect providers are discovered
+// here, so an unreachable issuer fails startup rather than the first login.
+func LoadProvidersFromEnv(ctx context.Context) (map[string]*Provider, error) {
+	providers := map[string]*Provider{}
+	for _, name := range strings.Split(os.Getenv("OIDC_PROVIDERS"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		p := presets[name]
+		p.Name = name
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		p.ClientID = os.Getenv(prefix + "CLIENT_ID")
+		p.ClientSecret = os.Getenv(prefix + "CLIENT_SECRET")
+		p.RedirectURL = os.Getenv(prefix + "REDIRECT_URL")
+		setFromEnv(&p.Issuer, prefix+"ISSUER")
+		setFromEnv(&p.AuthURL, prefix+"AUTH_URL")
+		setFromEnv(&p.TokenURL, prefix+"TOKEN_URL")
+		setFromEnv(&p.UserInfoURL, prefix+"USERINFO_URL")
+		setFromEnv(&p.JWKSURL, prefix+"JWKS_URL")
+		if scopes := os.Getenv(prefix + "SCOPES"); scopes != "" {
+			p.Scopes = strings.Fields(scopes)
+		}
+		if p.Issuer != "" && len(p.Scopes) == 0 {
+			p.Scopes = []string{"openid", "email", "profile"}
+		}
+
+		if p.ClientID == "" || p.RedirectURL == "" {
+			return nil, fmt.Errorf("provider %s needs %sCLIENT_ID and %sREDIRECT_URL", name, prefix, prefix)
+		}
+		if err := p.Discover(ctx); err != nil {
+			return nil, err
+		}
+		if p.AuthURL == "" || p.TokenURL == "" {
+			return nil, fmt.Errorf("provider %s needs an issuer or %sAUTH_URL and %sTOKEN_URL", name, prefix, prefix)
+		}
+
+		provider := p
+		providers[name] = &provider
+	}
+
+	return providers, nil
+}
+
+func setFromEnv(field *string, key string) {
+	if value := os.Getenv(key); value != "" {
+		*field = value
+	}
+}
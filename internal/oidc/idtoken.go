@@ -0,0 +1,197 @@
+package oidc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+// keyCacheLifetime - how long the provider's keys are kept before they are fetched again
+const keyCacheLifetime = time.Hour
+
+// keyCacheInit - guards creating the cache of a Provider built without LoadProvidersFromEnv
+var keyCacheInit sync.Mutex
+
+type keyCache struct {
+	mu        sync.Mutex
+	keys      map[string]interface{}
+	fetchedAt time.Time
+}
+
+type jsonWebKey struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// verifyIDToken - checks the id token's signature against the provider's published keys, and its issuer,
+// audience, expiry and nonce, returning its claims
+func (p *Provider) verifyIDToken(ctx context.Context, raw string, nonce string) (jwt.MapClaims, error) {
+	token, err := jwt.Parse(raw, func(token *jwt.Token) (interface{}, error) {
+		switch token.Method.(type) {
+		case *jwt.SigningMethodRSA, *jwt.SigningMethodECDSA:
+		default:
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+
+		kid, _ := token.Header["kid"].(string)
+		return p.publicKey(ctx, kid)
+	})
+	if err != nil || !token.Valid {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+	if !claims.VerifyIssuer(p.Issuer, true) {
+		return nil, fmt.Errorf("%w: wrong issuer", ErrInvalidToken)
+	}
+	if !audienceContains(claims["aud"], p.ClientID) {
+		return nil, fmt.Errorf("%w: wrong audience", ErrInvalidToken)
+	}
+	if _, ok := claims["exp"]; !ok {
+		return nil, fmt.Errorf("%w: no expiry", ErrInvalidToken)
+	}
+	if claimString(claims, "nonce") != nonce {
+		return nil, fmt.Errorf("%w: wrong nonce", ErrInvalidToken)
+	}
+
+	return claims, nil
+}
+
+// audienceContains - the aud claim may be a single string or a list
+func audienceContains(aud interface{}, clientID string) bool {
+	switch aud := aud.(type) {
+	case string:
+		return aud == clientID
+	case []interface{}:
+		for _, a := range aud {
+			if s, ok := a.(string); ok && s == clientID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// publicKey - finds the provider key with the kid, refetching the key set once when it is unknown
+// so that keys the provider rotated in are picked up
+func (p *Provider) publicKey(ctx context.Context, kid string) (interface{}, error) {
+	if p.JWKSURL == "" {
+		return nil, fmt.Errorf("%s publishes no keys", p.Name)
+	}
+	keyCacheInit.Lock()
+	if p.keys == nil {
+		p.keys = &keyCache{}
+	}
+	keyCacheInit.Unlock()
+
+	p.keys.mu.Lock()
+	defer p.keys.mu.Unlock()
+
+	if key, ok := p.keys.keys[kid]; ok && time.Since(p.keys.fetchedAt) < keyCacheLifetime {
+		return key, nil
+	}
+
+	keys, err := p.fetchKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	p.keys.keys = keys
+	p.keys.fetchedAt = time.Now()
+
+	if key, ok := keys[kid]; ok {
+		return key, nil
+	}
+	// a provider with a single key may leave the kid out
+	if kid == "" && len(keys) == 1 {
+		for _, key := range keys {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown key id: %q", kid)
+}
+
+func (p *Provider) fetchKeys(ctx context.Context) (map[string]interface{}, error) {
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := p.getJSON(ctx, p.JWKSURL, "", &set); err != nil {
+		return nil, fmt.Errorf("could not fetch the keys of %s: %w", p.Name, err)
+	}
+
+	keys := map[string]interface{}{}
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			// skip key types this client does not verify with
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+
+	return keys, nil
+}
+
+func (k jsonWebKey) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve: %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{
+			Curve: curve,
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type: %q", k.Kty)
+	}
+}
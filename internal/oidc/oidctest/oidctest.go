@@ -0,0 +1,209 @@
+// Package oidctest is a mock OpenID Connect provider for tests. It serves discovery, a key set and a token
+// endpoint from an httptest.Server, and checks what a real provider checks: the client, the redirect uri and
+// the PKCE verifier, and that a code is only exchanged once. There is no login page, a test authorizes a
+// user for an authorization url with Authorize and gets the code the provider would have redirected back with:
+//
+//	provider := oidctest.NewProvider(t)
+//	code, state := provider.Authorize(t, authURL, oidc.Identity{Subject: "alice"})
+package oidctest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/yuchida-tamu/git-workout-api/internal/oidc"
+)
+
+const (
+	ClientID     = "test-client"
+	ClientSecret = "test-secret"
+	RedirectURL  = "http://localhost/api/v1/auth/oidc/mock/callback"
+
+	keyID = "test-key"
+)
+
+// Provider - the mock provider; its Server is closed when the test ends
+type Provider struct {
+	Server *httptest.Server
+
+	key   *rsa.PrivateKey
+	mu    sync.Mutex
+	codes map[string]grant
+}
+
+// grant - what an authorization code was issued for
+type grant struct {
+	identity      oidc.Identity
+	redirectURI   string
+	codeChallenge string
+	nonce         string
+}
+
+func NewProvider(t *testing.T) *Provider {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := &Provider{
+		key:   key,
+		codes: map[string]grant{},
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", p.discovery)
+	mux.HandleFunc("/jwks", p.jwks)
+	mux.HandleFunc("/token", p.token)
+	p.Server = httptest.NewServer(mux)
+	t.Cleanup(p.Server.Close)
+
+	return p
+}
+
+// Issuer - the issuer the provider's id tokens are signed as
+func (p *Provider) Issuer() string {
+	return p.Server.URL
+}
+
+// Config - the configuration of a client of the provider, before discovery
+func (p *Provider) Config(name string) *oidc.Provider {
+	return &oidc.Provider{
+		Name:         name,
+		ClientID:     ClientID,
+		ClientSecret: ClientSecret,
+		RedirectURL:  RedirectURL,
+		Scopes:       []string{"openid", "email", "profile"},
+		Issuer:       p.Issuer(),
+		Client:       p.Server.Client(),
+	}
+}
+
+// Authorize - lets identity sign in at the authorization url, returning the code and the state the provider
+// redirects back with
+func (p *Provider) Authorize(t *testing.T, authURL string, identity oidc.Identity) (code string, state string) {
+	t.Helper()
+
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := u.Query()
+	if query.Get("client_id") != ClientID || query.Get("response_type") != "code" {
+		t.Fatalf("unexpected authorization request: %s", authURL)
+	}
+	if query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		t.Fatalf("the authorization request has no S256 code challenge: %s", authURL)
+	}
+
+	code = randomString(t)
+	p.mu.Lock()
+	p.codes[code] = grant{
+		identity:      identity,
+		redirectURI:   query.Get("redirect_uri"),
+		codeChallenge: query.Get("code_challenge"),
+		nonce:         query.Get("nonce"),
+	}
+	p.mu.Unlock()
+
+	return code, query.Get("state")
+}
+
+func (p *Provider) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, map[string]string{
+		"issuer":                 p.Issuer(),
+		"authorization_endpoint": p.Issuer() + "/authorize",
+		"token_endpoint":         p.Issuer() + "/token",
+		"jwks_uri":               p.Issuer() + "/jwks",
+	})
+}
+
+func (p *Provider) jwks(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kid": keyID,
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(p.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(p.key.E)).Bytes()),
+		}},
+	})
+}
+
+func (p *Provider) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("grant_type") != "authorization_code" {
+		http.Error(w, "invalid_request", http.StatusBadRequest)
+		return
+	}
+	if r.PostForm.Get("client_id") != ClientID || r.PostForm.Get("client_secret") != ClientSecret {
+		http.Error(w, "invalid_client", http.StatusUnauthorized)
+		return
+	}
+
+	// a code is redeemed once, whether or not the rest of the request is right
+	p.mu.Lock()
+	g, ok := p.codes[r.PostForm.Get("code")]
+	delete(p.codes, r.PostForm.Get("code"))
+	p.mu.Unlock()
+	if !ok ||
+		r.PostForm.Get("redirect_uri") != g.redirectURI ||
+		oidc.CodeChallenge(r.PostForm.Get("code_verifier")) != g.codeChallenge {
+		http.Error(w, "invalid_grant", http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":                p.Issuer(),
+		"aud":                ClientID,
+		"sub":                g.identity.Subject,
+		"email":              g.identity.Email,
+		"email_verified":     g.identity.EmailVerified,
+		"preferred_username": g.identity.Username,
+		"nonce":              g.nonce,
+		"iat":                now.Unix(),
+		"exp":                now.Add(time.Hour).Unix(),
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = keyID
+	idToken, err := token.SignedString(p.key)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, map[string]string{
+		"access_token": randomString(nil),
+		"token_type":   "Bearer",
+		"id_token":     idToken,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		panic(err)
+	}
+}
+
+func randomString(t *testing.T) string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		if t != nil {
+			t.Fatal(err)
+		}
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,248 @@
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrProviderError = errors.New("the identity provider returned an error")
+	ErrInvalidToken  = errors.New("invalid id token")
+)
+
+// Provider - an external identity provider users can sign in with. Providers with an Issuer are OpenID
+// Connect providers whose endpoints are discovered and whose id tokens are verified; providers without
+// one, such as GitHub, are plain OAuth2 and identify the user through UserInfoURL.
+type Provider struct {
+	Name         string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+
+	Issuer      string
+	AuthURL     string
+	TokenURL    string
+	UserInfoURL string
+	JWKSURL     string
+
+	Client *http.Client
+	keys   *keyCache
+}
+
+// Identity - who the provider says the user is. Subject is only unique within a provider.
+type Identity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Username      string
+}
+
+// Tokens - the tokens returned by the provider's token endpoint
+type Tokens struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	IDToken     string `json:"id_token"`
+}
+
+func (p *Provider) httpClient() *http.Client {
+	if p.Client != nil {
+		return p.Client
+	}
+	return &http.Client{Timeout: 10 * time.Second}
+}
+
+// Discover - fills in the endpoints the provider publishes at /.well-known/openid-configuration,
+// keeping any which were configured explicitly
+func (p *Provider) Discover(ctx context.Context) error {
+	if p.Issuer == "" {
+		return nil
+	}
+
+	var document struct {
+		Issuer                string `json:"issuer"`
+		AuthorizationEndpoint string `json:"authorization_endpoint"`
+		TokenEndpoint         string `json:"token_endpoint"`
+		UserInfoEndpoint      string `json:"userinfo_endpoint"`
+		JWKSURI               string `json:"jwks_uri"`
+	}
+	wellKnown := strings.TrimSuffix(p.Issuer, "/") + "/.well-known/openid-configuration"
+	if err := p.getJSON(ctx, wellKnown, "", &document); err != nil {
+		return fmt.Errorf("could not discover %s: %w", p.Name, err)
+	}
+	if document.Issuer != p.Issuer {
+		return fmt.Errorf("could not discover %s: issuer mismatch %q", p.Name, document.Issuer)
+	}
+
+	if p.AuthURL == "" {
+		p.AuthURL = document.AuthorizationEndpoint
+	}
+	if p.TokenURL == "" {
+		p.TokenURL = document.TokenEndpoint
+	}
+	if p.UserInfoURL == "" {
+		p.UserInfoURL = document.UserInfoEndpoint
+	}
+	if p.JWKSURL == "" {
+		p.JWKSURL = document.JWKSURI
+	}
+
+	return nil
+}
+
+// AuthCodeURL - the url to send the user to, asking for an authorization code bound to the PKCE verifier
+func (p *Provider) AuthCodeURL(state string, nonce string, verifier string) string {
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.ClientID},
+		"redirect_uri":          {p.RedirectURL},
+		"scope":                 {strings.Join(p.Scopes, " ")},
+		"state":                 {state},
+		"code_challenge":        {CodeChallenge(verifier)},
+		"code_challenge_method": {"S256"},
+	}
+	if p.Issuer != "" {
+		query.Set("nonce", nonce)
+	}
+
+	separator := "?"
+	if strings.Contains(p.AuthURL, "?") {
+		separator = "&"
+	}
+	return p.AuthURL + separator + query.Encode()
+}
+
+// Exchange - trades an authorization code for tokens
+func (p *Provider) Exchange(ctx context.Context, code string, verifier string) (Tokens, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.RedirectURL},
+		"client_id":     {p.ClientID},
+		"client_secret": {p.ClientSecret},
+		"code_verifier": {verifier},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return Tokens{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	// GitHub answers with a form encoded body unless asked for JSON
+	req.Header.Set("Accept", "application/json")
+
+	var tokens Tokens
+	if err := p.do(req, &tokens); err != nil {
+		return Tokens{}, fmt.Errorf("could not exchange the code with %s: %w", p.Name, err)
+	}
+	if tokens.AccessToken == "" && tokens.IDToken == "" {
+		return Tokens{}, fmt.Errorf("could not exchange the code with %s: %w", p.Name, ErrProviderError)
+	}
+
+	return tokens, nil
+}
+
+// Identify - works out who the tokens belong to. The id token is required from OpenID Connect providers
+// and is checked against the nonce the login was started with; plain OAuth2 providers are asked for
+// the user info instead.
+func (p *Provider) Identify(ctx context.Context, tokens Tokens, nonce string) (Identity, error) {
+	if p.Issuer != "" {
+		if tokens.IDToken == "" {
+			return Identity{}, ErrInvalidToken
+		}
+		claims, err := p.verifyIDToken(ctx, tokens.IDToken, nonce)
+		if err != nil {
+			return Identity{}, err
+		}
+		return identityFromClaims(claims), nil
+	}
+
+	if p.UserInfoURL == "" {
+		return Identity{}, fmt.Errorf("%s has no user info endpoint", p.Name)
+	}
+
+	var claims map[string]interface{}
+	if err := p.getJSON(ctx, p.UserInfoURL, tokens.AccessToken, &claims); err != nil {
+		return Identity{}, fmt.Errorf("could not fetch the user info from %s: %w", p.Name, err)
+	}
+
+	identity := identityFromClaims(claims)
+	if identity.Subject == "" {
+		return Identity{}, fmt.Errorf("the user info from %s has no subject", p.Name)
+	}
+	return identity, nil
+}
+
+// identityFromClaims - reads the standard claims, falling back to the field names GitHub uses
+func identityFromClaims(claims map[string]interface{}) Identity {
+	identity := Identity{
+		Subject:  claimString(claims, "sub"),
+		Email:    claimString(claims, "email"),
+		Username: claimString(claims, "preferred_username"),
+	}
+	if identity.Subject == "" {
+		identity.Subject = claimString(claims, "id")
+	}
+	if identity.Username == "" {
+		identity.Username = claimString(claims, "login")
+	}
+
+	switch verified := claims["email_verified"].(type) {
+	case bool:
+		identity.EmailVerified = verified
+	case string:
+		identity.EmailVerified = verified == "true"
+	}
+
+	return identity
+}
+
+func claimString(claims map[string]interface{}, name string) string {
+	switch value := claims[name].(type) {
+	case string:
+		return value
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	default:
+		return ""
+	}
+}
+
+func (p *Provider) getJSON(ctx context.Context, endpoint string, accessToken string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+
+	return p.do(req, v)
+}
+
+func (p *Provider) do(req *http.Request, v interface{}) error {
+	res, err := p.httpClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: %s", ErrProviderError, res.Status)
+	}
+
+	return json.Unmarshal(body, v)
+}
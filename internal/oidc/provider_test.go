@@ -0,0 +1,143 @@
+package oidc_test
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+
+	"github.com/yuchida-tamu/git-workout-api/internal/oidc"
+	"github.com/yuchida-tamu/git-workout-api/internal/oidc/oidctest"
+)
+
+func newProvider(t *testing.T) (*oidctest.Provider, *oidc.Provider) {
+	t.Helper()
+
+	mock := oidctest.NewProvider(t)
+	provider := mock.Config("mock")
+	if err := provider.Discover(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	return mock, provider
+}
+
+func TestDiscover(t *testing.T) {
+	mock, provider := newProvider(t)
+
+	if provider.AuthURL != mock.Issuer()+"/authorize" || provider.TokenURL != mock.Issuer()+"/token" || provider.JWKSURL != mock.Issuer()+"/jwks" {
+		t.Fatalf("the endpoints were not discovered: %+v", provider)
+	}
+
+	wrongIssuer := mock.Config("mock")
+	wrongIssuer.Issuer = mock.Issuer() + "/"
+	if err := wrongIssuer.Discover(context.Background()); err == nil {
+		t.Fatal("expected discovery to fail for an issuer which is not the one the provider names")
+	}
+}
+
+func TestLogin(t *testing.T) {
+	ctx := context.Background()
+	want := oidc.Identity{Subject: "alice", Email: "alice@example.com", EmailVerified: true, Username: "alice"}
+
+	t.Run("Identify", func(t *testing.T) {
+		mock, provider := newProvider(t)
+		verifier, err := oidc.NewVerifier()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		code, state := mock.Authorize(t, provider.AuthCodeURL("state", "nonce", verifier), want)
+		if state != "state" {
+			t.Fatalf("expected the state to be passed on, got %q", state)
+		}
+		tokens, err := provider.Exchange(ctx, code, verifier)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := provider.Identify(ctx, tokens, "nonce")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Fatalf("Identify returned %+v, want %+v", got, want)
+		}
+
+		// a code is only exchanged once
+		if _, err := provider.Exchange(ctx, code, verifier); !errors.Is(err, oidc.ErrProviderError) {
+			t.Fatalf("expected the second exchange of the code to fail, got %v", err)
+		}
+	})
+
+	t.Run("WrongVerifier", func(t *testing.T) {
+		mock, provider := newProvider(t)
+		verifier, err := oidc.NewVerifier()
+		if err != nil {
+			t.Fatal(err)
+		}
+		other, err := oidc.NewVerifier()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		code, _ := mock.Authorize(t, provider.AuthCodeURL("state", "nonce", verifier), want)
+		if _, err := provider.Exchange(ctx, code, other); !errors.Is(err, oidc.ErrProviderError) {
+			t.Fatalf("expected the exchange with another PKCE verifier to fail, got %v", err)
+		}
+	})
+
+	t.Run("WrongNonce", func(t *testing.T) {
+		mock, provider := newProvider(t)
+		verifier, err := oidc.NewVerifier()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		code, _ := mock.Authorize(t, provider.AuthCodeURL("state", "nonce", verifier), want)
+		tokens, err := provider.Exchange(ctx, code, verifier)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := provider.Identify(ctx, tokens, "another nonce"); !errors.Is(err, oidc.ErrInvalidToken) {
+			t.Fatalf("expected an id token with another nonce to be refused, got %v", err)
+		}
+	})
+
+	t.Run("WrongAudience", func(t *testing.T) {
+		mock, provider := newProvider(t)
+		verifier, err := oidc.NewVerifier()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// a token the provider issued to another client must not sign anyone in here
+		code, _ := mock.Authorize(t, provider.AuthCodeURL("state", "nonce", verifier), want)
+		tokens, err := provider.Exchange(ctx, code, verifier)
+		if err != nil {
+			t.Fatal(err)
+		}
+		provider.ClientID = "another-client"
+		if _, err := provider.Identify(ctx, tokens, "nonce"); !errors.Is(err, oidc.ErrInvalidToken) {
+			t.Fatalf("expected an id token for another client to be refused, got %v", err)
+		}
+	})
+}
+
+func TestAuthCodeURL(t *testing.T) {
+	_, provider := newProvider(t)
+
+	u, err := url.Parse(provider.AuthCodeURL("state", "nonce", "verifier"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := u.Query()
+	if query.Get("code_challenge") != oidc.CodeChallenge("verifier") || query.Get("code_challenge_method") != "S256" {
+		t.Fatalf("expected an S256 challenge of the verifier, got %s", u)
+	}
+	if query.Get("nonce") != "nonce" || query.Get("state") != "state" {
+		t.Fatalf("expected the state and nonce in the url, got %s", u)
+	}
+	if query.Get("code_verifier") != "" {
+		t.Fatal("the verifier must not leave the server")
+	}
+}
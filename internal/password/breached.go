@@ -0,0 +1,75 @@
+package password
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const rangePrefixLength = 5
+
+// BreachedList - an offline copy of a breached password hash list split into k-anonymity ranges,
+// the layout of the Pwned Passwords range API: Dir holds one file per 5 character upper case SHA-1
+// prefix (optionally with a .txt extension) whose lines are "SUFFIX:COUNT".
+// Only the range of the password's own hash prefix is ever read.
+type BreachedList struct {
+	Dir string
+}
+
+func NewBreachedList(dir string) *BreachedList {
+	return &BreachedList{
+		Dir: dir,
+	}
+}
+
+func (b *BreachedList) openRange(prefix string) (*os.File, error) {
+	f, err := os.Open(filepath.Join(b.Dir, prefix))
+	if errors.Is(err, os.ErrNotExist) {
+		return os.Open(filepath.Join(b.Dir, prefix+".txt"))
+	}
+	return f, err
+}
+
+// Count - how often the password appears in the list, zero if it does not
+func (b *BreachedList) Count(password string) (int, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:rangePrefixLength], hash[rangePrefixLength:]
+
+	f, err := b.openRange(prefix)
+	if errors.Is(err, os.ErrNotExist) {
+		// a missing range means no breached password has this prefix
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("could not open breached password range: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		lineSuffix, count, _ := strings.Cut(line, ":")
+		if !strings.EqualFold(lineSuffix, suffix) {
+			continue
+		}
+
+		n := 1
+		if count != "" {
+			if _, err := fmt.Sscan(count, &n); err != nil {
+				n = 1
+			}
+		}
+		return n, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, fmt.Errorf("could not read breached password range: %w", err)
+	}
+
+	return 0, nil
+}
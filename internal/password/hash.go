@@ -0,0 +1,223 @@
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Algorithm - a password hashing algorithm; it is also the prefix of the encoded hashes it produces
+type Algorithm string
+
+const (
+	AlgorithmBcrypt   Algorithm = "bcrypt"
+	AlgorithmArgon2id Algorithm = "argon2id"
+)
+
+var ErrUnknownHashFormat = errors.New("unknown password hash format")
+
+// Argon2Params - argon2id parameters; Memory is in KiB
+type Argon2Params struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// Hasher - hashes new passwords with the configured algorithm and parameters. Verify accepts every
+// supported format, so the configuration can change while older hashes keep working until they are
+// upgraded on the next login.
+type Hasher struct {
+	Algorithm  Algorithm
+	BcryptCost int
+	Argon2     Argon2Params
+}
+
+func DefaultHasher() Hasher {
+	return Hasher{
+		Algorithm:  AlgorithmBcrypt,
+		BcryptCost: 14,
+		// the second recommended option of RFC 9106, for memory constrained hosts
+		Argon2: Argon2Params{
+			Memory:      64 * 1024,
+			Iterations:  3,
+			Parallelism: 4,
+			SaltLength:  16,
+			KeyLength:   32,
+		},
+	}
+}
+
+func envUint(name string, bits int, target func(uint64)) error {
+	v := os.Getenv(name)
+	if v == "" {
+		return nil
+	}
+	n, err := strconv.ParseUint(v, 10, bits)
+	if err != nil || n == 0 {
+		return fmt.Errorf("invalid %s: %q", name, v)
+	}
+	target(n)
+	return nil
+}
+
+// NewHasherFromEnv - the default hasher adjusted by PASSWORD_HASH_ALGORITHM, PASSWORD_BCRYPT_COST,
+// PASSWORD_ARGON2_MEMORY (KiB), PASSWORD_ARGON2_ITERATIONS and PASSWORD_ARGON2_PARALLELISM
+func NewHasherFromEnv() (Hasher, error) {
+	h := DefaultHasher()
+
+	if v := os.Getenv("PASSWORD_HASH_ALGORITHM"); v != "" {
+		h.Algorithm = Algorithm(v)
+	}
+	if h.Algorithm != AlgorithmBcrypt && h.Algorithm != AlgorithmArgon2id {
+		return Hasher{}, fmt.Errorf("unknown PASSWORD_HASH_ALGORITHM: %q", h.Algorithm)
+	}
+
+	if v := os.Getenv("PASSWORD_BCRYPT_COST"); v != "" {
+		cost, err := strconv.Atoi(v)
+		if err != nil || cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+			return Hasher{}, fmt.Errorf("invalid PASSWORD_BCRYPT_COST: %q", v)
+		}
+		h.BcryptCost = cost
+	}
+
+	if err := envUint("PASSWORD_ARGON2_MEMORY", 32, func(n uint64) { h.Argon2.Memory = uint32(n) }); err != nil {
+		return Hasher{}, err
+	}
+	if err := envUint("PASSWORD_ARGON2_ITERATIONS", 32, func(n uint64) { h.Argon2.Iterations = uint32(n) }); err != nil {
+		return Hasher{}, err
+	}
+	if err := envUint("PASSWORD_ARGON2_PARALLELISM", 8, func(n uint64) { h.Argon2.Parallelism = uint8(n) }); err != nil {
+		return Hasher{}, err
+	}
+
+	return h, nil
+}
+
+// Hash - hashes the password into an encoded string which names its algorithm and parameters:
+// bcrypt's own "$2a$<cost>$..." or the PHC string "$argon2id$v=19$m=<memory>,t=<iterations>,p=<parallelism>$<salt>$<key>"
+func (h Hasher) Hash(password string) (string, error) {
+	switch h.Algorithm {
+	case AlgorithmBcrypt:
+		bytes, err := bcrypt.GenerateFromPassword([]byte(password), h.BcryptCost)
+		return string(bytes), err
+	case AlgorithmArgon2id:
+		salt := make([]byte, h.Argon2.SaltLength)
+		if _, err := rand.Read(salt); err != nil {
+			return "", err
+		}
+		key := argon2.IDKey([]byte(password), salt, h.Argon2.Iterations, h.Argon2.Memory, h.Argon2.Parallelism, h.Argon2.KeyLength)
+		return encodeArgon2id(h.Argon2, salt, key), nil
+	default:
+		return "", fmt.Errorf("unknown password hash algorithm: %q", h.Algorithm)
+	}
+}
+
+// Verify - checks the password against a hash in any supported format
+func Verify(password string, encoded string) (bool, error) {
+	switch algorithmOf(encoded) {
+	case AlgorithmBcrypt:
+		err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, nil
+		}
+		return err == nil, err
+	case AlgorithmArgon2id:
+		params, salt, key, err := decodeArgon2id(encoded)
+		if err != nil {
+			return false, err
+		}
+		other := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, params.KeyLength)
+		return subtle.ConstantTimeCompare(key, other) == 1, nil
+	default:
+		return false, ErrUnknownHashFormat
+	}
+}
+
+// NeedsRehash - reports whether the hash was made with another algorithm or other parameters than
+// the hasher's, so it should be replaced the next time the plain password is at hand
+func (h Hasher) NeedsRehash(encoded string) bool {
+	if algorithmOf(encoded) != h.Algorithm {
+		return true
+	}
+
+	switch h.Algorithm {
+	case AlgorithmBcrypt:
+		cost, err := bcrypt.Cost([]byte(encoded))
+		return err != nil || cost != h.BcryptCost
+	case AlgorithmArgon2id:
+		params, _, key, err := decodeArgon2id(encoded)
+		if err != nil {
+			return true
+		}
+		return params.Memory != h.Argon2.Memory ||
+			params.Iterations != h.Argon2.Iterations ||
+			params.Parallelism != h.Argon2.Parallelism ||
+			uint32(len(key)) != h.Argon2.KeyLength
+	default:
+		return true
+	}
+}
+
+func algorithmOf(encoded string) Algorithm {
+	switch {
+	case strings.HasPrefix(encoded, "$2a$"), strings.HasPrefix(encoded, "$2b$"), strings.HasPrefix(encoded, "$2y$"):
+		return AlgorithmBcrypt
+	case strings.HasPrefix(encoded, "$argon2id$"):
+		return AlgorithmArgon2id
+	default:
+		return ""
+	}
+}
+
+func encodeArgon2id(params Argon2Params, salt []byte, key []byte) string {
+	return fmt.Sprintf(
+		"$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version,
+		params.Memory,
+		params.Iterations,
+		params.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	)
+}
+
+func decodeArgon2id(encoded string) (Argon2Params, []byte, []byte, error) {
+	// "", "argon2id", "v=19", "m=...,t=...,p=...", salt, key
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 {
+		return Argon2Params{}, nil, nil, ErrUnknownHashFormat
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return Argon2Params{}, nil, nil, fmt.Errorf("unsupported argon2 version: %q", parts[2])
+	}
+
+	var params Argon2Params
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		return Argon2Params{}, nil, nil, fmt.Errorf("invalid argon2 parameters: %w", err)
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return Argon2Params{}, nil, nil, fmt.Errorf("invalid argon2 salt: %w", err)
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return Argon2Params{}, nil, nil, fmt.Errorf("invalid argon2 key: %w", err)
+	}
+
+	params.SaltLength = uint32(len(salt))
+	params.KeyLength = uint32(len(key))
+	return params, salt, key, nil
+}
@@ -0,0 +1,110 @@
+package password
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Policy - the rules a new password has to satisfy
+type Policy struct {
+	MinLength int
+	MaxLength int
+	// MinScore is the lowest acceptable Score, from 0 to 4
+	MinScore int
+	// Breached, when set, rejects passwords found in the local breached password list
+	Breached *BreachedList
+}
+
+// PolicyError - every rule a password broke, phrased for the user
+type PolicyError struct {
+	Problems []string
+}
+
+func (e *PolicyError) Error() string {
+	return "password does not meet the policy: " + strings.Join(e.Problems, "; ")
+}
+
+func DefaultPolicy() Policy {
+	return Policy{
+		MinLength: 10,
+		// bcrypt ignores everything past 72 bytes
+		MaxLength: 72,
+		MinScore:  3,
+	}
+}
+
+// NewPolicyFromEnv - the default policy adjusted by PASSWORD_MIN_LENGTH, PASSWORD_MIN_SCORE and
+// BREACHED_PASSWORDS_DIR
+func NewPolicyFromEnv() (Policy, error) {
+	policy := DefaultPolicy()
+
+	if v := os.Getenv("PASSWORD_MIN_LENGTH"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > policy.MaxLength {
+			return Policy{}, fmt.Errorf("invalid PASSWORD_MIN_LENGTH: %q", v)
+		}
+		policy.MinLength = n
+	}
+
+	if v := os.Getenv("PASSWORD_MIN_SCORE"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 || n > 4 {
+			return Policy{}, fmt.Errorf("invalid PASSWORD_MIN_SCORE: %q", v)
+		}
+		policy.MinScore = n
+	}
+
+	if dir := os.Getenv("BREACHED_PASSWORDS_DIR"); dir != "" {
+		if _, err := os.Stat(dir); err != nil {
+			return Policy{}, fmt.Errorf("invalid BREACHED_PASSWORDS_DIR: %w", err)
+		}
+		policy.Breached = NewBreachedList(dir)
+	}
+
+	return policy, nil
+}
+
+// Validate - checks the password against the policy. userInputs are details of the user, like the
+// username or email address, which must not make up the password. It returns a *PolicyError listing
+// every problem, or another error if the breached list could not be read.
+func (p Policy) Validate(password string, userInputs ...string) error {
+	var problems []string
+
+	length := len([]rune(password))
+	if length < p.MinLength {
+		problems = append(problems, fmt.Sprintf("must be at least %d characters long", p.MinLength))
+	}
+	if p.MaxLength > 0 && len(password) > p.MaxLength {
+		problems = append(problems, fmt.Sprintf("must be at most %d bytes long", p.MaxLength))
+	}
+
+	lowered := strings.ToLower(password)
+	for _, input := range userInputs {
+		if len(input) >= 3 && strings.Contains(lowered, strings.ToLower(input)) {
+			problems = append(problems, "must not contain your username or email address")
+			break
+		}
+	}
+
+	if length >= p.MinLength && Score(password, userInputs...) < p.MinScore {
+		problems = append(problems, "is too easy to guess: use a longer passphrase of uncommon words, avoid keyboard patterns, sequences and repeated characters")
+	}
+
+	if p.Breached != nil && password != "" {
+		count, err := p.Breached.Count(password)
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			problems = append(problems, "has appeared in a data breach and must not be used")
+		}
+	}
+
+	if len(problems) > 0 {
+		return &PolicyError{Problems: problems}
+	}
+
+	return nil
+}
@@ -0,0 +1,158 @@
+package password
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// commonPasswords are among the most used passwords; a password built around one of them is guessed
+// long before brute force would get to it
+var commonPasswords = []string{
+	"123456", "password", "12345678", "qwerty", "123456789", "12345", "1234", "111111", "1234567",
+	"dragon", "123123", "baseball", "abc123", "football", "monkey", "letmein", "696969", "shadow",
+	"master", "666666", "qwertyuiop", "123321", "mustang", "1234567890", "michael", "654321",
+	"superman", "1qaz2wsx", "7777777", "121212", "000000", "qazwsx", "123qwe", "killer", "trustno1",
+	"jordan", "jennifer", "zxcvbnm", "asdfgh", "hunter", "buster", "soccer", "harley", "batman",
+	"andrew", "tigger", "sunshine", "iloveyou", "2000", "charlie", "robert", "thomas", "hockey",
+	"ranger", "daniel", "starwars", "klaster", "112233", "george", "computer", "michelle", "jessica",
+	"pepper", "1111", "zxcvbn", "555555", "11111111", "131313", "freedom", "777777", "pass",
+	"maggie", "159753", "aaaaaa", "ginger", "princess", "joshua", "cheese", "amanda", "summer",
+	"love", "ashley", "nicole", "chelsea", "biteme", "matthew", "access", "yankees", "987654321",
+	"dallas", "austin", "thunder", "taylor", "matrix", "welcome", "admin", "login", "passw0rd",
+	"workout", "fitness", "github", "gitworkout", "secret", "changeme", "default",
+}
+
+// keyboardRows are checked for runs of adjacent keys such as "asdf" or "7890"
+var keyboardRows = []string{
+	"`1234567890-=",
+	"qwertyuiop[]\\",
+	"asdfghjkl;'",
+	"zxcvbnm,./",
+	"abcdefghijklmnopqrstuvwxyz",
+}
+
+func charsetSize(password string) float64 {
+	var lower, upper, digit, symbol, other bool
+	for _, r := range password {
+		switch {
+		case r > unicode.MaxASCII:
+			other = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+
+	size := 0.0
+	if lower {
+		size += 26
+	}
+	if upper {
+		size += 26
+	}
+	if digit {
+		size += 10
+	}
+	if symbol {
+		size += 33
+	}
+	if other {
+		size += 100
+	}
+	return math.Max(size, 1)
+}
+
+// isPredictableStep reports whether b continues a repeat, a keyboard run or an alphabet/number sequence after a
+func isPredictableStep(a, b rune) bool {
+	a, b = unicode.ToLower(a), unicode.ToLower(b)
+	if a == b || b == a+1 || b == a-1 {
+		return true
+	}
+	for _, row := range keyboardRows {
+		i := strings.IndexRune(row, a)
+		if i < 0 {
+			continue
+		}
+		if (i+1 < len(row) && rune(row[i+1]) == b) || (i > 0 && rune(row[i-1]) == b) {
+			return true
+		}
+	}
+	return false
+}
+
+// stripMatches removes every occurrence of the words from password and returns what is left and
+// how many bits the removed words are worth to an attacker who tries them first
+func stripMatches(password string, words []string, bitsPerWord float64) (string, float64) {
+	bits := 0.0
+	lowered := strings.ToLower(password)
+	for _, word := range words {
+		word = strings.ToLower(word)
+		if len(word) < 3 {
+			continue
+		}
+		for {
+			i := strings.Index(lowered, word)
+			if i < 0 {
+				break
+			}
+			lowered = lowered[:i] + lowered[i+len(word):]
+			password = password[:i] + password[i+len(word):]
+			bits += bitsPerWord
+		}
+	}
+	return password, bits
+}
+
+// leetReplacer undoes the usual letter substitutions so "p@ssw0rd" is recognised as "password"
+var leetReplacer = strings.NewReplacer("@", "a", "4", "a", "3", "e", "1", "i", "!", "i", "0", "o", "$", "s", "5", "s", "7", "t")
+
+// EstimateBits - a rough estimate, in the spirit of zxcvbn, of log2 of the guesses needed to find the password.
+// Dictionary words and the user's own details cost an attacker almost nothing, predictable runs of
+// characters little, and only the remaining characters count at full strength.
+func EstimateBits(password string, userInputs ...string) float64 {
+	if password == "" {
+		return 0
+	}
+
+	size := charsetSize(password)
+	rest := leetReplacer.Replace(password)
+
+	rest, inputBits := stripMatches(rest, userInputs, 2)
+	rest, commonBits := stripMatches(rest, commonPasswords, math.Log2(float64(len(commonPasswords))))
+
+	bits := inputBits + commonBits
+	runes := []rune(rest)
+	for i, r := range runes {
+		if i > 0 && isPredictableStep(runes[i-1], r) {
+			bits += 1
+			continue
+		}
+		bits += math.Log2(size)
+	}
+
+	return bits
+}
+
+// Score - buckets EstimateBits into 0 (too guessable) to 4 (very unguessable) on the zxcvbn scale,
+// whose thresholds are 10^3, 10^6, 10^8 and 10^10 guesses
+func Score(password string, userInputs ...string) int {
+	bits := EstimateBits(password, userInputs...)
+	switch {
+	case bits < 10:
+		return 0
+	case bits < 20:
+		return 1
+	case bits < 26.6:
+		return 2
+	case bits < 33.2:
+		return 3
+	default:
+		return 4
+	}
+}
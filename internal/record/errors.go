@@ -0,0 +1,16 @@
+package record
+
+import "errors"
+
+var (
+	// ErrNotFound - the record does not exist
+	ErrNotFound = errors.New("record not found")
+	// ErrConflict - the record clashes with one which exists
+	ErrConflict = errors.New("record conflict")
+	// ErrValidation - the record is not valid, or refers to something which does not exist
+	ErrValidation = errors.New("invalid record")
+	// ErrForbidden - the caller may not access the record
+	ErrForbidden = errors.New("not allowed to access the record")
+	// ErrVersionMismatch - a write expected a version of the record which is no longer current
+	ErrVersionMismatch = errors.New("the record has been changed since it was read")
+)
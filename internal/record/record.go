@@ -2,7 +2,9 @@ package record
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
 	uuid "github.com/satori/go.uuid"
 	"github.com/yuchida-tamu/git-workout-api/internal/user"
@@ -13,16 +15,36 @@ type Record struct {
 	DateCreated string
 	MessageBody string
 	Author      string
+	// Version counts the updates of the record, starting at 1
+	Version int
+}
+
+// Comment - an annotation left on a record, either by its author or by their coach
+type Comment struct {
+	ID          string
+	RecordID    string
+	Author      string
+	MessageBody string
+	DateCreated time.Time
 }
 
 type Store interface {
 	GetRecordsByAuthor(context.Context, string) ([]Record, error)
 	GetRecordById(context.Context, string) (Record, error)
 	PostRecord(context.Context, Record) (Record, error)
+	// UpdateRecord only updates a record still at rcd.Version, unless that is 0, and returns it with the next version
 	UpdateRecord(ctx context.Context, ID string, rcd Record) (Record, error)
-	DeleteRecord(context.Context, string) error
+	// DeleteRecord deletes the record if it is still at version, any version when that is 0, and fails with
+	// ErrVersionMismatch otherwise
+	DeleteRecord(ctx context.Context, ID string, version int) error
+	GetCommentsByRecord(ctx context.Context, recordID string) ([]Comment, error)
+	PostComment(context.Context, Comment) (Comment, error)
 
 	GetUser(context.Context, string) (user.User, error)
+
+	// WithTx - runs fn in a transaction which every call passing on fn's context takes part in; it rolls back
+	// when fn returns an error or panics
+	WithTx(ctx context.Context, fn func(ctx context.Context) error) error
 }
 
 type Service struct {
@@ -55,14 +77,7 @@ func (s *Service) GetRecordById(ctx context.Context, ID string) (Record, error)
 }
 
 func (s *Service) PostRecord(ctx context.Context, rcd Record) (Record, error) {
-	// validate uuid format
-	if _, err := uuid.FromString(rcd.Author); err != nil {
-		fmt.Println(err)
-		return Record{}, err
-	}
-	// check if the user already exists
-	if _, err := s.Store.GetUser(ctx, rcd.Author); err != nil {
-		fmt.Print(err)
+	if err := s.checkAuthor(ctx, rcd.Author); err != nil {
 		return Record{}, err
 	}
 
@@ -76,14 +91,7 @@ func (s *Service) PostRecord(ctx context.Context, rcd Record) (Record, error) {
 }
 
 func (s *Service) UpdateRecord(ctx context.Context, ID string, rcd Record) (Record, error) {
-	// validate uuid format
-	if _, err := uuid.FromString(rcd.Author); err != nil {
-		fmt.Println(err)
-		return Record{}, err
-	}
-	// check if the user already exists
-	if _, err := s.Store.GetUser(ctx, rcd.Author); err != nil {
-		fmt.Print(err)
+	if err := s.checkAuthor(ctx, rcd.Author); err != nil {
 		return Record{}, err
 	}
 
@@ -96,12 +104,84 @@ func (s *Service) UpdateRecord(ctx context.Context, ID string, rcd Record) (Reco
 	return updatedRecord, nil
 }
 
-func (s *Service) DeleteRecord(ctx context.Context, ID string) error {
-	err := s.Store.DeleteRecord(ctx, ID)
+// ImportRecords - posts records of one author all at once; when one of them fails none is kept
+func (s *Service) ImportRecords(ctx context.Context, author string, rcds []Record) ([]Record, error) {
+	if err := s.checkAuthor(ctx, author); err != nil {
+		return []Record{}, err
+	}
+
+	imported := make([]Record, 0, len(rcds))
+	err := s.Store.WithTx(ctx, func(ctx context.Context) error {
+		for _, rcd := range rcds {
+			rcd.Author = author
+			postedRecord, err := s.Store.PostRecord(ctx, rcd)
+			if err != nil {
+				return err
+			}
+			imported = append(imported, postedRecord)
+		}
+		return nil
+	})
 	if err != nil {
 		fmt.Println(err)
+		return []Record{}, err
+	}
+
+	return imported, nil
+}
+
+// checkAuthor - a record has to belong to an existing user
+func (s *Service) checkAuthor(ctx context.Context, author string) error {
+	// validate uuid format
+	if _, err := uuid.FromString(author); err != nil {
+		fmt.Println(err)
+		return fmt.Errorf("%w: the author is not a valid id", ErrValidation)
+	}
+	// check if the user already exists
+	if _, err := s.Store.GetUser(ctx, author); err != nil {
+		fmt.Println(err)
+		if errors.Is(err, user.ErrNotFound) {
+			return fmt.Errorf("%w: the author does not exist", ErrValidation)
+		}
 		return err
 	}
 
 	return nil
 }
+
+// DeleteRecord - deletes the record if it is still at version, any version when that is 0
+func (s *Service) DeleteRecord(ctx context.Context, ID string, version int) error {
+	if err := s.Store.DeleteRecord(ctx, ID, version); err != nil {
+		fmt.Println(err)
+		return err
+	}
+
+	return nil
+}
+
+func (s *Service) GetCommentsByRecord(ctx context.Context, recordID string) ([]Comment, error) {
+	comments, err := s.Store.GetCommentsByRecord(ctx, recordID)
+	if err != nil {
+		fmt.Println(err)
+		return []Comment{}, err
+	}
+
+	return comments, nil
+}
+
+func (s *Service) PostComment(ctx context.Context, comment Comment) (Comment, error) {
+	// check if the record exists
+	if _, err := s.Store.GetRecordById(ctx, comment.RecordID); err != nil {
+		fmt.Println(err)
+		return Comment{}, err
+	}
+
+	comment.DateCreated = time.Now().UTC()
+	postedComment, err := s.Store.PostComment(ctx, comment)
+	if err != nil {
+		fmt.Println(err)
+		return Comment{}, err
+	}
+
+	return postedComment, nil
+}
@@ -0,0 +1,272 @@
+package signing
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+const (
+	privateKeySuffix = ".pem"
+	publicKeySuffix  = ".pub.pem"
+	// hmacKeyID names the shared secret key used when no key directory is configured
+	hmacKeyID = "hs256"
+)
+
+// Key - a key tokens are signed or verified with. Keys loaded from a public key file have no Private part
+// and only verify tokens, which is how retired keys stay usable until their tokens expire.
+type Key struct {
+	ID      string
+	Method  jwt.SigningMethod
+	Private interface{}
+	Public  interface{}
+}
+
+// KeySet - every key the API accepts, plus the one it currently signs with
+type KeySet struct {
+	signing *Key
+	keys    map[string]*Key
+}
+
+// NewHMACKeySet - a key set holding a single HS256 shared secret
+func NewHMACKeySet(secret []byte) *KeySet {
+	key := &Key{
+		ID:      hmacKeyID,
+		Method:  jwt.SigningMethodHS256,
+		Private: secret,
+		Public:  secret,
+	}
+
+	return &KeySet{
+		signing: key,
+		keys:    map[string]*Key{key.ID: key},
+	}
+}
+
+// LoadKeySet - loads signing keys from the directory named by JWT_KEYS_DIR, falling back to an HS256 key
+// from SIGNING_SECRET when it is not set; one of the two is required. In the directory <kid>.pem holds an RSA or Ed25519 private key
+// (PKCS#1 or PKCS#8) and <kid>.pub.pem a public key which only verifies. JWT_SIGNING_KEY_ID picks the key
+// new tokens are signed with; by default it is the last private key in lexical order, so naming keys by
+// date rotates to the newest one.
+func LoadKeySet() (*KeySet, error) {
+	dir := os.Getenv("JWT_KEYS_DIR")
+	if dir == "" {
+		secret := os.Getenv("SIGNING_SECRET")
+		if secret == "" {
+			// an empty HS256 key would let anyone sign tokens the API accepts
+			return nil, errors.New("neither JWT_KEYS_DIR nor SIGNING_SECRET is set")
+		}
+		return NewHMACKeySet([]byte(secret)), nil
+	}
+
+	return LoadKeySetFromDir(dir, os.Getenv("JWT_SIGNING_KEY_ID"))
+}
+
+// LoadKeySetFromDir - loads every key file in dir, see LoadKeySet for the layout
+func LoadKeySetFromDir(dir string, signingKeyID string) (*KeySet, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("could not read the key directory: %w", err)
+	}
+
+	ks := &KeySet{keys: map[string]*Key{}}
+	var privateIDs []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, privateKeySuffix) {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, fmt.Errorf("could not read key %s: %w", name, err)
+		}
+
+		var key *Key
+		if strings.HasSuffix(name, publicKeySuffix) {
+			key, err = parsePublicKey(strings.TrimSuffix(name, publicKeySuffix), data)
+		} else {
+			key, err = parsePrivateKey(strings.TrimSuffix(name, privateKeySuffix), data)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not parse key %s: %w", name, err)
+		}
+
+		if existing, ok := ks.keys[key.ID]; ok && existing.Private != nil {
+			// a private key already carries its public half
+			continue
+		}
+		ks.keys[key.ID] = key
+		if key.Private != nil {
+			privateIDs = append(privateIDs, key.ID)
+		}
+	}
+
+	if len(privateIDs) == 0 {
+		return nil, errors.New("the key directory holds no private key to sign with")
+	}
+
+	if signingKeyID == "" {
+		sort.Strings(privateIDs)
+		signingKeyID = privateIDs[len(privateIDs)-1]
+	}
+	signingKey, ok := ks.keys[signingKeyID]
+	if !ok || signingKey.Private == nil {
+		return nil, fmt.Errorf("no private key with id %q", signingKeyID)
+	}
+	ks.signing = signingKey
+
+	return ks, nil
+}
+
+func parsePrivateKey(kid string, data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	var parsed interface{}
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		return &Key{ID: kid, Method: jwt.SigningMethodRS256, Private: k, Public: &k.PublicKey}, nil
+	case ed25519.PrivateKey:
+		return &Key{ID: kid, Method: SigningMethodEdDSA, Private: k, Public: k.Public()}, nil
+	default:
+		return nil, fmt.Errorf("unsupported private key type %T", parsed)
+	}
+}
+
+func parsePublicKey(kid string, data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	var parsed interface{}
+	var err error
+	switch block.Type {
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch k := parsed.(type) {
+	case *rsa.PublicKey:
+		return &Key{ID: kid, Method: jwt.SigningMethodRS256, Public: k}, nil
+	case ed25519.PublicKey:
+		return &Key{ID: kid, Method: SigningMethodEdDSA, Public: k}, nil
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T", parsed)
+	}
+}
+
+// Sign - signs the claims with the current signing key, naming it in the kid header
+func (ks *KeySet) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(ks.signing.Method, claims)
+	token.Header["kid"] = ks.signing.ID
+
+	return token.SignedString(ks.signing.Private)
+}
+
+// Parse - parses and verifies a token with the key named by its kid header.
+// A token without a kid is only accepted when the set consists of the single HS256 key.
+func (ks *KeySet) Parse(tokenString string) (*jwt.Token, error) {
+	return jwt.Parse(tokenString, ks.keyFunc)
+}
+
+func (ks *KeySet) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" && ks.signing.ID == hmacKeyID {
+		kid = hmacKeyID
+	}
+
+	key, ok := ks.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key: %q", kid)
+	}
+
+	// the algorithm has to be the key's own, never whatever the token header claims
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+
+	return key.Public, nil
+}
+
+// JWK - a public key in JSON Web Key format (RFC 7517)
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS - the public keys other services need to verify tokens; shared secrets are never published
+func (ks *KeySet) JWKS() JWKS {
+	ids := make([]string, 0, len(ks.keys))
+	for id := range ks.keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	jwks := JWKS{Keys: []JWK{}}
+	for _, id := range ids {
+		key := ks.keys[id]
+		switch pub := key.Public.(type) {
+		case *rsa.PublicKey:
+			jwks.Keys = append(jwks.Keys, JWK{
+				Kty: "RSA",
+				Kid: key.ID,
+				Use: "sig",
+				Alg: key.Method.Alg(),
+				N:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			jwks.Keys = append(jwks.Keys, JWK{
+				Kty: "OKP",
+				Kid: key.ID,
+				Use: "sig",
+				Alg: key.Method.Alg(),
+				Crv: "Ed25519",
+				X:   base64.RawURLEncoding.EncodeToString(pub),
+			})
+		}
+	}
+
+	return jwks
+}
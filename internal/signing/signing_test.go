@@ -0,0 +1,233 @@
+package signing_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/yuchida-tamu/git-workout-api/internal/signing"
+)
+
+// the smallest RSA key crypto/rsa generates quickly enough for every test run
+const testRSABits = 1024
+
+func writePEM(t *testing.T, dir string, name string, blockType string, der []byte) {
+	t.Helper()
+
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	if err := os.WriteFile(filepath.Join(dir, name), data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func marshalPKCS8(t *testing.T, key interface{}) []byte {
+	t.Helper()
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der
+}
+
+// keyDir - a key directory of a PKCS#1 and a PKCS#8 RSA key and a PKCS#8 Ed25519 key, named so that the
+// Ed25519 key comes last
+func keyDir(t *testing.T) (string, *rsa.PrivateKey, ed25519.PrivateKey) {
+	t.Helper()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, testRSABits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherRSAKey, err := rsa.GenerateKey(rand.Reader, testRSABits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	writePEM(t, dir, "2024-01.pem", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey))
+	writePEM(t, dir, "2024-06.pem", "PRIVATE KEY", marshalPKCS8(t, otherRSAKey))
+	writePEM(t, dir, "2025-01.pem", "PRIVATE KEY", marshalPKCS8(t, edKey))
+
+	return dir, rsaKey, edKey
+}
+
+func signAndParse(t *testing.T, ks *signing.KeySet) *jwt.Token {
+	t.Helper()
+
+	signed, err := ks.Sign(jwt.MapClaims{"userId": "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := ks.Parse(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestLoadKeySet(t *testing.T) {
+	t.Run("NoKeys", func(t *testing.T) {
+		t.Setenv("JWT_KEYS_DIR", "")
+		t.Setenv("SIGNING_SECRET", "")
+
+		if _, err := signing.LoadKeySet(); err == nil {
+			t.Fatal("expected loading to fail without a key directory or a secret")
+		}
+	})
+
+	t.Run("Secret", func(t *testing.T) {
+		t.Setenv("JWT_KEYS_DIR", "")
+		t.Setenv("SIGNING_SECRET", "secret")
+
+		ks, err := signing.LoadKeySet()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token := signAndParse(t, ks); token.Method.Alg() != "HS256" {
+			t.Fatalf("expected an HS256 token, got %s", token.Method.Alg())
+		}
+		if len(ks.JWKS().Keys) != 0 {
+			t.Fatal("a shared secret must never be published")
+		}
+	})
+
+	t.Run("SigningKeyID", func(t *testing.T) {
+		dir, _, _ := keyDir(t)
+		t.Setenv("JWT_KEYS_DIR", dir)
+		t.Setenv("JWT_SIGNING_KEY_ID", "2024-01")
+
+		ks, err := signing.LoadKeySet()
+		if err != nil {
+			t.Fatal(err)
+		}
+		token := signAndParse(t, ks)
+		if token.Header["kid"] != "2024-01" || token.Method.Alg() != "RS256" {
+			t.Fatalf("expected an RS256 token of the chosen key, got %v", token.Header)
+		}
+	})
+}
+
+func TestLoadKeySetFromDir(t *testing.T) {
+	dir, _, _ := keyDir(t)
+
+	t.Run("DefaultKey", func(t *testing.T) {
+		ks, err := signing.LoadKeySetFromDir(dir, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		token := signAndParse(t, ks)
+		if token.Header["kid"] != "2025-01" || token.Method.Alg() != "EdDSA" {
+			t.Fatalf("expected an EdDSA token of the last key, got %v", token.Header)
+		}
+		if len(ks.JWKS().Keys) != 3 {
+			t.Fatalf("expected every key to be published, got %+v", ks.JWKS())
+		}
+	})
+
+	t.Run("PKCS8RSA", func(t *testing.T) {
+		ks, err := signing.LoadKeySetFromDir(dir, "2024-06")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token := signAndParse(t, ks); token.Method.Alg() != "RS256" {
+			t.Fatalf("expected an RS256 token, got %s", token.Method.Alg())
+		}
+	})
+
+	t.Run("UnknownKeyID", func(t *testing.T) {
+		if _, err := signing.LoadKeySetFromDir(dir, "2023-01"); err == nil {
+			t.Fatal("expected loading to fail for a signing key which is not there")
+		}
+	})
+
+	t.Run("NoPrivateKey", func(t *testing.T) {
+		if _, err := signing.LoadKeySetFromDir(t.TempDir(), ""); err == nil {
+			t.Fatal("expected loading to fail without a key to sign with")
+		}
+	})
+}
+
+func TestRetiredKey(t *testing.T) {
+	dir, rsaKey, _ := keyDir(t)
+	ks, err := signing.LoadKeySetFromDir(dir, "2024-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed, err := ks.Sign(jwt.MapClaims{"userId": "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the private key is gone, only its public half is kept to verify the tokens still around
+	if err := os.Remove(filepath.Join(dir, "2024-01.pem")); err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writePEM(t, dir, "2024-01.pub.pem", "PUBLIC KEY", der)
+
+	if _, err := signing.LoadKeySetFromDir(dir, "2024-01"); err == nil {
+		t.Fatal("expected a public key to be refused as the signing key")
+	}
+	rotated, err := signing.LoadKeySetFromDir(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rotated.Parse(signed); err != nil {
+		t.Fatalf("expected a token of the retired key to verify, got %v", err)
+	}
+}
+
+func TestParseRejectsForeignAlgorithm(t *testing.T) {
+	dir, rsaKey, edKey := keyDir(t)
+	ks, err := signing.LoadKeySetFromDir(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+
+	for _, c := range []struct {
+		name   string
+		kid    string
+		method jwt.SigningMethod
+		key    interface{}
+	}{
+		// the public key is no secret, an HS256 token keyed with it must not pass for the RSA key's
+		{"HS256WithRSAKey", "2024-01", jwt.SigningMethodHS256, publicPEM},
+		{"RS256WithEdDSAKey", "2025-01", jwt.SigningMethodRS256, rsaKey},
+		{"EdDSAWithRSAKey", "2024-01", signing.SigningMethodEdDSA, edKey},
+		{"NoKeyID", "", jwt.SigningMethodRS256, rsaKey},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			token := jwt.NewWithClaims(c.method, jwt.MapClaims{"userId": "mallory"})
+			if c.kid != "" {
+				token.Header["kid"] = c.kid
+			}
+			signed, err := token.SignedString(c.key)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := ks.Parse(signed); err == nil {
+				t.Fatal("expected a token signed with another algorithm than its key's to be refused")
+			}
+		})
+	}
+}
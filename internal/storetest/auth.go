@@ -0,0 +1,217 @@
+package storetest
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/yuchida-tamu/git-workout-api/internal/auth"
+)
+
+func runAuthTests(t *testing.T, newStore Factory) {
+	// the single-use checks are what stops a refresh token, reset link or login state from being redeemed
+	// twice, so exactly one of any number of concurrent callers may win
+	t.Run("RefreshTokenUsedOnce", func(t *testing.T) {
+		ctx := context.Background()
+		store := newStore(t)
+		u := postUser(ctx, t, store)
+
+		token, err := store.PostRefreshToken(ctx, auth.RefreshToken{
+			UserID:      u.ID,
+			FamilyID:    uuid.NewV4().String(),
+			TokenHash:   unique("refresh"),
+			ExpiresAt:   now().Add(time.Hour),
+			DateCreated: now(),
+		})
+		requireNoError(t, err)
+
+		requireSingleWinner(t, func() (bool, error) { return store.MarkRefreshTokenUsed(ctx, token.ID) })
+
+		got, err := store.GetRefreshTokenByHash(ctx, token.TokenHash)
+		requireNoError(t, err)
+		if !got.Used {
+			t.Fatal("the refresh token is not marked as used")
+		}
+	})
+
+	t.Run("RevokeRefreshTokenFamily", func(t *testing.T) {
+		ctx := context.Background()
+		store := newStore(t)
+		u := postUser(ctx, t, store)
+		familyID := uuid.NewV4().String()
+
+		var hashes []string
+		for _, family := range []string{familyID, familyID, uuid.NewV4().String()} {
+			token, err := store.PostRefreshToken(ctx, auth.RefreshToken{
+				UserID:      u.ID,
+				FamilyID:    family,
+				TokenHash:   unique("refresh"),
+				ExpiresAt:   now().Add(time.Hour),
+				DateCreated: now(),
+			})
+			requireNoError(t, err)
+			hashes = append(hashes, token.TokenHash)
+		}
+
+		requireNoError(t, store.RevokeRefreshTokenFamily(ctx, familyID))
+
+		for i, hash := range hashes {
+			got, err := store.GetRefreshTokenByHash(ctx, hash)
+			requireNoError(t, err)
+			if want := i < 2; got.Revoked != want {
+				t.Fatalf("token %d: expected revoked=%v, got %v", i, want, got.Revoked)
+			}
+		}
+	})
+
+	t.Run("OneTimeTokenUsedOnce", func(t *testing.T) {
+		ctx := context.Background()
+		store := newStore(t)
+		u := postUser(ctx, t, store)
+
+		token, err := store.PostOneTimeToken(ctx, auth.OneTimeToken{
+			UserID:      u.ID,
+			Purpose:     auth.PurposePasswordReset,
+			TokenHash:   unique("reset"),
+			ExpiresAt:   now().Add(time.Hour),
+			DateCreated: now(),
+		})
+		requireNoError(t, err)
+
+		requireSingleWinner(t, func() (bool, error) { return store.MarkOneTimeTokenUsed(ctx, token.ID) })
+	})
+
+	t.Run("MFACodesUsedOnce", func(t *testing.T) {
+		ctx := context.Background()
+		store := newStore(t)
+		u := postUser(ctx, t, store)
+		hashes := []string{auth.HashToken(unique("code")), auth.HashToken(unique("code")), auth.HashToken(unique("code"))}
+
+		requireNoError(t, store.PutMFA(ctx, auth.MFA{
+			UserID:             u.ID,
+			Secret:             "secret",
+			Enabled:            true,
+			RecoveryCodeHashes: hashes,
+			LastUsedStep:       10,
+			DateCreated:        now(),
+		}))
+
+		requireSingleWinner(t, func() (bool, error) { return store.MarkMFAStepUsed(ctx, u.ID, 11) })
+		// an earlier step is as used as the last one
+		marked, err := store.MarkMFAStepUsed(ctx, u.ID, 9)
+		requireNoError(t, err)
+		if marked {
+			t.Fatal("expected a step before the last used one to be refused")
+		}
+
+		// the first and the last code, the list is cut differently at either end
+		for _, hash := range []string{hashes[0], hashes[2]} {
+			requireSingleWinner(t, func() (bool, error) { return store.RemoveMFARecoveryCode(ctx, u.ID, hash) })
+		}
+
+		got, err := store.GetMFA(ctx, u.ID)
+		requireNoError(t, err)
+		if got.LastUsedStep != 11 {
+			t.Fatalf("expected the last used step 11, got %d", got.LastUsedStep)
+		}
+		if len(got.RecoveryCodeHashes) != 1 || got.RecoveryCodeHashes[0] != hashes[1] {
+			t.Fatalf("expected only the unused recovery code to be left, got %v", got.RecoveryCodeHashes)
+		}
+
+		requireSingleWinner(t, func() (bool, error) { return store.RemoveMFARecoveryCode(ctx, u.ID, hashes[1]) })
+		got, err = store.GetMFA(ctx, u.ID)
+		requireNoError(t, err)
+		if len(got.RecoveryCodeHashes) != 0 {
+			t.Fatalf("expected no recovery code to be left, got %v", got.RecoveryCodeHashes)
+		}
+	})
+
+	t.Run("OIDCLoginDeletedOnce", func(t *testing.T) {
+		ctx := context.Background()
+		store := newStore(t)
+
+		login, err := store.PostOIDCLogin(ctx, auth.OIDCLogin{
+			StateHash:   unique("state"),
+			Provider:    "mock",
+			Verifier:    "verifier",
+			Nonce:       "nonce",
+			ExpiresAt:   now().Add(time.Minute),
+			DateCreated: now(),
+		})
+		requireNoError(t, err)
+
+		got, err := store.GetOIDCLoginByStateHash(ctx, login.StateHash)
+		requireNoError(t, err)
+		if got.LinkUserID != "" {
+			t.Fatalf("expected no user to link, got %q", got.LinkUserID)
+		}
+
+		requireSingleWinner(t, func() (bool, error) { return store.DeleteOIDCLogin(ctx, login.ID) })
+
+		_, err = store.GetOIDCLoginByStateHash(ctx, login.StateHash)
+		requireError(t, err, auth.ErrNotFound)
+	})
+
+	t.Run("SessionsMostRecentlyUsedFirst", func(t *testing.T) {
+		ctx := context.Background()
+		store := newStore(t)
+		u := postUser(ctx, t, store)
+		start := now()
+
+		var IDs []string
+		for i := 0; i < 3; i++ {
+			session, err := store.PostSession(ctx, auth.Session{
+				UserID:      u.ID,
+				Device:      "phone",
+				LastUsed:    start,
+				DateCreated: start,
+			})
+			requireNoError(t, err)
+			IDs = append(IDs, session.ID)
+		}
+		// using the first session makes it the most recent one
+		requireNoError(t, store.TouchSession(ctx, IDs[0], auth.ClientInfo{IP: "10.0.0.1"}, start.Add(time.Minute)))
+
+		sessions, err := store.GetSessionsByUser(ctx, u.ID)
+		requireNoError(t, err)
+		if len(sessions) != 3 {
+			t.Fatalf("expected 3 sessions, got %d", len(sessions))
+		}
+		if sessions[0].ID != IDs[0] || sessions[0].IP != "10.0.0.1" {
+			t.Fatalf("expected the touched session first, got %+v", sessions[0])
+		}
+
+		_, err = store.GetSession(ctx, uuid.NewV4().String())
+		requireError(t, err, auth.ErrNotFound)
+	})
+}
+
+// requireSingleWinner - calls fn concurrently and checks that exactly one call reported true
+func requireSingleWinner(t *testing.T, fn func() (bool, error)) {
+	t.Helper()
+
+	var wg sync.WaitGroup
+	won := make([]bool, concurrency)
+	errs := make([]error, concurrency)
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			won[i], errs[i] = fn()
+		}(i)
+	}
+	wg.Wait()
+
+	winners := 0
+	for i := range won {
+		requireNoError(t, errs[i])
+		if won[i] {
+			winners++
+		}
+	}
+	if winners != 1 {
+		t.Fatalf("expected exactly one of %d concurrent calls to succeed, %d did", concurrency, winners)
+	}
+}
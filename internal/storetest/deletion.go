@@ -0,0 +1,190 @@
+package storetest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/yuchida-tamu/git-workout-api/internal/auth"
+	"github.com/yuchida-tamu/git-workout-api/internal/record"
+	"github.com/yuchida-tamu/git-workout-api/internal/user"
+)
+
+func runDeletionTests(t *testing.T, newStore Factory) {
+	t.Run("ScheduleAndCancel", func(t *testing.T) {
+		ctx := context.Background()
+		store := newStore(t)
+		u := postUser(ctx, t, store)
+		deleteAfter := now().Add(time.Hour)
+
+		requireNoError(t, store.SetUserDeleteAfter(ctx, u.ID, deleteAfter, 0))
+		got, err := store.GetUser(ctx, u.ID)
+		requireNoError(t, err)
+		if !got.DeleteAfter.Equal(deleteAfter) {
+			t.Fatalf("expected the deletion to be due at %v, got %v", deleteAfter, got.DeleteAfter)
+		}
+
+		due, err := store.GetUsersDueForDeletion(ctx, deleteAfter.Add(-time.Minute))
+		requireNoError(t, err)
+		if containsUser(due, u.ID) {
+			t.Fatal("the user is due for deletion before the grace period is over")
+		}
+		due, err = store.GetUsersDueForDeletion(ctx, deleteAfter)
+		requireNoError(t, err)
+		if !containsUser(due, u.ID) {
+			t.Fatal("the user is not due for deletion once the grace period is over")
+		}
+
+		requireNoError(t, store.SetUserDeleteAfter(ctx, u.ID, time.Time{}, 0))
+		got, err = store.GetUser(ctx, u.ID)
+		requireNoError(t, err)
+		if !got.DeleteAfter.IsZero() {
+			t.Fatalf("expected no deletion to be pending, got %v", got.DeleteAfter)
+		}
+		due, err = store.GetUsersDueForDeletion(ctx, deleteAfter)
+		requireNoError(t, err)
+		if containsUser(due, u.ID) {
+			t.Fatal("the user is still due for deletion after it was cancelled")
+		}
+	})
+
+	t.Run("Purge", func(t *testing.T) {
+		ctx := context.Background()
+		store := newStore(t)
+		u := postUser(ctx, t, store)
+		other := postUser(ctx, t, store)
+
+		rcd, err := store.PostRecord(ctx, record.Record{MessageBody: "deadlift 3x5", Author: u.ID})
+		requireNoError(t, err)
+		otherRcd, err := store.PostRecord(ctx, record.Record{MessageBody: "squat 5x5", Author: other.ID})
+		requireNoError(t, err)
+		_, err = store.PostComment(ctx, record.Comment{RecordID: otherRcd.ID, Author: u.ID, MessageBody: "nice", DateCreated: now()})
+		requireNoError(t, err)
+		otherComment, err := store.PostComment(ctx, record.Comment{RecordID: otherRcd.ID, Author: other.ID, MessageBody: "thanks", DateCreated: now()})
+		requireNoError(t, err)
+
+		session, err := store.PostSession(ctx, auth.Session{UserID: u.ID, LastUsed: now(), DateCreated: now()})
+		requireNoError(t, err)
+		token, err := store.PostRefreshToken(ctx, auth.RefreshToken{
+			UserID:      u.ID,
+			FamilyID:    session.ID,
+			TokenHash:   unique("refresh"),
+			ExpiresAt:   now().Add(time.Hour),
+			DateCreated: now(),
+		})
+		requireNoError(t, err)
+		_, err = store.PostAPIKey(ctx, auth.APIKey{
+			UserID:      u.ID,
+			Name:        "ci",
+			Prefix:      unique("key"),
+			KeyHash:     unique("hash"),
+			Scopes:      []auth.Scope{},
+			DateCreated: now(),
+		})
+		requireNoError(t, err)
+
+		// sessions other users granted the user's OAuth clients go with the clients
+		client, err := store.PostOAuthClient(ctx, auth.OAuthClient{
+			OwnerID:      u.ID,
+			Name:         "tracker",
+			RedirectURIs: []string{"https://example.com/callback"},
+			Scopes:       []auth.Scope{},
+			DateCreated:  now(),
+		})
+		requireNoError(t, err)
+		clientSession, err := store.PostSession(ctx, auth.Session{UserID: other.ID, ClientID: client.ID, LastUsed: now(), DateCreated: now()})
+		requireNoError(t, err)
+		otherSession, err := store.PostSession(ctx, auth.Session{UserID: other.ID, LastUsed: now(), DateCreated: now()})
+		requireNoError(t, err)
+
+		_, err = store.PostAuditEvent(ctx, user.AuditEvent{UserID: u.ID, Action: user.AuditActionDeletionScheduled, DateCreated: now()})
+		requireNoError(t, err)
+
+		requireNoError(t, store.SetUserDeleteAfter(ctx, u.ID, now(), 0))
+		requireNoError(t, store.WithTx(ctx, func(ctx context.Context) error {
+			return store.PurgeUser(ctx, u.ID, now())
+		}))
+
+		_, err = store.GetUser(ctx, u.ID)
+		requireError(t, err, user.ErrNotFound)
+		_, err = store.GetRecordById(ctx, rcd.ID)
+		requireError(t, err, record.ErrNotFound)
+		comments, err := store.GetCommentsByRecord(ctx, otherRcd.ID)
+		requireNoError(t, err)
+		if len(comments) != 1 || comments[0].ID != otherComment.ID {
+			t.Fatalf("expected only the other user's comment to be left, got %+v", comments)
+		}
+		_, err = store.GetSession(ctx, session.ID)
+		requireError(t, err, auth.ErrNotFound)
+		_, err = store.GetSession(ctx, clientSession.ID)
+		requireError(t, err, auth.ErrNotFound)
+		_, err = store.GetRefreshTokenByHash(ctx, token.TokenHash)
+		requireError(t, err, auth.ErrNotFound)
+		keys, err := store.GetAPIKeysByUser(ctx, u.ID)
+		requireNoError(t, err)
+		if len(keys) != 0 {
+			t.Fatalf("expected the API keys to be purged, got %d", len(keys))
+		}
+		_, err = store.GetOAuthClient(ctx, client.ID)
+		requireError(t, err, auth.ErrNotFound)
+
+		// nothing of the other user goes
+		_, err = store.GetRecordById(ctx, otherRcd.ID)
+		requireNoError(t, err)
+		_, err = store.GetSession(ctx, otherSession.ID)
+		requireNoError(t, err)
+
+		events, err := store.GetAuditEventsByUser(ctx, u.ID)
+		requireNoError(t, err)
+		if len(events) != 1 {
+			t.Fatalf("expected the audit event to outlive the user, got %d events", len(events))
+		}
+	})
+
+	t.Run("PurgeOnlyWhenDue", func(t *testing.T) {
+		ctx := context.Background()
+		store := newStore(t)
+		u := postUser(ctx, t, store)
+		rcd, err := store.PostRecord(ctx, record.Record{MessageBody: "deadlift 3x5", Author: u.ID})
+		requireNoError(t, err)
+
+		// a user who was never scheduled, one not due yet and one who cancelled are all kept, and the
+		// transaction rolls back what was purged before the user
+		for _, deleteAfter := range []time.Time{{}, now().Add(time.Hour), {}} {
+			requireNoError(t, store.SetUserDeleteAfter(ctx, u.ID, deleteAfter, 0))
+			err := store.WithTx(ctx, func(ctx context.Context) error {
+				return store.PurgeUser(ctx, u.ID, now())
+			})
+			requireError(t, err, user.ErrDeletionNotDue)
+
+			_, err = store.GetUser(ctx, u.ID)
+			requireNoError(t, err)
+			_, err = store.GetRecordById(ctx, rcd.ID)
+			requireNoError(t, err)
+		}
+	})
+
+	t.Run("AuditEventsMostRecentFirst", func(t *testing.T) {
+		ctx := context.Background()
+		store := newStore(t)
+		u := postUser(ctx, t, store)
+		start := now()
+
+		for _, event := range []user.AuditEvent{
+			{UserID: u.ID, Action: user.AuditActionDeletionScheduled, DateCreated: start},
+			{UserID: u.ID, Action: user.AuditActionDeletionCancelled, DateCreated: start.Add(time.Minute)},
+		} {
+			_, err := store.PostAuditEvent(ctx, event)
+			requireNoError(t, err)
+		}
+
+		events, err := store.GetAuditEventsByUser(ctx, u.ID)
+		requireNoError(t, err)
+		if len(events) != 2 {
+			t.Fatalf("expected 2 audit events, got %d", len(events))
+		}
+		if events[0].Action != user.AuditActionDeletionCancelled || !events[0].DateCreated.Equal(start.Add(time.Minute)) {
+			t.Fatalf("expected the cancellation first, got %+v", events[0])
+		}
+	})
+}
@@ -0,0 +1,237 @@
+package storetest
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/yuchida-tamu/git-workout-api/internal/record"
+)
+
+func runRecordTests(t *testing.T, newStore Factory) {
+	t.Run("PostAndGet", func(t *testing.T) {
+		ctx := context.Background()
+		store := newStore(t)
+		author := postUser(ctx, t, store)
+		other := postUser(ctx, t, store)
+
+		posted, err := store.PostRecord(ctx, record.Record{
+			DateCreated: "2022-05-01",
+			MessageBody: "bench press 5x5",
+			Author:      author.ID,
+		})
+		requireNoError(t, err)
+		if posted.ID == "" {
+			t.Fatal("PostRecord did not assign an ID")
+		}
+		_, err = store.PostRecord(ctx, record.Record{MessageBody: "deadlift 3x5", Author: other.ID})
+		requireNoError(t, err)
+
+		got, err := store.GetRecordById(ctx, posted.ID)
+		requireNoError(t, err)
+		if got != posted {
+			t.Fatalf("GetRecordById returned %+v, want %+v", got, posted)
+		}
+
+		records, err := store.GetRecordsByAuthor(ctx, author.ID)
+		requireNoError(t, err)
+		if len(records) != 1 || records[0] != posted {
+			t.Fatalf("GetRecordsByAuthor returned %+v, want only %+v", records, posted)
+		}
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		ctx := context.Background()
+		store := newStore(t)
+		author := postUser(ctx, t, store)
+
+		posted, err := store.PostRecord(ctx, record.Record{MessageBody: "squat 5x5", Author: author.ID})
+		requireNoError(t, err)
+
+		updated, err := store.UpdateRecord(ctx, posted.ID, record.Record{
+			DateCreated: "2022-05-02",
+			MessageBody: "squat 3x5",
+			Author:      author.ID,
+		})
+		requireNoError(t, err)
+		if updated.ID != posted.ID || updated.MessageBody != "squat 3x5" {
+			t.Fatalf("UpdateRecord returned %+v", updated)
+		}
+
+		got, err := store.GetRecordById(ctx, posted.ID)
+		requireNoError(t, err)
+		if got != updated {
+			t.Fatalf("GetRecordById returned %+v, want %+v", got, updated)
+		}
+	})
+
+	t.Run("UpdateChecksVersion", func(t *testing.T) {
+		ctx := context.Background()
+		store := newStore(t)
+		author := postUser(ctx, t, store)
+
+		posted, err := store.PostRecord(ctx, record.Record{MessageBody: "bench 5x5", Author: author.ID})
+		requireNoError(t, err)
+		if posted.Version != 1 {
+			t.Fatalf("expected a new record at version 1, got %d", posted.Version)
+		}
+
+		updated, err := store.UpdateRecord(ctx, posted.ID, record.Record{MessageBody: "bench 3x5", Author: author.ID, Version: 1})
+		requireNoError(t, err)
+		if updated.Version != 2 {
+			t.Fatalf("expected the update to move the record to version 2, got %d", updated.Version)
+		}
+
+		// a second writer which read version 1 as well must not overwrite the first one
+		_, err = store.UpdateRecord(ctx, posted.ID, record.Record{MessageBody: "bench 1x5", Author: author.ID, Version: 1})
+		requireError(t, err, record.ErrVersionMismatch)
+		got, err := store.GetRecordById(ctx, posted.ID)
+		requireNoError(t, err)
+		if got != updated {
+			t.Fatalf("GetRecordById returned %+v, want %+v", got, updated)
+		}
+
+		// without a version the update applies to whichever version is current
+		updated, err = store.UpdateRecord(ctx, posted.ID, record.Record{MessageBody: "bench 1x5", Author: author.ID})
+		requireNoError(t, err)
+		if updated.Version != 3 {
+			t.Fatalf("expected an unconditional update to move the record to version 3, got %d", updated.Version)
+		}
+
+		_, err = store.UpdateRecord(ctx, uuid.NewV4().String(), record.Record{MessageBody: "bench 1x5", Author: author.ID})
+		requireError(t, err, record.ErrNotFound)
+	})
+
+	t.Run("DeleteChecksVersion", func(t *testing.T) {
+		ctx := context.Background()
+		store := newStore(t)
+		author := postUser(ctx, t, store)
+
+		posted, err := store.PostRecord(ctx, record.Record{MessageBody: "squat 5x5", Author: author.ID})
+		requireNoError(t, err)
+		updated, err := store.UpdateRecord(ctx, posted.ID, record.Record{MessageBody: "squat 3x5", Author: author.ID})
+		requireNoError(t, err)
+
+		// a writer which read the record before the update must not delete it
+		requireError(t, store.DeleteRecord(ctx, posted.ID, posted.Version), record.ErrVersionMismatch)
+		_, err = store.GetRecordById(ctx, posted.ID)
+		requireNoError(t, err)
+
+		requireNoError(t, store.DeleteRecord(ctx, posted.ID, updated.Version))
+		requireError(t, store.DeleteRecord(ctx, posted.ID, 0), record.ErrNotFound)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		ctx := context.Background()
+		store := newStore(t)
+		author := postUser(ctx, t, store)
+
+		posted, err := store.PostRecord(ctx, record.Record{MessageBody: "row 4x8", Author: author.ID})
+		requireNoError(t, err)
+		requireNoError(t, store.DeleteRecord(ctx, posted.ID, 0))
+
+		_, err = store.GetRecordById(ctx, posted.ID)
+		requireError(t, err, record.ErrNotFound)
+
+		records, err := store.GetRecordsByAuthor(ctx, author.ID)
+		requireNoError(t, err)
+		if len(records) != 0 {
+			t.Fatalf("expected no records after deleting, got %+v", records)
+		}
+	})
+
+	t.Run("DeletedWithAuthor", func(t *testing.T) {
+		ctx := context.Background()
+		store := newStore(t)
+		author := postUser(ctx, t, store)
+
+		posted, err := store.PostRecord(ctx, record.Record{MessageBody: "lunges 3x12", Author: author.ID})
+		requireNoError(t, err)
+		requireNoError(t, store.DeleteUser(ctx, author.ID))
+
+		_, err = store.GetRecordById(ctx, posted.ID)
+		requireError(t, err, record.ErrNotFound)
+	})
+
+	t.Run("UnknownAuthor", func(t *testing.T) {
+		ctx := context.Background()
+		store := newStore(t)
+
+		_, err := store.PostRecord(ctx, record.Record{MessageBody: "plank 3x60s", Author: uuid.NewV4().String()})
+		requireError(t, err, record.ErrValidation)
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		ctx := context.Background()
+		store := newStore(t)
+
+		_, err := store.GetRecordById(ctx, uuid.NewV4().String())
+		requireError(t, err, record.ErrNotFound)
+
+		records, err := store.GetRecordsByAuthor(ctx, uuid.NewV4().String())
+		requireNoError(t, err)
+		if len(records) != 0 {
+			t.Fatalf("expected no records for an unknown author, got %+v", records)
+		}
+	})
+
+	t.Run("CommentsInOrder", func(t *testing.T) {
+		ctx := context.Background()
+		store := newStore(t)
+		author := postUser(ctx, t, store)
+
+		posted, err := store.PostRecord(ctx, record.Record{MessageBody: "press 5x5", Author: author.ID})
+		requireNoError(t, err)
+
+		// posted out of order, read back oldest first
+		start := now()
+		for _, offset := range []int{2, 0, 1} {
+			_, err := store.PostComment(ctx, record.Comment{
+				RecordID:    posted.ID,
+				Author:      author.ID,
+				MessageBody: "comment",
+				DateCreated: start.Add(time.Duration(offset) * time.Minute),
+			})
+			requireNoError(t, err)
+		}
+
+		comments, err := store.GetCommentsByRecord(ctx, posted.ID)
+		requireNoError(t, err)
+		if len(comments) != 3 {
+			t.Fatalf("expected 3 comments, got %d", len(comments))
+		}
+		for i, comment := range comments {
+			if want := start.Add(time.Duration(i) * time.Minute); !comment.DateCreated.Equal(want) {
+				t.Fatalf("comment %d was created at %v, want %v", i, comment.DateCreated, want)
+			}
+		}
+	})
+
+	t.Run("ConcurrentPosts", func(t *testing.T) {
+		ctx := context.Background()
+		store := newStore(t)
+		author := postUser(ctx, t, store)
+
+		var wg sync.WaitGroup
+		errs := make([]error, concurrency)
+		for i := 0; i < concurrency; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				_, errs[i] = store.PostRecord(ctx, record.Record{MessageBody: "lunge 3x10", Author: author.ID})
+			}(i)
+		}
+		wg.Wait()
+
+		for _, err := range errs {
+			requireNoError(t, err)
+		}
+		records, err := store.GetRecordsByAuthor(ctx, author.ID)
+		requireNoError(t, err)
+		if len(records) != concurrency {
+			t.Fatalf("expected %d records, got %d", concurrency, len(records))
+		}
+	})
+}
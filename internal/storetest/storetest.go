@@ -0,0 +1,87 @@
+// Package storetest is a conformance suite for the store interfaces, so that every backend - Postgres,
+// SQLite and the in-memory store - behaves the same way towards the services. A backend runs it from its
+// own tests:
+//
+//	func TestStore(t *testing.T) {
+//		storetest.Run(t, func(t *testing.T) storetest.Store { return NewStore() })
+//	}
+//
+// The suite never assumes an empty store, every test creates the data it looks at, so a factory may hand out
+// the same database for every test.
+package storetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/yuchida-tamu/git-workout-api/internal/auth"
+	"github.com/yuchida-tamu/git-workout-api/internal/record"
+	"github.com/yuchida-tamu/git-workout-api/internal/user"
+)
+
+// Store - the interfaces the suite covers
+type Store interface {
+	user.Store
+	record.Store
+	auth.Store
+}
+
+// Factory - returns the store a single test runs against
+type Factory func(t *testing.T) Store
+
+// concurrency - how many goroutines the concurrency tests start at once
+const concurrency = 16
+
+// Run - runs every conformance test against stores returned by newStore
+func Run(t *testing.T, newStore Factory) {
+	t.Run("Users", func(t *testing.T) { runUserTests(t, newStore) })
+	t.Run("Records", func(t *testing.T) { runRecordTests(t, newStore) })
+	t.Run("Auth", func(t *testing.T) { runAuthTests(t, newStore) })
+	t.Run("Tx", func(t *testing.T) { runTxTests(t, newStore) })
+	t.Run("Deletion", func(t *testing.T) { runDeletionTests(t, newStore) })
+}
+
+// requireError - the stores report failures as the domain errors, which the transport layer maps to status codes
+func requireError(t *testing.T, err error, target error) {
+	t.Helper()
+	if err == nil {
+		t.Fatalf("expected %q, got no error", target)
+	}
+	if !errors.Is(err, target) {
+		t.Fatalf("expected %q, got: %v", target, err)
+	}
+}
+
+func requireNoError(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// unique - a value no other test, or earlier run against the same database, uses
+func unique(prefix string) string {
+	return fmt.Sprintf("%s-%s", prefix, uuid.NewV4().String()[:8])
+}
+
+// now - the current time at the precision every backend keeps
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
+func postUser(ctx context.Context, t *testing.T, store Store) user.User {
+	t.Helper()
+	username := unique("user")
+	u, err := store.PostUser(ctx, user.User{
+		Username: username,
+		Password: "hash",
+		Email:    username + "@example.com",
+	})
+	requireNoError(t, err)
+
+	return u
+}
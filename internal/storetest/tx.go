@@ -0,0 +1,113 @@
+package storetest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/yuchida-tamu/git-workout-api/internal/record"
+	"github.com/yuchida-tamu/git-workout-api/internal/user"
+)
+
+var errAbort = errors.New("abort the transaction")
+
+func runTxTests(t *testing.T, newStore Factory) {
+	t.Run("Commit", func(t *testing.T) {
+		ctx := context.Background()
+		store := newStore(t)
+
+		var posted user.User
+		var rcd record.Record
+		err := store.WithTx(ctx, func(ctx context.Context) error {
+			posted = postUser(ctx, t, store)
+
+			// the transaction reads its own writes
+			if _, err := store.GetUser(ctx, posted.ID); err != nil {
+				return err
+			}
+
+			var err error
+			rcd, err = store.PostRecord(ctx, record.Record{MessageBody: "farmer's walk 3x40m", Author: posted.ID})
+			return err
+		})
+		requireNoError(t, err)
+
+		_, err = store.GetUser(ctx, posted.ID)
+		requireNoError(t, err)
+		_, err = store.GetRecordById(ctx, rcd.ID)
+		requireNoError(t, err)
+	})
+
+	t.Run("RollbackOnError", func(t *testing.T) {
+		ctx := context.Background()
+		store := newStore(t)
+		kept := postUser(ctx, t, store)
+
+		var posted user.User
+		var rcd record.Record
+		err := store.WithTx(ctx, func(ctx context.Context) error {
+			posted = postUser(ctx, t, store)
+
+			var err error
+			rcd, err = store.PostRecord(ctx, record.Record{MessageBody: "sled push 4x20m", Author: posted.ID})
+			requireNoError(t, err)
+			requireNoError(t, store.DeleteUser(ctx, kept.ID))
+
+			return errAbort
+		})
+		requireError(t, err, errAbort)
+
+		_, err = store.GetUser(ctx, posted.ID)
+		requireError(t, err, user.ErrNotFound)
+		_, err = store.GetRecordById(ctx, rcd.ID)
+		requireError(t, err, record.ErrNotFound)
+		_, err = store.GetUser(ctx, kept.ID)
+		requireNoError(t, err)
+	})
+
+	t.Run("RollbackOnPanic", func(t *testing.T) {
+		ctx := context.Background()
+		store := newStore(t)
+
+		var posted user.User
+		func() {
+			defer func() {
+				if p := recover(); p != errAbort {
+					t.Fatalf("expected WithTx to panic with %q again, got %v", errAbort, p)
+				}
+			}()
+
+			_ = store.WithTx(ctx, func(ctx context.Context) error {
+				posted = postUser(ctx, t, store)
+				panic(errAbort)
+			})
+		}()
+
+		_, err := store.GetUser(ctx, posted.ID)
+		requireError(t, err, user.ErrNotFound)
+	})
+
+	t.Run("Nested", func(t *testing.T) {
+		ctx := context.Background()
+		store := newStore(t)
+
+		var outer, inner user.User
+		err := store.WithTx(ctx, func(ctx context.Context) error {
+			outer = postUser(ctx, t, store)
+
+			// a nested WithTx joins the outer transaction, so its writes go with the outer rollback
+			requireNoError(t, store.WithTx(ctx, func(ctx context.Context) error {
+				inner = postUser(ctx, t, store)
+				return nil
+			}))
+
+			return errAbort
+		})
+		requireError(t, err, errAbort)
+
+		_, err = store.GetUser(ctx, outer.ID)
+		requireError(t, err, user.ErrNotFound)
+		_, err = store.GetUser(ctx, inner.ID)
+		requireError(t, err, user.ErrNotFound)
+	})
+}
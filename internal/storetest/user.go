@@ -0,0 +1,260 @@
+package storetest
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/yuchida-tamu/git-workout-api/internal/user"
+)
+
+func runUserTests(t *testing.T, newStore Factory) {
+	t.Run("PostAndGet", func(t *testing.T) {
+		ctx := context.Background()
+		store := newStore(t)
+
+		posted := postUser(ctx, t, store)
+		if posted.ID == "" {
+			t.Fatal("PostUser did not assign an ID")
+		}
+		if posted.Role != user.RoleUser {
+			t.Fatalf("expected the default role %q, got %q", user.RoleUser, posted.Role)
+		}
+
+		got, err := store.GetUser(ctx, posted.ID)
+		requireNoError(t, err)
+		if got != posted {
+			t.Fatalf("GetUser returned %+v, want %+v", got, posted)
+		}
+
+		got, err = store.GetUserByUsername(ctx, posted.Username)
+		requireNoError(t, err)
+		if got.ID != posted.ID {
+			t.Fatalf("GetUserByUsername returned user %s, want %s", got.ID, posted.ID)
+		}
+
+		got, err = store.GetUserByEmail(ctx, strings.ToUpper(posted.Email))
+		requireNoError(t, err)
+		if got.ID != posted.ID {
+			t.Fatalf("GetUserByEmail ignoring case returned user %s, want %s", got.ID, posted.ID)
+		}
+
+		users, err := store.GetUsers(ctx)
+		requireNoError(t, err)
+		if !containsUser(users, posted.ID) {
+			t.Fatal("GetUsers does not include the posted user")
+		}
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		ctx := context.Background()
+		store := newStore(t)
+		posted := postUser(ctx, t, store)
+
+		username := unique("renamed")
+		updated, err := store.UpdateUser(ctx, posted.ID, user.User{Username: username, Password: "new-hash"})
+		requireNoError(t, err)
+		if updated.ID != posted.ID || updated.Username != username {
+			t.Fatalf("UpdateUser returned %+v", updated)
+		}
+
+		requireNoError(t, store.UpdateUserRole(ctx, posted.ID, user.RoleCoach))
+		requireNoError(t, store.SetUserDisabled(ctx, posted.ID, true))
+		requireNoError(t, store.UpdateUserPassword(ctx, posted.ID, "newer-hash"))
+
+		got, err := store.GetUser(ctx, posted.ID)
+		requireNoError(t, err)
+		if got.Username != username || got.Password != "newer-hash" || got.Role != user.RoleCoach || !got.Disabled {
+			t.Fatalf("updates were not stored: %+v", got)
+		}
+		// the email is left alone by the other updates
+		if got.Email != posted.Email {
+			t.Fatalf("expected email %q, got %q", posted.Email, got.Email)
+		}
+	})
+
+	t.Run("UpdateChecksVersion", func(t *testing.T) {
+		ctx := context.Background()
+		store := newStore(t)
+		posted := postUser(ctx, t, store)
+		if posted.Version != 1 {
+			t.Fatalf("expected a new user at version 1, got %d", posted.Version)
+		}
+
+		updated, err := store.UpdateUser(ctx, posted.ID, user.User{Username: unique("renamed"), Password: "hash", Version: 1})
+		requireNoError(t, err)
+		if updated.Version != 2 || updated.Email != posted.Email {
+			t.Fatalf("UpdateUser returned %+v", updated)
+		}
+
+		_, err = store.UpdateUser(ctx, posted.ID, user.User{Username: unique("renamed"), Password: "hash", Version: 1})
+		requireError(t, err, user.ErrVersionMismatch)
+		_, err = store.UpdateUser(ctx, uuid.NewV4().String(), user.User{Username: unique("renamed"), Password: "hash"})
+		requireError(t, err, user.ErrNotFound)
+
+		// every other write moves the version on as well
+		requireNoError(t, store.SetUserDisabled(ctx, posted.ID, true))
+		requireNoError(t, store.UpdateUserEmail(ctx, posted.ID, unique("changed")+"@example.com"))
+		requireNoError(t, store.SetUserDeleteAfter(ctx, posted.ID, now().Add(time.Hour), 0))
+		got, err := store.GetUser(ctx, posted.ID)
+		requireNoError(t, err)
+		if got.Version != 5 {
+			t.Fatalf("expected the user at version 5, got %d", got.Version)
+		}
+	})
+
+	t.Run("ScheduleDeletionChecksVersion", func(t *testing.T) {
+		ctx := context.Background()
+		store := newStore(t)
+		posted := postUser(ctx, t, store)
+		requireNoError(t, store.SetUserDisabled(ctx, posted.ID, true))
+
+		err := store.SetUserDeleteAfter(ctx, posted.ID, now().Add(time.Hour), posted.Version)
+		requireError(t, err, user.ErrVersionMismatch)
+		got, err := store.GetUser(ctx, posted.ID)
+		requireNoError(t, err)
+		if !got.DeleteAfter.IsZero() {
+			t.Fatalf("expected no deletion to be scheduled at a stale version, got %v", got.DeleteAfter)
+		}
+
+		requireNoError(t, store.SetUserDeleteAfter(ctx, posted.ID, now().Add(time.Hour), got.Version))
+		err = store.SetUserDeleteAfter(ctx, uuid.NewV4().String(), now().Add(time.Hour), 0)
+		requireError(t, err, user.ErrNotFound)
+	})
+
+	t.Run("UpdateEmailResetsVerification", func(t *testing.T) {
+		ctx := context.Background()
+		store := newStore(t)
+		posted := postUser(ctx, t, store)
+
+		email := unique("changed") + "@example.com"
+		requireNoError(t, store.UpdateUserEmail(ctx, posted.ID, email))
+
+		got, err := store.GetUser(ctx, posted.ID)
+		requireNoError(t, err)
+		if got.Email != email || got.EmailVerified {
+			t.Fatalf("expected unverified email %q, got %q verified=%v", email, got.Email, got.EmailVerified)
+		}
+
+		// an empty address removes it, and any number of users may have none
+		requireNoError(t, store.UpdateUserEmail(ctx, posted.ID, ""))
+		other := postUser(ctx, t, store)
+		requireNoError(t, store.UpdateUserEmail(ctx, other.ID, ""))
+	})
+
+	t.Run("DuplicateEmail", func(t *testing.T) {
+		ctx := context.Background()
+		store := newStore(t)
+		posted := postUser(ctx, t, store)
+
+		_, err := store.PostUser(ctx, user.User{
+			Username: unique("user"),
+			Password: "hash",
+			Email:    strings.ToUpper(posted.Email),
+		})
+		requireError(t, err, user.ErrConflict)
+	})
+
+	t.Run("DuplicateUsername", func(t *testing.T) {
+		ctx := context.Background()
+		store := newStore(t)
+		posted := postUser(ctx, t, store)
+		other := postUser(ctx, t, store)
+
+		_, err := store.PostUser(ctx, user.User{
+			Username: strings.ToUpper(posted.Username),
+			Password: "hash",
+		})
+		requireError(t, err, user.ErrConflict)
+
+		_, err = store.UpdateUser(ctx, other.ID, user.User{Username: posted.Username, Password: "hash"})
+		requireError(t, err, user.ErrConflict)
+	})
+
+	t.Run("UsernameIgnoresCase", func(t *testing.T) {
+		ctx := context.Background()
+		store := newStore(t)
+		posted := postUser(ctx, t, store)
+
+		got, err := store.GetUserByUsername(ctx, strings.ToUpper(posted.Username))
+		requireNoError(t, err)
+		if got.ID != posted.ID {
+			t.Fatalf("GetUserByUsername returned %+v, want %+v", got, posted)
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		ctx := context.Background()
+		store := newStore(t)
+		posted := postUser(ctx, t, store)
+
+		requireNoError(t, store.DeleteUser(ctx, posted.ID))
+
+		_, err := store.GetUser(ctx, posted.ID)
+		requireError(t, err, user.ErrNotFound)
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		ctx := context.Background()
+		store := newStore(t)
+
+		_, err := store.GetUser(ctx, uuid.NewV4().String())
+		requireError(t, err, user.ErrNotFound)
+		_, err = store.GetUserByUsername(ctx, unique("missing"))
+		requireError(t, err, user.ErrNotFound)
+		_, err = store.GetUserByEmail(ctx, unique("missing")+"@example.com")
+		requireError(t, err, user.ErrNotFound)
+	})
+
+	t.Run("ConcurrentPosts", func(t *testing.T) {
+		ctx := context.Background()
+		store := newStore(t)
+
+		var wg sync.WaitGroup
+		posted := make([]user.User, concurrency)
+		errs := make([]error, concurrency)
+		for i := 0; i < concurrency; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				username := unique("concurrent")
+				posted[i], errs[i] = store.PostUser(ctx, user.User{
+					Username: username,
+					Password: "hash",
+					Email:    username + "@example.com",
+				})
+			}(i)
+		}
+		wg.Wait()
+
+		ids := map[string]bool{}
+		for i := range posted {
+			requireNoError(t, errs[i])
+			ids[posted[i].ID] = true
+		}
+		if len(ids) != concurrency {
+			t.Fatalf("expected %d distinct IDs, got %d", concurrency, len(ids))
+		}
+
+		users, err := store.GetUsers(ctx)
+		requireNoError(t, err)
+		for ID := range ids {
+			if !containsUser(users, ID) {
+				t.Fatalf("user %s posted concurrently is missing", ID)
+			}
+		}
+	})
+}
+
+func containsUser(users []user.User, ID string) bool {
+	for _, u := range users {
+		if u.ID == ID {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,61 @@
+package totp
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+)
+
+// rfc6238Secret - the SHA1 key of RFC 6238 Appendix B, the ASCII string "12345678901234567890"
+var rfc6238Secret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+// rfc6238Vectors - the SHA1 test vectors of RFC 6238 Appendix B; the RFC lists 8 digit codes, a 6 digit code
+// is their last 6 digits
+var rfc6238Vectors = []struct {
+	unix int64
+	code string
+}{
+	{59, "287082"},
+	{1111111109, "081804"},
+	{1111111111, "050471"},
+	{1234567890, "005924"},
+	{2000000000, "279037"},
+	{20000000000, "353130"},
+}
+
+func TestCodeAt(t *testing.T) {
+	for _, v := range rfc6238Vectors {
+		code, err := CodeAt(rfc6238Secret, Step(time.Unix(v.unix, 0)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code != v.code {
+			t.Errorf("code at %d: got %s, want %s", v.unix, code, v.code)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, v := range rfc6238Vectors {
+		at := time.Unix(v.unix, 0)
+		step, ok := Validate(rfc6238Secret, v.code, at)
+		if !ok || step != Step(at) {
+			t.Errorf("code at %d: expected step %d to be accepted, got %d %v", v.unix, Step(at), step, ok)
+		}
+
+		// one step of clock drift either way is allowed, more is not
+		if _, ok := Validate(rfc6238Secret, v.code, at.Add(Period)); !ok {
+			t.Errorf("code at %d: expected the code to be accepted a step later", v.unix)
+		}
+		if _, ok := Validate(rfc6238Secret, v.code, at.Add(3*Period)); ok {
+			t.Errorf("code at %d: expected the code to be refused three steps later", v.unix)
+		}
+	}
+
+	if _, ok := Validate(rfc6238Secret, "94287082", time.Unix(59, 0)); ok {
+		t.Error("expected an 8 digit code to be refused")
+	}
+	if _, ok := Validate("not base32!", "287082", time.Unix(59, 0)); ok {
+		t.Error("expected an invalid secret to be refused")
+	}
+}
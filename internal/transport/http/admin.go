@@ -0,0 +1,119 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/yuchida-tamu/git-workout-api/internal/user"
+)
+
+type UserForAdmin struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+	Role     string `json:"role"`
+	Disabled bool   `json:"disabled"`
+}
+
+type UpdateUserRoleRequest struct {
+	Role string `json:"role" validate:"required,oneof=user coach admin"`
+}
+
+func convertUserToUserForAdmin(u user.User) UserForAdmin {
+	return UserForAdmin{
+		ID:       u.ID,
+		Username: u.Username,
+		Role:     string(u.Role),
+		Disabled: u.Disabled,
+	}
+}
+
+// GetUsers - a handler for admins to list every user account
+func (h *Handler) GetUsers(w http.ResponseWriter, r *http.Request) {
+	users, err := h.Service.User.GetUsers(r.Context())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	usersForAdmin := make([]UserForAdmin, 0, len(users))
+	for _, u := range users {
+		usersForAdmin = append(usersForAdmin, convertUserToUserForAdmin(u))
+	}
+
+	if err := json.NewEncoder(w).Encode(usersForAdmin); err != nil {
+		panic(err)
+	}
+}
+
+// UpdateUserRole - a handler for admins to change the role of a user
+func (h *Handler) UpdateUserRole(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	if id == "" {
+		writeStatus(w, r, http.StatusBadRequest, "the id is missing")
+		return
+	}
+
+	var roleReq UpdateUserRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&roleReq); err != nil {
+		writeInvalidBody(w, r)
+		return
+	}
+
+	if err := validate.Struct(roleReq); err != nil {
+		writeValidationError(w, r, err)
+		return
+	}
+
+	if err := h.Service.User.UpdateUserRole(r.Context(), id, user.Role(roleReq.Role)); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	h.writeUserForAdmin(w, r, id)
+}
+
+// DisableUser - a handler for admins to disable an account so it can no longer sign in
+func (h *Handler) DisableUser(w http.ResponseWriter, r *http.Request) {
+	h.setUserDisabled(w, r, true)
+}
+
+// EnableUser - a handler for admins to re-enable a disabled account
+func (h *Handler) EnableUser(w http.ResponseWriter, r *http.Request) {
+	h.setUserDisabled(w, r, false)
+}
+
+func (h *Handler) setUserDisabled(w http.ResponseWriter, r *http.Request, disabled bool) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	if id == "" {
+		writeStatus(w, r, http.StatusBadRequest, "the id is missing")
+		return
+	}
+
+	// an admin locking themselves out is never intended
+	if disabled && checkUserHasAccess(r.Context(), id) {
+		writeStatus(w, r, http.StatusBadRequest, "cannot disable your own account")
+		return
+	}
+
+	if err := h.Service.User.SetUserDisabled(r.Context(), id, disabled); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	h.writeUserForAdmin(w, r, id)
+}
+
+func (h *Handler) writeUserForAdmin(w http.ResponseWriter, r *http.Request, id string) {
+	u, err := h.Service.User.GetUser(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(convertUserToUserForAdmin(u)); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,129 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/yuchida-tamu/git-workout-api/internal/auth"
+)
+
+type PostAPIKeyRequest struct {
+	Name   string   `json:"name" validate:"required,max=100"`
+	Scopes []string `json:"scopes" validate:"required,min=1"`
+	// ExpiresAt is optional; a key without it stays valid until revoked
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type APIKeyResponse struct {
+	ID          string     `json:"id"`
+	Name        string     `json:"name"`
+	Prefix      string     `json:"prefix"`
+	Scopes      []string   `json:"scopes"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	LastUsed    *time.Time `json:"last_used"`
+	DateCreated time.Time  `json:"date_created"`
+}
+
+type PostAPIKeyResponse struct {
+	APIKeyResponse
+	// Key is only ever returned here, when the key is created
+	Key string `json:"key"`
+}
+
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+func convertAPIKeyToResponse(key auth.APIKey) APIKeyResponse {
+	scopes := make([]string, 0, len(key.Scopes))
+	for _, s := range key.Scopes {
+		scopes = append(scopes, string(s))
+	}
+
+	return APIKeyResponse{
+		ID:          key.ID,
+		Name:        key.Name,
+		Prefix:      key.Prefix,
+		Scopes:      scopes,
+		ExpiresAt:   optionalTime(key.ExpiresAt),
+		LastUsed:    optionalTime(key.LastUsed),
+		DateCreated: key.DateCreated,
+	}
+}
+
+// PostAPIKey - a handler creating an API key for the current user
+func (h *Handler) PostAPIKey(w http.ResponseWriter, r *http.Request) {
+	var request PostAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeInvalidBody(w, r)
+		return
+	}
+
+	if err := validate.Struct(request); err != nil {
+		writeValidationError(w, r, err)
+		return
+	}
+
+	scopes := make([]auth.Scope, 0, len(request.Scopes))
+	for _, s := range request.Scopes {
+		scopes = append(scopes, auth.Scope(s))
+	}
+	var expiresAt time.Time
+	if request.ExpiresAt != nil {
+		expiresAt = request.ExpiresAt.UTC()
+	}
+
+	raw, key, err := h.Service.Auth.CreateAPIKey(r.Context(), currentUserID(r.Context()), request.Name, scopes, expiresAt)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(PostAPIKeyResponse{
+		APIKeyResponse: convertAPIKeyToResponse(key),
+		Key:            raw,
+	}); err != nil {
+		panic(err)
+	}
+}
+
+// GetAPIKeys - a handler listing the current user's API keys, without the keys themselves
+func (h *Handler) GetAPIKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.Service.Auth.GetAPIKeysByUser(r.Context(), currentUserID(r.Context()))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	response := make([]APIKeyResponse, 0, len(keys))
+	for _, key := range keys {
+		response = append(response, convertAPIKeyToResponse(key))
+	}
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		panic(err)
+	}
+}
+
+// DeleteAPIKey - a handler revoking one of the current user's API keys
+func (h *Handler) DeleteAPIKey(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	if id == "" {
+		writeStatus(w, r, http.StatusBadRequest, "the id is missing")
+		return
+	}
+
+	if err := h.Service.Auth.RevokeAPIKey(r.Context(), currentUserID(r.Context()), id); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
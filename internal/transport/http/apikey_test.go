@@ -0,0 +1,52 @@
+package http_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/yuchida-tamu/git-workout-api/internal/auth"
+	"github.com/yuchida-tamu/git-workout-api/internal/user"
+)
+
+func TestAPIKeyScopes(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	// an admin, so that a refusal of the admin routes is down to the key and not the role
+	if err := s.store.UpdateUserRole(ctx, s.user.ID, user.RoleAdmin); err != nil {
+		t.Fatal(err)
+	}
+	raw, _, err := s.auth.CreateAPIKey(ctx, s.user.ID, "reader", []auth.Scope{auth.ScopeRecordsRead}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.token = ""
+	key := map[string]string{"Authorization": "ApiKey " + raw}
+
+	requireStatus(t, s.do(t, http.MethodGet, "/api/v1/record/author/"+s.user.ID, "", key), http.StatusOK)
+
+	for _, c := range []struct {
+		name   string
+		method string
+		path   string
+		body   string
+	}{
+		{"Write", http.MethodPost, "/api/v1/record", `{"DateCreated": "2026-10-19", "MessageBody": "squat 5x5"}`},
+		{"Import", http.MethodPost, "/api/v1/record/author/" + s.user.ID + "/import", `{"records": [{"message_body": "squat 5x5"}]}`},
+		{"Admin", http.MethodGet, "/api/v1/admin/user", ""},
+		{"AdminRole", http.MethodPut, "/api/v1/admin/user/" + s.user.ID + "/role", `{"role": "admin"}`},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			requireStatus(t, s.do(t, c.method, c.path, c.body, key), http.StatusForbidden)
+		})
+	}
+
+	records, err := s.store.GetRecordsByAuthor(ctx, s.user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 0 {
+		t.Fatalf("expected a read-only key to write nothing, got %+v", records)
+	}
+}
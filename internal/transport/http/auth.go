@@ -1,42 +1,76 @@
 package http
 
 import (
-	"encoding/json"
-	"errors"
+	"fmt"
+	"log"
+	"math"
 	"net/http"
-	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/yuchida-tamu/git-workout-api/internal/auth"
+	"github.com/yuchida-tamu/git-workout-api/internal/user"
 )
 
-type ErrorResponse struct {
-	Message   string `json:"message"`
-	ErrorCode int    `json:"error_code"`
+// writeTooManyAttempts - answers a locked out login with 429 and when it may be retried
+func writeTooManyAttempts(w http.ResponseWriter, r *http.Request, retryAfter time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	p := newProblem(http.StatusTooManyRequests, "too many failed attempts, try again later")
+	p.Type = problemTypeTooManyAttempts
+	p.ErrorCode = errorCodeTooManyAttempts
+	writeProblem(w, r, p)
 }
 
-func JWTAuthMiddleware(next http.Handler) http.Handler {
+// writeInvalidCredentials - answers a login which did not prove who the user is
+func writeInvalidCredentials(w http.ResponseWriter, r *http.Request, detail string) {
+	p := newProblem(http.StatusUnauthorized, detail)
+	p.Type = problemTypeInvalidCredentials
+	p.ErrorCode = errorCodeInvalidCredentials
+	writeProblem(w, r, p)
+}
+
+// writeTokenExpired - clients refresh the access token when they get this
+func writeTokenExpired(w http.ResponseWriter, r *http.Request) {
+	p := newProblem(http.StatusUnauthorized, "token expired")
+	p.Type = problemTypeTokenExpired
+	p.ErrorCode = errorCodeTokenExpired
+	writeProblem(w, r, p)
+}
+
+func (h *Handler) JWTAuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// API keys were already checked by AddCurrentUserToContextMiddleware
+		if currentAPIKeyID(r.Context()) != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
 		authHeader := r.Header["Authorization"]
 		if authHeader == nil {
-			http.Error(w, "not authorized", http.StatusUnauthorized)
+			writeStatus(w, r, http.StatusUnauthorized, "not authorized")
 			return
 		}
 
 		// Bearer: token-string, (in the Header, Request should have "Authorization", which is formatted as "bearer [encoded jwt key]")
 		authHeaderParts := strings.Split(authHeader[0], " ")
 		if len(authHeaderParts) != 2 || strings.ToLower(authHeaderParts[0]) != "bearer" {
-			http.Error(w, "not authorized", http.StatusUnauthorized)
+			writeStatus(w, r, http.StatusUnauthorized, "not authorized")
 			return
 		}
 
-		valid, expired := validateToken(authHeaderParts[1])
+		valid, expired := h.validateToken(authHeaderParts[1])
 		if !valid {
-			http.Error(w, "not authorized", http.StatusUnauthorized)
+			writeStatus(w, r, http.StatusUnauthorized, "not authorized")
 			return
 		}
 		if expired {
-			http.Error(w, "token expired", http.StatusUnauthorized)
+			writeTokenExpired(w, r)
+			return
+		}
+		if !h.checkSessionIsActive(r) {
+			writeStatus(w, r, http.StatusUnauthorized, "session revoked")
 			return
 		}
 
@@ -44,37 +78,40 @@ func JWTAuthMiddleware(next http.Handler) http.Handler {
 	})
 }
 
-func JWTAuth(
+func (h *Handler) JWTAuth(
 	original func(w http.ResponseWriter, r *http.Request),
 ) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
+		// API keys were already checked by AddCurrentUserToContextMiddleware
+		if currentAPIKeyID(r.Context()) != "" {
+			original(w, r)
+			return
+		}
+
 		authHeader := r.Header["Authorization"]
 		if authHeader == nil {
-			http.Error(w, "not authorized", http.StatusUnauthorized)
+			writeStatus(w, r, http.StatusUnauthorized, "not authorized")
 			return
 		}
 
 		// Bearer: token-string, (in the Header, Request should have "Authorization", which is formatted as "bearer [encoded jwt key]")
 		authHeaderParts := strings.Split(authHeader[0], " ")
 		if len(authHeaderParts) != 2 || strings.ToLower(authHeaderParts[0]) != "bearer" {
-			http.Error(w, "not authorized", http.StatusUnauthorized)
+			writeStatus(w, r, http.StatusUnauthorized, "not authorized")
 			return
 		}
 
-		valid, expired := validateToken(authHeaderParts[1])
+		valid, expired := h.validateToken(authHeaderParts[1])
 		if expired {
-			w.WriteHeader(http.StatusUnauthorized)
-			err := json.NewEncoder(w).Encode(ErrorResponse{
-				Message:   "token expired",
-				ErrorCode: -2000,
-			})
-			if err != nil {
-				http.Error(w, "something went wrong", http.StatusUnauthorized)
-				return
-			}
+			writeTokenExpired(w, r)
+			return
 		}
 		if !valid {
-			http.Error(w, "not authorized invalid token", http.StatusUnauthorized)
+			writeStatus(w, r, http.StatusUnauthorized, "not authorized invalid token")
+			return
+		}
+		if !h.checkSessionIsActive(r) {
+			writeStatus(w, r, http.StatusUnauthorized, "session revoked")
 			return
 		}
 
@@ -83,20 +120,110 @@ func JWTAuth(
 	}
 }
 
-func validateToken(accessToken string) (valid bool, expired bool) {
-	var mySigningKey = []byte(os.Getenv("SIGNING_SECRET"))
-	t, err := jwt.Parse(accessToken, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("could not validate auth token")
+// checkSessionIsActive - reports whether the session named by the token's sid claim is still active,
+// so that revoking a session also invalidates access tokens issued for it
+func (h *Handler) checkSessionIsActive(r *http.Request) bool {
+	sessionID := currentSessionID(r.Context())
+	if sessionID == "" {
+		return false
+	}
+
+	active, err := h.Service.Auth.IsSessionActive(r.Context(), sessionID)
+	if err != nil {
+		log.Print(err)
+		return false
+	}
+
+	return active
+}
+
+// RequirePermission - a policy wrapper which lets a route declare the permission its caller's role must grant.
+// It relies on the role added to the context by AddCurrentUserToContextMiddleware, so it is meant to sit inside JWTAuth.
+func RequirePermission(
+	permission user.Permission,
+	original func(w http.ResponseWriter, r *http.Request),
+) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !currentUserRole(r.Context()).Can(permission) {
+			writeStatus(w, r, http.StatusForbidden, "forbidden")
+			return
 		}
 
-		return mySigningKey, nil
-	})
+		original(w, r)
+	}
+}
+
+// RequireScope - a policy wrapper which lets a route declare the scopes its caller's credential must carry.
+// Tokens of this API's own logins carry every scope; API keys and third-party apps carry what they were granted.
+// It relies on the scopes added to the context by AddCurrentUserToContextMiddleware, so it is meant to sit inside JWTAuth.
+func RequireScope(
+	scope auth.Scope,
+	original func(w http.ResponseWriter, r *http.Request),
+) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !auth.ContainsScopes(currentScopes(r.Context()), []auth.Scope{scope}) {
+			writeInsufficientScope(w, r, scope)
+			return
+		}
+
+		original(w, r)
+	}
+}
+
+// writeInsufficientScope - answers with 403 and the insufficient_scope challenge of RFC 6750, section 3.1
+func writeInsufficientScope(w http.ResponseWriter, r *http.Request, scope auth.Scope) {
+	scheme := "Bearer"
+	if currentAPIKeyID(r.Context()) != "" {
+		scheme = "ApiKey"
+	}
+	w.Header().Set("WWW-Authenticate", fmt.Sprintf(
+		`%s error="insufficient_scope", error_description="the credential lacks a required scope", scope="%s"`,
+		scheme,
+		scope,
+	))
+	p := newProblem(http.StatusForbidden, fmt.Sprintf("the credential lacks the %s scope", scope))
+	p.Type = problemTypeInsufficientScope
+	writeProblem(w, r, p)
+}
+
+// RequireFirstParty - a policy wrapper for routes which manage the account and its credentials. They need a
+// logged in session of this API's own clients, so that neither a leaked API key nor a third-party app can
+// mint further credentials or take over the account.
+func RequireFirstParty(
+	original func(w http.ResponseWriter, r *http.Request),
+) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if currentAPIKeyID(r.Context()) != "" || currentClientID(r.Context()) != "" {
+			writeStatus(w, r, http.StatusForbidden, "not available to api keys or third-party apps")
+			return
+		}
+
+		original(w, r)
+	}
+}
+
+// accessTokenType - the typ claim of access tokens. Tokens signed for anything else, such as MFA challenges,
+// carry another typ and an aud, and are never accepted as access tokens.
+const accessTokenType = "access"
+
+// isAccessToken - access tokens issued before they carried a typ have none, but never an aud
+func isAccessToken(claims jwt.MapClaims) bool {
+	typ, _ := claims["typ"].(string)
+	_, hasAudience := claims["aud"]
+	return (typ == "" || typ == accessTokenType) && !hasAudience
+}
+
+func (h *Handler) validateToken(accessToken string) (valid bool, expired bool) {
+	t, err := h.Keys.Parse(accessToken)
 
 	v, ok := err.(*jwt.ValidationError)
 
 	if !ok {
-		return t.Valid, false
+		if err != nil || !t.Valid {
+			return false, false
+		}
+		claims, ok := t.Claims.(jwt.MapClaims)
+		return ok && isAccessToken(claims), false
 	}
 
 	if v.Errors == jwt.ValidationErrorExpired {
@@ -19,3 +19,18 @@ func RetrieveJWTTokenFromHeader(r *http.Request) string {
 
 	return authHeaderParts[1]
 }
+
+// RetrieveAPIKeyFromHeader - returns the key of an "Authorization: ApiKey [key]" header, or "" for any other scheme
+func RetrieveAPIKeyFromHeader(r *http.Request) string {
+	authHeader := r.Header["Authorization"]
+	if authHeader == nil {
+		return ""
+	}
+
+	authHeaderParts := strings.Split(authHeader[0], " ")
+	if len(authHeaderParts) != 2 || strings.ToLower(authHeaderParts[0]) != "apikey" {
+		return ""
+	}
+
+	return authHeaderParts[1]
+}
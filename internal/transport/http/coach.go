@@ -0,0 +1,182 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/yuchida-tamu/git-workout-api/internal/coach"
+)
+
+type CoachService interface {
+	Invite(ctx context.Context, coachID string, athleteID string, scopes []coach.Scope) (coach.Relationship, error)
+	Respond(ctx context.Context, athleteID string, ID string, accept bool) (coach.Relationship, error)
+	GetRelationshipsByCoach(ctx context.Context, coachID string) ([]coach.Relationship, error)
+	GetRelationshipsByAthlete(ctx context.Context, athleteID string) ([]coach.Relationship, error)
+	EndRelationship(ctx context.Context, userID string, ID string) error
+	HasScope(ctx context.Context, coachID string, athleteID string, scope coach.Scope) (bool, error)
+}
+
+type InviteAthleteRequest struct {
+	AthleteID string   `json:"athlete_id" validate:"required,uuid"`
+	Scopes    []string `json:"scopes" validate:"required,min=1,dive,oneof=records:read records:comment templates:assign"`
+}
+
+type RelationshipResponse struct {
+	ID          string    `json:"id"`
+	CoachID     string    `json:"coach_id"`
+	AthleteID   string    `json:"athlete_id"`
+	Scopes      []string  `json:"scopes"`
+	Status      string    `json:"status"`
+	DateCreated time.Time `json:"date_created"`
+}
+
+func convertRelationshipToResponse(rel coach.Relationship) RelationshipResponse {
+	scopes := make([]string, 0, len(rel.Scopes))
+	for _, s := range rel.Scopes {
+		scopes = append(scopes, string(s))
+	}
+	return RelationshipResponse{
+		ID:          rel.ID,
+		CoachID:     rel.CoachID,
+		AthleteID:   rel.AthleteID,
+		Scopes:      scopes,
+		Status:      string(rel.Status),
+		DateCreated: rel.DateCreated,
+	}
+}
+
+func writeRelationships(w http.ResponseWriter, rels []coach.Relationship) {
+	response := make([]RelationshipResponse, 0, len(rels))
+	for _, rel := range rels {
+		response = append(response, convertRelationshipToResponse(rel))
+	}
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		panic(err)
+	}
+}
+
+// InviteAthlete - a handler for coaches to invite an athlete and request scoped access to their logs
+func (h *Handler) InviteAthlete(w http.ResponseWriter, r *http.Request) {
+	var inviteReq InviteAthleteRequest
+	if err := json.NewDecoder(r.Body).Decode(&inviteReq); err != nil {
+		writeInvalidBody(w, r)
+		return
+	}
+
+	if err := validate.Struct(inviteReq); err != nil {
+		writeValidationError(w, r, err)
+		return
+	}
+
+	scopes := make([]coach.Scope, 0, len(inviteReq.Scopes))
+	for _, s := range inviteReq.Scopes {
+		scopes = append(scopes, coach.Scope(s))
+	}
+
+	rel, err := h.Service.Coach.Invite(r.Context(), currentUserID(r.Context()), inviteReq.AthleteID, scopes)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(convertRelationshipToResponse(rel)); err != nil {
+		panic(err)
+	}
+}
+
+// AcceptInvitation - a handler for athletes to accept a coach's invitation
+func (h *Handler) AcceptInvitation(w http.ResponseWriter, r *http.Request) {
+	h.respondToInvitation(w, r, true)
+}
+
+// DeclineInvitation - a handler for athletes to decline a coach's invitation
+func (h *Handler) DeclineInvitation(w http.ResponseWriter, r *http.Request) {
+	h.respondToInvitation(w, r, false)
+}
+
+func (h *Handler) respondToInvitation(w http.ResponseWriter, r *http.Request, accept bool) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	if id == "" {
+		writeStatus(w, r, http.StatusBadRequest, "the id is missing")
+		return
+	}
+
+	rel, err := h.Service.Coach.Respond(r.Context(), currentUserID(r.Context()), id, accept)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(convertRelationshipToResponse(rel)); err != nil {
+		panic(err)
+	}
+}
+
+// GetAthletes - a handler listing the relationships in which the current user is the coach
+func (h *Handler) GetAthletes(w http.ResponseWriter, r *http.Request) {
+	rels, err := h.Service.Coach.GetRelationshipsByCoach(r.Context(), currentUserID(r.Context()))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeRelationships(w, rels)
+}
+
+// GetCoaches - a handler listing the relationships, including pending invitations, in which the current user is the athlete
+func (h *Handler) GetCoaches(w http.ResponseWriter, r *http.Request) {
+	rels, err := h.Service.Coach.GetRelationshipsByAthlete(r.Context(), currentUserID(r.Context()))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeRelationships(w, rels)
+}
+
+// EndRelationship - a handler for either side to end a coach relationship
+func (h *Handler) EndRelationship(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	if id == "" {
+		writeStatus(w, r, http.StatusBadRequest, "the id is missing")
+		return
+	}
+
+	if err := h.Service.Coach.EndRelationship(r.Context(), currentUserID(r.Context()), id); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(Response{message: "Successfully deleted"}); err != nil {
+		panic(err)
+	}
+}
+
+// checkUserCanAccessAuthor - reports whether the current user may act on records written by authorID,
+// either because they are the author or because the author granted them the scope as their coach
+func (h *Handler) checkUserCanAccessAuthor(ctx context.Context, authorID string, scope coach.Scope) bool {
+	if checkUserHasAccess(ctx, authorID) {
+		return true
+	}
+
+	currentUserId := currentUserID(ctx)
+	if currentUserId == "" {
+		return false
+	}
+
+	granted, err := h.Service.Coach.HasScope(ctx, currentUserId, authorID, scope)
+	if err != nil {
+		log.Print(err)
+		return false
+	}
+
+	return granted
+}
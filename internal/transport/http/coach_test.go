@@ -0,0 +1,42 @@
+package http_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/yuchida-tamu/git-workout-api/internal/coach"
+	"github.com/yuchida-tamu/git-workout-api/internal/user"
+)
+
+func TestCoachAccessFollowsRole(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	// the logged in user coaches bob, who accepted
+	if err := s.store.UpdateUserRole(ctx, s.user.ID, user.RoleCoach); err != nil {
+		t.Fatal(err)
+	}
+	athlete, err := s.store.PostUser(ctx, user.User{Username: "bob", Email: "bob@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.store.PostRelationship(ctx, coach.Relationship{
+		CoachID:     s.user.ID,
+		AthleteID:   athlete.ID,
+		Scopes:      []coach.Scope{coach.ScopeReadRecords},
+		Status:      coach.StatusActive,
+		DateCreated: time.Now().UTC(),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	path := "/api/v1/record/author/" + athlete.ID
+
+	requireStatus(t, s.do(t, http.MethodGet, path, "", nil), http.StatusOK)
+
+	// the relationship is still there, but a former coach reads no records through it
+	if err := s.store.UpdateUserRole(ctx, s.user.ID, user.RoleUser); err != nil {
+		t.Fatal(err)
+	}
+	requireStatus(t, s.do(t, http.MethodGet, path, "", nil), http.StatusForbidden)
+}
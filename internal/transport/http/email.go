@@ -0,0 +1,124 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/yuchida-tamu/git-workout-api/internal/auth"
+)
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+type UpdateEmailRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// VerifyEmail - a handler confirming an email address with the token mailed to it
+func (h *Handler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var verifyReq VerifyEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&verifyReq); err != nil {
+		writeInvalidBody(w, r)
+		return
+	}
+
+	if err := validate.Struct(verifyReq); err != nil {
+		writeValidationError(w, r, err)
+		return
+	}
+
+	if err := h.Service.Auth.VerifyEmail(r.Context(), verifyReq.Token); err != nil {
+		log.Print(err)
+		if errors.Is(err, auth.ErrInvalidOneTimeToken) {
+			writeStatus(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeStatus(w, r, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ResendEmailVerification - a handler mailing a fresh verification token to the current user
+func (h *Handler) ResendEmailVerification(w http.ResponseWriter, r *http.Request) {
+	if err := h.Service.Auth.SendEmailVerification(r.Context(), currentUserID(r.Context())); err != nil {
+		log.Print(err)
+		if errors.Is(err, auth.ErrNoEmail) {
+			writeStatus(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeStatus(w, r, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// UpdateUserEmail - a handler changing the user's email address and sending a verification token to the new one
+func (h *Handler) UpdateUserEmail(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	if id == "" {
+		writeStatus(w, r, http.StatusBadRequest, "the id is missing")
+		return
+	}
+
+	// validate userId and currentId in the context match
+	if hasAccess := checkUserHasAccess(r.Context(), id); !hasAccess {
+		writeStatus(w, r, http.StatusForbidden, "forbidden")
+		return
+	}
+
+	var emailReq UpdateEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&emailReq); err != nil {
+		writeInvalidBody(w, r)
+		return
+	}
+
+	if err := validate.Struct(emailReq); err != nil {
+		writeValidationError(w, r, err)
+		return
+	}
+
+	if err := h.Service.User.UpdateUserEmail(r.Context(), id, emailReq.Email); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	if err := h.Service.Auth.SendEmailVerification(r.Context(), id); err != nil {
+		log.Print(err)
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// RequireVerifiedEmail - a policy wrapper for routes which publish content. It only takes effect when
+// REQUIRE_VERIFIED_EMAIL is enabled, so unverified users can always log in and use the rest of the API.
+func (h *Handler) RequireVerifiedEmail(
+	original func(w http.ResponseWriter, r *http.Request),
+) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !h.RequireVerifiedEmailToPublish {
+			original(w, r)
+			return
+		}
+
+		// read from the store rather than a token claim so verifying takes effect immediately
+		currentUser, err := h.Service.User.GetUser(r.Context(), currentUserID(r.Context()))
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		if !currentUser.EmailVerified {
+			writeStatus(w, r, http.StatusForbidden, "email address not verified")
+			return
+		}
+
+		original(w, r)
+	}
+}
@@ -0,0 +1,78 @@
+package http
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/yuchida-tamu/git-workout-api/internal/auth"
+	"github.com/yuchida-tamu/git-workout-api/internal/coach"
+	"github.com/yuchida-tamu/git-workout-api/internal/record"
+	"github.com/yuchida-tamu/git-workout-api/internal/user"
+)
+
+// errorStatuses - the status code of each domain error, checked in order with errors.Is. The services and
+// stores of the user, record and coach packages return their package's errors wrapped with more detail, so
+// that the status of a failure is decided here once instead of in every handler.
+var errorStatuses = []struct {
+	err    error
+	status int
+}{
+	{user.ErrNotFound, http.StatusNotFound},
+	{record.ErrNotFound, http.StatusNotFound},
+	{coach.ErrNotFound, http.StatusNotFound},
+	{auth.ErrNotFound, http.StatusNotFound},
+	{auth.ErrSessionNotFound, http.StatusNotFound},
+	{auth.ErrAPIKeyNotFound, http.StatusNotFound},
+	{auth.ErrOAuthClientNotFound, http.StatusNotFound},
+	{auth.ErrIdentityNotFound, http.StatusNotFound},
+
+	{user.ErrConflict, http.StatusConflict},
+	{record.ErrConflict, http.StatusConflict},
+	{coach.ErrConflict, http.StatusConflict},
+	{auth.ErrMFAAlreadyEnabled, http.StatusConflict},
+
+	{user.ErrValidation, http.StatusBadRequest},
+	{record.ErrValidation, http.StatusBadRequest},
+	{coach.ErrValidation, http.StatusBadRequest},
+	{auth.ErrValidation, http.StatusBadRequest},
+	{auth.ErrInvalidMFACode, http.StatusBadRequest},
+	{auth.ErrMFANotEnrolled, http.StatusBadRequest},
+
+	{user.ErrForbidden, http.StatusForbidden},
+	{record.ErrForbidden, http.StatusForbidden},
+	{coach.ErrForbidden, http.StatusForbidden},
+
+	{user.ErrVersionMismatch, http.StatusPreconditionFailed},
+	{record.ErrVersionMismatch, http.StatusPreconditionFailed},
+}
+
+// statusForError - the status code for err and the part of its message which is safe to show, which starts
+// at the domain error: services add detail after it, the stores add context about the query before it.
+// Errors which are not domain errors are a 500 and only logged.
+func statusForError(err error) (int, string) {
+	for _, e := range errorStatuses {
+		if errors.Is(err, e.err) {
+			message := err.Error()
+			if i := strings.Index(message, e.err.Error()); i >= 0 {
+				message = message[i:]
+			}
+			return e.status, message
+		}
+	}
+
+	return http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError)
+}
+
+// writeError - logs err and answers with the problem it maps to; password policy violations list every
+// broken rule
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	log.Printf("request %s: %v", currentRequestID(r.Context()), err)
+	if writePasswordPolicyError(w, r, err) {
+		return
+	}
+
+	status, message := statusForError(err)
+	writeStatus(w, r, status, message)
+}
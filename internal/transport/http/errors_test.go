@@ -0,0 +1,121 @@
+package http_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/yuchida-tamu/git-workout-api/internal/auth"
+	"github.com/yuchida-tamu/git-workout-api/internal/memstore"
+)
+
+// errDatabaseDown - what a store failing for reasons of its own returns; clients must never see it
+var errDatabaseDown = errors.New("pq: connection refused by 10.0.0.5")
+
+// failingStore - a memory store whose auth lookups fail once failing is set
+type failingStore struct {
+	*memstore.Store
+	failing bool
+}
+
+func (s *failingStore) GetAPIKey(ctx context.Context, ID string) (auth.APIKey, error) {
+	if s.failing {
+		return auth.APIKey{}, errDatabaseDown
+	}
+	return s.Store.GetAPIKey(ctx, ID)
+}
+
+func (s *failingStore) GetMFA(ctx context.Context, userID string) (auth.MFA, error) {
+	if s.failing {
+		return auth.MFA{}, errDatabaseDown
+	}
+	return s.Store.GetMFA(ctx, userID)
+}
+
+func (s *failingStore) GetIdentitiesByUser(ctx context.Context, userID string) ([]auth.Identity, error) {
+	if s.failing {
+		return nil, errDatabaseDown
+	}
+	return s.Store.GetIdentitiesByUser(ctx, userID)
+}
+
+func (s *failingStore) PostSession(ctx context.Context, session auth.Session) (auth.Session, error) {
+	if s.failing {
+		return auth.Session{}, errDatabaseDown
+	}
+	return s.Store.PostSession(ctx, session)
+}
+
+func requireNoLeak(t *testing.T, body string) {
+	t.Helper()
+
+	if strings.Contains(body, errDatabaseDown.Error()) {
+		t.Fatalf("the store's error reached the client: %s", body)
+	}
+}
+
+func TestErrorStatuses(t *testing.T) {
+	s := newTestServer(t)
+	missing := uuid.NewV4().String()
+
+	for _, c := range []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+	}{
+		{"UnknownScope", http.MethodPost, "/api/v1/auth/apikeys", `{"name": "importer", "scopes": ["everything"]}`, http.StatusBadRequest},
+		{"ExpiredKey", http.MethodPost, "/api/v1/auth/apikeys", `{"name": "importer", "scopes": ["records:read"], "expires_at": "2000-01-01T00:00:00Z"}`, http.StatusBadRequest},
+		{"MissingAPIKey", http.MethodDelete, "/api/v1/auth/apikeys/" + missing, "", http.StatusNotFound},
+		{"MissingSession", http.MethodDelete, "/api/v1/auth/sessions/" + missing, "", http.StatusNotFound},
+		{"MissingIdentity", http.MethodDelete, "/api/v1/auth/identities/" + missing, "", http.StatusNotFound},
+		{"MFANotEnrolled", http.MethodDelete, "/api/v1/auth/mfa", `{"code": "123456"}`, http.StatusBadRequest},
+		{"WrongMFACode", http.MethodPost, "/api/v1/auth/mfa/verify", `{"code": "123456"}`, http.StatusBadRequest},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			if c.name == "WrongMFACode" {
+				if _, err := s.auth.EnrollMFA(context.Background(), s.user.ID, s.user.Username); err != nil {
+					t.Fatal(err)
+				}
+			}
+			requireStatus(t, s.do(t, c.method, c.path, c.body, nil), c.status)
+		})
+	}
+}
+
+func TestStoreFailuresAreInternal(t *testing.T) {
+	mem := memstore.NewStore()
+	store := &failingStore{Store: mem}
+	s := newTestServerWith(t, mem, store)
+	store.failing = true
+
+	for _, c := range []struct {
+		name   string
+		method string
+		path   string
+		body   string
+	}{
+		{"DeleteAPIKey", http.MethodDelete, "/api/v1/auth/apikeys/" + uuid.NewV4().String(), ""},
+		{"DeleteIdentity", http.MethodDelete, "/api/v1/auth/identities/" + uuid.NewV4().String(), ""},
+		{"EnrollMFA", http.MethodPost, "/api/v1/auth/mfa/enroll", ""},
+		{"VerifyMFA", http.MethodPost, "/api/v1/auth/mfa/verify", `{"code": "123456"}`},
+		{"DisableMFA", http.MethodDelete, "/api/v1/auth/mfa", `{"code": "123456"}`},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			rec := s.do(t, c.method, c.path, c.body, nil)
+			requireStatus(t, rec, http.StatusInternalServerError)
+			requireNoLeak(t, rec.Body.String())
+		})
+	}
+
+	t.Run("Login", func(t *testing.T) {
+		s.token = ""
+		rec := s.do(t, http.MethodPost, "/api/v1/auth/auth", `{"username": "alice", "password": "`+testPassword+`"}`, nil)
+		requireStatus(t, rec, http.StatusInternalServerError)
+		requireNoLeak(t, rec.Body.String())
+	})
+}
@@ -0,0 +1,64 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// formatETag - the entity tag of a user or record at version; a version only ever counts up, so the tag is a
+// strong one
+func formatETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// etagListed - reports whether an If-Match or If-None-Match header value lists etag or is "*". The strong
+// comparison of If-Match never matches a weak tag, the weak one of If-None-Match ignores the W/ prefix.
+func etagListed(header string, etag string, weak bool) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return true
+		}
+		if weak {
+			tag = strings.TrimPrefix(tag, "W/")
+		}
+		if tag == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkIfMatch - the version a PUT or DELETE may change, which is 0 - any version - without an If-Match header.
+// A header not listing the current version is answered with 412 and ok is false. The write itself has to
+// check the version again, another request may change the resource in between.
+func checkIfMatch(w http.ResponseWriter, r *http.Request, current int) (version int, ok bool) {
+	header := r.Header.Get("If-Match")
+	if header == "" || strings.TrimSpace(header) == "*" {
+		return 0, true
+	}
+
+	if !etagListed(header, formatETag(current), false) {
+		writeStatus(w, r, http.StatusPreconditionFailed, "the resource has been changed since it was read")
+		return 0, false
+	}
+
+	return current, true
+}
+
+// writeNotModified - sets the ETag of a GET and answers 304 when If-None-Match shows the client has the
+// current version already; the handler is done when it returns true
+func writeNotModified(w http.ResponseWriter, r *http.Request, current int) bool {
+	etag := formatETag(current)
+	w.Header().Set("ETag", etag)
+
+	header := r.Header.Get("If-None-Match")
+	if header == "" || !etagListed(header, etag, true) {
+		return false
+	}
+
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
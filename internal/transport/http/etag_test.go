@@ -0,0 +1,91 @@
+package http_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestGetRecordETag(t *testing.T) {
+	s := newTestServer(t)
+	rcd := s.postRecord(t)
+	path := "/api/v1/record/" + rcd.ID
+
+	rec := s.do(t, http.MethodGet, path, "", nil)
+	requireStatus(t, rec, http.StatusOK)
+	if etag := rec.Header().Get("ETag"); etag != `"1"` {
+		t.Fatalf(`expected the ETag "1", got %q`, etag)
+	}
+
+	for _, header := range []string{`"1"`, `W/"1"`, `"0", "1"`, `*`} {
+		rec = s.do(t, http.MethodGet, path, "", map[string]string{"If-None-Match": header})
+		requireStatus(t, rec, http.StatusNotModified)
+		if rec.Body.Len() != 0 {
+			t.Fatalf("expected no body with 304, got %s", rec.Body)
+		}
+	}
+
+	rec = s.do(t, http.MethodGet, path, "", map[string]string{"If-None-Match": `"0"`})
+	requireStatus(t, rec, http.StatusOK)
+}
+
+func TestUpdateRecordIfMatch(t *testing.T) {
+	s := newTestServer(t)
+	rcd := s.postRecord(t)
+	path := "/api/v1/record/" + rcd.ID
+	body := `{"DateCreated": "2026-10-19", "MessageBody": "deadlift 3x5"}`
+
+	requireStatus(t, s.do(t, http.MethodPut, path, body, map[string]string{"If-Match": `"2"`}), http.StatusPreconditionFailed)
+	// If-Match compares strongly, a weak tag never matches even at the current version
+	requireStatus(t, s.do(t, http.MethodPut, path, body, map[string]string{"If-Match": `W/"1"`}), http.StatusPreconditionFailed)
+
+	rec := s.do(t, http.MethodPut, path, body, map[string]string{"If-Match": `"1"`})
+	requireStatus(t, rec, http.StatusOK)
+	if etag := rec.Header().Get("ETag"); etag != `"2"` {
+		t.Fatalf(`expected the ETag "2" after the update, got %q`, etag)
+	}
+
+	// the version read before the update is stale now
+	requireStatus(t, s.do(t, http.MethodPut, path, body, map[string]string{"If-Match": `"1"`}), http.StatusPreconditionFailed)
+	requireStatus(t, s.do(t, http.MethodPut, path, body, nil), http.StatusOK)
+}
+
+func TestDeleteRecordIfMatch(t *testing.T) {
+	s := newTestServer(t)
+	rcd := s.postRecord(t)
+	path := "/api/v1/record/" + rcd.ID
+
+	requireStatus(t, s.do(t, http.MethodDelete, path, "", map[string]string{"If-Match": `"2"`}), http.StatusPreconditionFailed)
+	requireStatus(t, s.do(t, http.MethodDelete, path, "", map[string]string{"If-Match": `W/"1"`}), http.StatusPreconditionFailed)
+	if _, err := s.store.GetRecordById(context.Background(), rcd.ID); err != nil {
+		t.Fatalf("expected the record to be kept after a failed precondition, got %v", err)
+	}
+
+	requireStatus(t, s.do(t, http.MethodDelete, path, "", map[string]string{"If-Match": `"1"`}), http.StatusOK)
+	requireStatus(t, s.do(t, http.MethodGet, path, "", nil), http.StatusNotFound)
+}
+
+func TestUserETag(t *testing.T) {
+	s := newTestServer(t)
+	path := "/api/v1/user/" + s.user.ID
+
+	rec := s.do(t, http.MethodGet, path, "", nil)
+	requireStatus(t, rec, http.StatusOK)
+	etag := rec.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("expected an ETag on the user")
+	}
+	requireStatus(t, s.do(t, http.MethodGet, path, "", map[string]string{"If-None-Match": etag}), http.StatusNotModified)
+
+	requireStatus(t, s.do(t, http.MethodDelete, path, "", map[string]string{"If-Match": `"999"`}), http.StatusPreconditionFailed)
+	requireStatus(t, s.do(t, http.MethodDelete, path, "", map[string]string{"If-Match": "W/" + etag}), http.StatusPreconditionFailed)
+	u, err := s.store.GetUser(context.Background(), s.user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !u.DeleteAfter.IsZero() {
+		t.Fatal("expected no deletion to be scheduled after a failed precondition")
+	}
+
+	requireStatus(t, s.do(t, http.MethodDelete, path, "", map[string]string{"If-Match": etag}), http.StatusAccepted)
+}
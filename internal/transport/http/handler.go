@@ -9,55 +9,120 @@ import (
 	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/yuchida-tamu/git-workout-api/internal/auth"
+	"github.com/yuchida-tamu/git-workout-api/internal/signing"
+	"github.com/yuchida-tamu/git-workout-api/internal/user"
 )
 
 type Service struct {
 	User   UserService
 	Record RecordService
+	Coach  CoachService
+	Auth   AuthService
 }
 
 type Handler struct {
 	Router  *mux.Router
 	Service Service
+	Keys    *signing.KeySet
 	Server  *http.Server
+	// RequireVerifiedEmailToPublish gates the routes wrapped in RequireVerifiedEmail
+	RequireVerifiedEmailToPublish bool
 }
 
-func NewHandler(service Service) *Handler {
+func NewHandler(service Service, keys *signing.KeySet) *Handler {
 	h := &Handler{
 		Service: service,
+		Keys:    keys,
+
+		RequireVerifiedEmailToPublish: os.Getenv("REQUIRE_VERIFIED_EMAIL") == "true",
 	}
 	h.Router = mux.NewRouter()
+	h.Router.NotFoundHandler = http.HandlerFunc(NotFoundHandler)
+	h.Router.MethodNotAllowedHandler = http.HandlerFunc(MethodNotAllowedHandler)
 	h.mapRoutes()
 
 	// use middlewares
 	h.Router.Use(JSONMiddleware)
 	h.Router.Use(LoggingMiddleware)
 	h.Router.Use(TimeoutMiddleware)
-	h.Router.Use(AddCurrentUserToContextMiddleware)
+	h.Router.Use(h.AddCurrentUserToContextMiddleware)
 
 	h.Server = &http.Server{
-		Addr:    "0.0.0.0:8080",
-		Handler: h.Router,
+		Addr: "0.0.0.0:8080",
+		// the request id wraps the router so that requests without a route get one as well
+		Handler: RequestIDMiddleware(h.Router),
 	}
 
 	return h
 }
 
+// mapRoutes - registers every route. Routes taking credentials which can be limited, API keys and tokens of
+// third-party apps, declare the scope they need with RequireScope; routes managing the account itself are
+// RequireFirstParty instead.
 func (h *Handler) mapRoutes() {
+	// Keys
+	h.Router.HandleFunc("/.well-known/jwks.json", h.GetJWKS).Methods("GET")
 	// Auth
 	h.Router.HandleFunc("/api/v1/auth/auth", h.AuthUser).Methods("POST")
 	h.Router.HandleFunc("/api/v1/auth/refresh", h.RefreshToken).Methods("POST")
+	h.Router.HandleFunc("/api/v1/auth/logout", h.Logout).Methods("POST")
+	h.Router.HandleFunc("/api/v1/auth/sessions", h.JWTAuth(RequireFirstParty(h.GetSessions))).Methods("GET")
+	h.Router.HandleFunc("/api/v1/auth/sessions/{id}", h.JWTAuth(RequireFirstParty(h.DeleteSession))).Methods("DELETE")
+	h.Router.HandleFunc("/api/v1/auth/apikeys", h.JWTAuth(RequireFirstParty(h.GetAPIKeys))).Methods("GET")
+	h.Router.HandleFunc("/api/v1/auth/apikeys", h.JWTAuth(RequireFirstParty(h.PostAPIKey))).Methods("POST")
+	h.Router.HandleFunc("/api/v1/auth/apikeys/{id}", h.JWTAuth(RequireFirstParty(h.DeleteAPIKey))).Methods("DELETE")
+	h.Router.HandleFunc("/api/v1/auth/oidc", h.GetOIDCProviders).Methods("GET")
+	h.Router.HandleFunc("/api/v1/auth/oidc/{provider}/login", h.BeginOIDCLogin).Methods("GET")
+	h.Router.HandleFunc("/api/v1/auth/oidc/{provider}/link", h.JWTAuth(RequireFirstParty(h.BeginOIDCLink))).Methods("POST")
+	h.Router.HandleFunc("/api/v1/auth/oidc/{provider}/callback", h.OIDCCallback).Methods("GET")
+	h.Router.HandleFunc("/api/v1/auth/identities", h.JWTAuth(RequireScope(auth.ScopeProfileRead, h.GetIdentities))).Methods("GET")
+	h.Router.HandleFunc("/api/v1/auth/identities/{id}", h.JWTAuth(RequireFirstParty(h.DeleteIdentity))).Methods("DELETE")
+	h.Router.HandleFunc("/api/v1/auth/lockouts", h.JWTAuth(RequireScope(auth.ScopeProfileRead, h.GetLockoutEvents))).Methods("GET")
+	h.Router.HandleFunc("/api/v1/auth/password/forgot", h.ForgotPassword).Methods("POST")
+	h.Router.HandleFunc("/api/v1/auth/password/reset", h.ResetPassword).Methods("POST")
+	h.Router.HandleFunc("/api/v1/auth/email/verify", h.VerifyEmail).Methods("POST")
+	h.Router.HandleFunc("/api/v1/auth/email/resend", h.JWTAuth(RequireFirstParty(h.ResendEmailVerification))).Methods("POST")
+	h.Router.HandleFunc("/api/v1/auth/mfa/challenge", h.CompleteMFAChallenge).Methods("POST")
+	h.Router.HandleFunc("/api/v1/auth/mfa/enroll", h.JWTAuth(RequireFirstParty(h.EnrollMFA))).Methods("POST")
+	h.Router.HandleFunc("/api/v1/auth/mfa/verify", h.JWTAuth(RequireFirstParty(h.VerifyMFA))).Methods("POST")
+	h.Router.HandleFunc("/api/v1/auth/mfa", h.JWTAuth(RequireFirstParty(h.DisableMFA))).Methods("DELETE")
+	// OAuth
+	h.Router.HandleFunc("/api/v1/oauth/clients", h.JWTAuth(RequireFirstParty(h.GetOAuthClients))).Methods("GET")
+	h.Router.HandleFunc("/api/v1/oauth/clients", h.JWTAuth(RequireFirstParty(h.PostOAuthClient))).Methods("POST")
+	h.Router.HandleFunc("/api/v1/oauth/clients/{id}", h.JWTAuth(RequireFirstParty(h.DeleteOAuthClient))).Methods("DELETE")
+	h.Router.HandleFunc("/api/v1/oauth/authorize", h.JWTAuth(RequireFirstParty(h.GetAuthorization))).Methods("GET")
+	h.Router.HandleFunc("/api/v1/oauth/authorize", h.JWTAuth(RequireFirstParty(h.PostAuthorization))).Methods("POST")
+	h.Router.HandleFunc("/api/v1/oauth/token", h.OAuthToken).Methods("POST")
+	h.Router.HandleFunc("/api/v1/oauth/introspect", h.IntrospectOAuthToken).Methods("POST")
+	h.Router.HandleFunc("/api/v1/oauth/revoke", h.RevokeOAuthToken).Methods("POST")
 	// User
 	h.Router.HandleFunc("/api/v1/user", h.PostUser).Methods("POST")
-	h.Router.HandleFunc("/api/v1/user/{id}", JWTAuth(h.GetUser)).Methods("GET")
-	h.Router.HandleFunc("/api/v1/user/{id}", JWTAuth(h.UpdateUser)).Methods("PUT")
-	h.Router.HandleFunc("/api/v1/user/{id}", JWTAuth(h.DeleteUser)).Methods("DELETE")
+	h.Router.HandleFunc("/api/v1/user/{id}", h.JWTAuth(RequireScope(auth.ScopeProfileRead, h.GetUser))).Methods("GET")
+	h.Router.HandleFunc("/api/v1/user/{id}", h.JWTAuth(RequireFirstParty(h.UpdateUser))).Methods("PUT")
+	h.Router.HandleFunc("/api/v1/user/{id}", h.JWTAuth(RequireFirstParty(h.DeleteUser))).Methods("DELETE")
+	h.Router.HandleFunc("/api/v1/user/{id}/email", h.JWTAuth(RequireFirstParty(h.UpdateUserEmail))).Methods("PUT")
+	// Admin
+	h.Router.HandleFunc("/api/v1/admin/user", h.JWTAuth(RequireFirstParty(RequirePermission(user.PermissionListUsers, h.GetUsers)))).Methods("GET")
+	h.Router.HandleFunc("/api/v1/admin/user/{id}/role", h.JWTAuth(RequireFirstParty(RequirePermission(user.PermissionManageUsers, h.UpdateUserRole)))).Methods("PUT")
+	h.Router.HandleFunc("/api/v1/admin/user/{id}/disable", h.JWTAuth(RequireFirstParty(RequirePermission(user.PermissionManageUsers, h.DisableUser)))).Methods("POST")
+	h.Router.HandleFunc("/api/v1/admin/user/{id}/enable", h.JWTAuth(RequireFirstParty(RequirePermission(user.PermissionManageUsers, h.EnableUser)))).Methods("POST")
 	// Record
-	h.Router.HandleFunc("/api/v1/record", JWTAuth(h.PostRecord)).Methods("POST")
-	h.Router.HandleFunc("/api/v1/record/author/{id}", JWTAuth(h.GetRecordByAuthor)).Methods("GET")
-	h.Router.HandleFunc("/api/v1/record/{id}", JWTAuth(h.GetRecordById)).Methods("GET")
-	h.Router.HandleFunc("/api/v1/record/{id}", JWTAuth(h.UpdateRecord)).Methods("PUT")
-	h.Router.HandleFunc("/api/v1/record/{id}", JWTAuth(h.DeleteRecord)).Methods("DELETE")
+	h.Router.HandleFunc("/api/v1/record", h.JWTAuth(RequireScope(auth.ScopeRecordsWrite, h.RequireVerifiedEmail(h.PostRecord)))).Methods("POST")
+	h.Router.HandleFunc("/api/v1/record/author/{id}", h.JWTAuth(RequireScope(auth.ScopeRecordsRead, h.GetRecordByAuthor))).Methods("GET")
+	h.Router.HandleFunc("/api/v1/record/author/{id}/import", h.JWTAuth(RequireScope(auth.ScopeRecordsWrite, h.RequireVerifiedEmail(h.ImportRecords)))).Methods("POST")
+	h.Router.HandleFunc("/api/v1/record/{id}", h.JWTAuth(RequireScope(auth.ScopeRecordsRead, h.GetRecordById))).Methods("GET")
+	h.Router.HandleFunc("/api/v1/record/{id}", h.JWTAuth(RequireScope(auth.ScopeRecordsWrite, h.UpdateRecord))).Methods("PUT")
+	h.Router.HandleFunc("/api/v1/record/{id}", h.JWTAuth(RequireScope(auth.ScopeRecordsWrite, h.DeleteRecord))).Methods("DELETE")
+	h.Router.HandleFunc("/api/v1/record/{id}/comment", h.JWTAuth(RequireScope(auth.ScopeRecordsRead, h.GetCommentsByRecord))).Methods("GET")
+	h.Router.HandleFunc("/api/v1/record/{id}/comment", h.JWTAuth(RequireScope(auth.ScopeRecordsWrite, h.RequireVerifiedEmail(h.PostComment)))).Methods("POST")
+	// Coach
+	h.Router.HandleFunc("/api/v1/coach/invitation", h.JWTAuth(RequireScope(auth.ScopeProfileWrite, RequirePermission(user.PermissionCoachAthletes, h.RequireVerifiedEmail(h.InviteAthlete))))).Methods("POST")
+	h.Router.HandleFunc("/api/v1/coach/invitation/{id}/accept", h.JWTAuth(RequireScope(auth.ScopeProfileWrite, h.AcceptInvitation))).Methods("POST")
+	h.Router.HandleFunc("/api/v1/coach/invitation/{id}/decline", h.JWTAuth(RequireScope(auth.ScopeProfileWrite, h.DeclineInvitation))).Methods("POST")
+	h.Router.HandleFunc("/api/v1/coach/athletes", h.JWTAuth(RequireScope(auth.ScopeProfileRead, RequirePermission(user.PermissionCoachAthletes, h.GetAthletes)))).Methods("GET")
+	h.Router.HandleFunc("/api/v1/coach/coaches", h.JWTAuth(RequireScope(auth.ScopeProfileRead, h.GetCoaches))).Methods("GET")
+	h.Router.HandleFunc("/api/v1/coach/relationship/{id}", h.JWTAuth(RequireScope(auth.ScopeProfileWrite, h.EndRelationship))).Methods("DELETE")
 }
 
 func (h *Handler) Serve() error {
@@ -0,0 +1,132 @@
+package http_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/yuchida-tamu/git-workout-api/internal/auth"
+	"github.com/yuchida-tamu/git-workout-api/internal/coach"
+	"github.com/yuchida-tamu/git-workout-api/internal/mailer"
+	"github.com/yuchida-tamu/git-workout-api/internal/memstore"
+	"github.com/yuchida-tamu/git-workout-api/internal/oidc"
+	"github.com/yuchida-tamu/git-workout-api/internal/password"
+	"github.com/yuchida-tamu/git-workout-api/internal/record"
+	"github.com/yuchida-tamu/git-workout-api/internal/signing"
+	transportHttp "github.com/yuchida-tamu/git-workout-api/internal/transport/http"
+	"github.com/yuchida-tamu/git-workout-api/internal/user"
+)
+
+const testPassword = "correct horse battery staple"
+
+// testServer - the API on a memory store, with a user logged in as token
+type testServer struct {
+	handler http.Handler
+	store   *memstore.Store
+	auth    *auth.Service
+	user    user.User
+	token   string
+}
+
+// testStore - what the services need; tests failing a store operation wrap the memory store
+type testStore interface {
+	user.Store
+	record.Store
+	coach.Store
+	auth.Store
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+
+	store := memstore.NewStore()
+	return newTestServerWith(t, store, store)
+}
+
+// newTestServerWith - the API on store, which stores in mem
+func newTestServerWith(t *testing.T, mem *memstore.Store, store testStore) *testServer {
+	t.Helper()
+
+	// the lowest bcrypt cost, logging in once per test need not be slow
+	hasher := password.DefaultHasher()
+	hasher.BcryptCost = 4
+	policy := password.DefaultPolicy()
+
+	authService := auth.NewService(store, mailer.NewLogMailer(""), policy, hasher, map[string]*oidc.Provider{})
+	h := transportHttp.NewHandler(transportHttp.Service{
+		User:   user.NewService(store, policy, hasher),
+		Record: record.NewService(store),
+		Coach:  coach.NewService(store),
+		Auth:   authService,
+	}, signing.NewHMACKeySet([]byte("test-secret")))
+
+	hash, err := hasher.Hash(testPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := mem.PostUser(context.Background(), user.User{Username: "alice", Email: "alice@example.com", Password: hash})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &testServer{handler: h.Server.Handler, store: mem, auth: authService, user: u}
+	login := s.login(t)
+	if login.Token == "" {
+		t.Fatalf("failed to log in: %+v", login)
+	}
+	s.token = login.Token
+
+	return s
+}
+
+// login - logs in with the user's password, without a token of an earlier login
+func (s *testServer) login(t *testing.T) transportHttp.AuthUserResponse {
+	t.Helper()
+
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/auth/auth", strings.NewReader(`{"username": "alice", "password": "`+testPassword+`"}`))
+	rec := httptest.NewRecorder()
+	s.handler.ServeHTTP(rec, r)
+	requireStatus(t, rec, http.StatusOK)
+
+	var login transportHttp.AuthUserResponse
+	if err := json.NewDecoder(rec.Body).Decode(&login); err != nil {
+		t.Fatal(err)
+	}
+	return login
+}
+
+func (s *testServer) do(t *testing.T, method string, path string, body string, header map[string]string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	if s.token != "" {
+		r.Header.Set("Authorization", "Bearer "+s.token)
+	}
+	for k, v := range header {
+		r.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	s.handler.ServeHTTP(rec, r)
+	return rec
+}
+
+func (s *testServer) postRecord(t *testing.T) record.Record {
+	t.Helper()
+
+	rcd, err := s.store.PostRecord(context.Background(), record.Record{DateCreated: "2026-10-19", MessageBody: "deadlift 5x5", Author: s.user.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rcd
+}
+
+func requireStatus(t *testing.T, rec *httptest.ResponseRecorder, want int) {
+	t.Helper()
+
+	if rec.Code != want {
+		t.Fatalf("expected status %d, got %d: %s", want, rec.Code, rec.Body)
+	}
+}
@@ -0,0 +1,15 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// GetJWKS - a handler publishing the public signing keys so other services can verify tokens themselves
+func (h *Handler) GetJWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+
+	if err := json.NewEncoder(w).Encode(h.Keys.JWKS()); err != nil {
+		panic(err)
+	}
+}
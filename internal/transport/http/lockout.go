@@ -0,0 +1,39 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+type LockoutEventResponse struct {
+	ID          string    `json:"id"`
+	IP          string    `json:"ip"`
+	Reason      string    `json:"reason"`
+	LockedUntil time.Time `json:"locked_until"`
+	DateCreated time.Time `json:"date_created"`
+}
+
+// GetLockoutEvents - a handler listing the lockouts caused by failed logins to the current user's account
+func (h *Handler) GetLockoutEvents(w http.ResponseWriter, r *http.Request) {
+	events, err := h.Service.Auth.GetLockoutEventsByUser(r.Context(), currentUserID(r.Context()))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	response := make([]LockoutEventResponse, 0, len(events))
+	for _, event := range events {
+		response = append(response, LockoutEventResponse{
+			ID:          event.ID,
+			IP:          event.IP,
+			Reason:      string(event.Reason),
+			LockedUntil: event.LockedUntil,
+			DateCreated: event.DateCreated,
+		})
+	}
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		panic(err)
+	}
+}
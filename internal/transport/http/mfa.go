@@ -0,0 +1,218 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/yuchida-tamu/git-workout-api/internal/auth"
+	"github.com/yuchida-tamu/git-workout-api/internal/user"
+)
+
+// the typ and aud claims of MFA challenge tokens, which access token validation rejects
+const (
+	mfaChallengeType     = "mfa_challenge"
+	mfaChallengeAudience = "mfa"
+)
+
+type MFAEnrollmentResponse struct {
+	Secret          string   `json:"secret"`
+	ProvisioningURI string   `json:"provisioning_uri"`
+	RecoveryCodes   []string `json:"recovery_codes"`
+}
+
+type MFACodeRequest struct {
+	Code string `json:"code" validate:"required"`
+}
+
+type MFAChallengeRequest struct {
+	MFAToken string `json:"mfa_token" validate:"required"`
+	Code     string `json:"code" validate:"required"`
+}
+
+// writeMFAChallenge - answers a successful password check for a user with MFA enabled with a short-lived
+// challenge token instead of real tokens. Its jti is stored, so that the challenge completes one login only.
+func (h *Handler) writeMFAChallenge(w http.ResponseWriter, r *http.Request, u user.User, device string) {
+	challengeID, err := h.Service.Auth.IssueMFAChallenge(r.Context(), u.ID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	challenge, err := h.Keys.Sign(jwt.MapClaims{
+		"typ":    mfaChallengeType,
+		"aud":    mfaChallengeAudience,
+		"jti":    challengeID,
+		"userId": u.ID,
+		"device": device,
+		"exp":    time.Now().Add(auth.MFAChallengeLifetime).Unix(),
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	response := AuthUserResponse{
+		UserID:      u.ID,
+		MFARequired: true,
+		MFAToken:    challenge,
+	}
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		panic(err)
+	}
+}
+
+func (h *Handler) parseMFAChallenge(challenge string) (jwt.MapClaims, error) {
+	token, err := h.Keys.Parse(challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid || claims["typ"] != mfaChallengeType || !claims.VerifyAudience(mfaChallengeAudience, true) {
+		return nil, errors.New("not an mfa challenge token")
+	}
+
+	return claims, nil
+}
+
+// CompleteMFAChallenge - a handler exchanging an MFA challenge token and a TOTP or recovery code for real tokens
+func (h *Handler) CompleteMFAChallenge(w http.ResponseWriter, r *http.Request) {
+	var challengeReq MFAChallengeRequest
+	if err := json.NewDecoder(r.Body).Decode(&challengeReq); err != nil {
+		writeInvalidBody(w, r)
+		return
+	}
+
+	if err := validate.Struct(challengeReq); err != nil {
+		writeValidationError(w, r, err)
+		return
+	}
+
+	claims, err := h.parseMFAChallenge(challengeReq.MFAToken)
+	if err != nil {
+		log.Print(err)
+		writeStatus(w, r, http.StatusUnauthorized, "not authorized")
+		return
+	}
+	userID, _ := claims["userId"].(string)
+	device, _ := claims["device"].(string)
+	challengeID, _ := claims["jti"].(string)
+
+	currentUser, err := h.Service.User.GetUser(r.Context(), userID)
+	if err != nil || currentUser.Disabled {
+		writeInvalidCredentials(w, r, "not authorized")
+		return
+	}
+
+	// wrong codes count against the same limits as wrong passwords
+	ip := clientInfoFromRequest(r, "").IP
+	if retryAfter := h.Service.Auth.LoginLockedFor(currentUser.Username, ip); retryAfter > 0 {
+		writeTooManyAttempts(w, r, retryAfter)
+		return
+	}
+
+	err = h.Service.Auth.CompleteMFAChallenge(r.Context(), userID, challengeID, challengeReq.Code)
+	if errors.Is(err, auth.ErrInvalidOneTimeToken) {
+		writeStatus(w, r, http.StatusUnauthorized, "not authorized")
+		return
+	}
+	if errors.Is(err, auth.ErrInvalidMFACode) || errors.Is(err, auth.ErrMFANotEnrolled) {
+		h.Service.Auth.RecordLoginFailure(r.Context(), currentUser.Username, ip)
+		writeInvalidCredentials(w, r, "invalid two-factor authentication code")
+		return
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	tokenPair, err := h.generateTokenPair(r.Context(), currentUser, clientInfoFromRequest(r, device))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	h.Service.Auth.RecordLoginSuccess(currentUser.Username)
+
+	response := AuthUserResponse{
+		Token:        tokenPair["access_token"],
+		RefreshToken: tokenPair["refresh_token"],
+		UserID:       currentUser.ID,
+	}
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		panic(err)
+	}
+}
+
+// EnrollMFA - a handler starting TOTP enrollment; the secret and recovery codes are only shown this once
+func (h *Handler) EnrollMFA(w http.ResponseWriter, r *http.Request) {
+	userID := currentUserID(r.Context())
+	currentUser, err := h.Service.User.GetUser(r.Context(), userID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	enrollment, err := h.Service.Auth.EnrollMFA(r.Context(), userID, currentUser.Username)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	response := MFAEnrollmentResponse{
+		Secret:          enrollment.Secret,
+		ProvisioningURI: enrollment.ProvisioningURI,
+		RecoveryCodes:   enrollment.RecoveryCodes,
+	}
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		panic(err)
+	}
+}
+
+// VerifyMFA - a handler enabling a pending enrollment once a first code from the authenticator app is verified
+func (h *Handler) VerifyMFA(w http.ResponseWriter, r *http.Request) {
+	var codeReq MFACodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&codeReq); err != nil {
+		writeInvalidBody(w, r)
+		return
+	}
+
+	if err := validate.Struct(codeReq); err != nil {
+		writeValidationError(w, r, err)
+		return
+	}
+
+	if err := h.Service.Auth.ConfirmMFA(r.Context(), currentUserID(r.Context()), codeReq.Code); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DisableMFA - a handler turning two-factor authentication off, which needs a current code
+func (h *Handler) DisableMFA(w http.ResponseWriter, r *http.Request) {
+	var codeReq MFACodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&codeReq); err != nil {
+		writeInvalidBody(w, r)
+		return
+	}
+
+	if err := validate.Struct(codeReq); err != nil {
+		writeValidationError(w, r, err)
+		return
+	}
+
+	if err := h.Service.Auth.DisableMFA(r.Context(), currentUserID(r.Context()), codeReq.Code); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,66 @@
+package http_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/yuchida-tamu/git-workout-api/internal/totp"
+	transportHttp "github.com/yuchida-tamu/git-workout-api/internal/transport/http"
+)
+
+func TestMFAChallenge(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+
+	enrollment, err := s.auth.EnrollMFA(ctx, s.user.ID, s.user.Username)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, err := totp.CodeAt(enrollment.Secret, totp.Step(time.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.auth.ConfirmMFA(ctx, s.user.ID, code); err != nil {
+		t.Fatal(err)
+	}
+
+	login := s.login(t)
+	if !login.MFARequired || login.MFAToken == "" || login.Token != "" {
+		t.Fatalf("expected a challenge instead of tokens, got %+v", login)
+	}
+	challenge := func(token string, code string) int {
+		body, err := json.Marshal(transportHttp.MFAChallengeRequest{MFAToken: token, Code: code})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s.do(t, http.MethodPost, "/api/v1/auth/mfa/challenge", string(body), nil).Code
+	}
+
+	// the challenge is no access token
+	rec := s.do(t, http.MethodGet, "/api/v1/user/"+s.user.ID, "", map[string]string{"Authorization": "Bearer " + login.MFAToken})
+	requireStatus(t, rec, http.StatusUnauthorized)
+	// and an access token is no challenge
+	if status := challenge(s.token, enrollment.RecoveryCodes[0]); status != http.StatusUnauthorized {
+		t.Fatalf("expected an access token to be refused as a challenge, got %d", status)
+	}
+
+	// a mistyped code can be corrected with the same challenge
+	if status := challenge(login.MFAToken, "000000x"); status != http.StatusUnauthorized {
+		t.Fatalf("expected a wrong code to be refused, got %d", status)
+	}
+	if status := challenge(login.MFAToken, enrollment.RecoveryCodes[0]); status != http.StatusOK {
+		t.Fatalf("expected the challenge to complete the login, got %d", status)
+	}
+
+	// but the challenge completes one login only, even with another valid code
+	if status := challenge(login.MFAToken, enrollment.RecoveryCodes[1]); status != http.StatusUnauthorized {
+		t.Fatalf("expected a used challenge to be refused, got %d", status)
+	}
+	// which the refused challenge did not use up
+	if status := challenge(s.login(t).MFAToken, enrollment.RecoveryCodes[1]); status != http.StatusOK {
+		t.Fatalf("expected the code to be left for the next challenge, got %d", status)
+	}
+}
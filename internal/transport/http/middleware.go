@@ -2,13 +2,14 @@ package http
 
 import (
 	"context"
-	"fmt"
 	"net/http"
-	"os"
+	"regexp"
 	"time"
 
 	jwt "github.com/dgrijalva/jwt-go"
+	uuid "github.com/satori/go.uuid"
 	log "github.com/sirupsen/logrus"
+	"github.com/yuchida-tamu/git-workout-api/internal/auth"
 )
 
 // JSONMiddleware - a middleware function to set Http Header
@@ -20,12 +21,30 @@ func JSONMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// RequestIDMiddleware - tags every request with an id, the client's X-Request-ID when it sends a sane one,
+// which is echoed in the response and in problem details so that a failure can be found in the logs
+func RequestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get("X-Request-ID")
+		if !requestIDPattern.MatchString(requestID) {
+			requestID = uuid.NewV4().String()
+		}
+		w.Header().Set("X-Request-ID", requestID)
+
+		ctx := context.WithValue(r.Context(), "request_id", requestID)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,128}$`)
+
 func LoggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		log.WithFields(
 			log.Fields{
-				"method": r.Method,
-				"path":   r.URL.Path,
+				"method":     r.Method,
+				"path":       r.URL.Path,
+				"request_id": currentRequestID(r.Context()),
 			},
 		).Info("handled request")
 
@@ -43,8 +62,13 @@ func TimeoutMiddleware(next http.Handler) http.Handler {
 	})
 }
 
-func AddCurrentUserToContextMiddleware(next http.Handler) http.Handler {
+func (h *Handler) AddCurrentUserToContextMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if apiKey := RetrieveAPIKeyFromHeader(r); apiKey != "" {
+			h.addAPIKeyUserToContext(next, w, r, apiKey)
+			return
+		}
+
 		// retrieve user Id from jwt
 		t := RetrieveJWTTokenFromHeader(r)
 		if t == "" {
@@ -52,12 +76,7 @@ func AddCurrentUserToContextMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
-		token, err := jwt.Parse(t, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return "", fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-			}
-			return []byte(os.Getenv("SIGNING_SECRET")), nil
-		})
+		token, err := h.Keys.Parse(t)
 
 		if err != nil {
 			next.ServeHTTP(w, r)
@@ -66,18 +85,57 @@ func AddCurrentUserToContextMiddleware(next http.Handler) http.Handler {
 
 		tokenClaims, ok := token.Claims.(jwt.MapClaims)
 		if !ok || !token.Valid {
-			w.WriteHeader(http.StatusBadRequest)
+			writeStatus(w, r, http.StatusBadRequest, "not a valid token")
+			return
+		}
+
+		// other tokens, such as MFA challenges, never identify a user to the API
+		if !isAccessToken(tokenClaims) {
+			next.ServeHTTP(w, r)
 			return
 		}
 
 		userIdInToken := tokenClaims["userId"]
 		if userIdInToken == nil {
-			w.WriteHeader(http.StatusBadRequest)
+			writeStatus(w, r, http.StatusBadRequest, "not a valid token")
 			return
 		}
 
-		// add user id to the current context
+		// add user id, role, session id, client id and scopes to the current context
 		ctx := context.WithValue(r.Context(), "user_id", userIdInToken)
+		if roleInToken, ok := tokenClaims["role"].(string); ok {
+			ctx = context.WithValue(ctx, "user_role", roleInToken)
+		}
+		if sessionIdInToken, ok := tokenClaims["sid"].(string); ok {
+			ctx = context.WithValue(ctx, "session_id", sessionIdInToken)
+		}
+		// tokens issued to third-party apps name the app
+		clientIdInToken, _ := tokenClaims["client_id"].(string)
+		if clientIdInToken != "" {
+			ctx = context.WithValue(ctx, "client_id", clientIdInToken)
+		}
+		if scopeInToken, ok := tokenClaims["scope"].(string); ok {
+			ctx = context.WithValue(ctx, "scopes", auth.ParseScopes(scopeInToken))
+		} else if clientIdInToken == "" {
+			// own logins issued before tokens carried scopes
+			ctx = context.WithValue(ctx, "scopes", auth.AllScopes)
+		}
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+// addAPIKeyUserToContext - identifies the caller by an API key. The role is read from the store, as there is no
+// token to carry it, and the key id is added so handlers can tell key callers from logged in sessions.
+func (h *Handler) addAPIKeyUserToContext(next http.Handler, w http.ResponseWriter, r *http.Request, raw string) {
+	key, u, err := h.Service.Auth.AuthenticateAPIKey(r.Context(), raw)
+	if err != nil {
+		next.ServeHTTP(w, r)
+		return
+	}
+
+	ctx := context.WithValue(r.Context(), "user_id", u.ID)
+	ctx = context.WithValue(ctx, "user_role", string(u.Role))
+	ctx = context.WithValue(ctx, "api_key_id", key.ID)
+	ctx = context.WithValue(ctx, "scopes", key.Scopes)
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
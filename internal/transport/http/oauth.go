@@ -0,0 +1,465 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/gorilla/mux"
+	"github.com/yuchida-tamu/git-workout-api/internal/auth"
+)
+
+const oauthAccessTokenLifetime = time.Hour
+
+type PostOAuthClientRequest struct {
+	Name         string   `json:"name" validate:"required,max=100"`
+	RedirectURIs []string `json:"redirect_uris" validate:"required,min=1"`
+	Scopes       []string `json:"scopes" validate:"required,min=1"`
+	// Confidential clients, such as server side apps, get a secret; native and browser apps cannot keep one
+	Confidential bool `json:"confidential"`
+}
+
+type OAuthClientResponse struct {
+	ClientID     string    `json:"client_id"`
+	Name         string    `json:"name"`
+	RedirectURIs []string  `json:"redirect_uris"`
+	Scopes       []string  `json:"scopes"`
+	Confidential bool      `json:"confidential"`
+	DateCreated  time.Time `json:"date_created"`
+}
+
+type PostOAuthClientResponse struct {
+	OAuthClientResponse
+	// ClientSecret is only ever returned here, when the client is registered
+	ClientSecret string `json:"client_secret,omitempty"`
+}
+
+type AuthorizationRequestBody struct {
+	ClientID            string `json:"client_id" validate:"required"`
+	RedirectURI         string `json:"redirect_uri" validate:"required"`
+	Scope               string `json:"scope"`
+	State               string `json:"state"`
+	CodeChallenge       string `json:"code_challenge" validate:"required"`
+	CodeChallengeMethod string `json:"code_challenge_method" validate:"required"`
+	Approve             bool   `json:"approve"`
+}
+
+// ConsentResponse - what the consent screen shows the user before they approve or deny a request
+type ConsentResponse struct {
+	ClientID        string   `json:"client_id"`
+	ClientName      string   `json:"client_name"`
+	RedirectURI     string   `json:"redirect_uri"`
+	Scopes          []string `json:"scopes"`
+	ConsentRequired bool     `json:"consent_required"`
+}
+
+type AuthorizationResponse struct {
+	RedirectTo string `json:"redirect_to"`
+}
+
+type OAuthTokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+	Scope        string `json:"scope"`
+}
+
+type OAuthErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+// IntrospectionResponse - RFC 7662; an inactive token only reports that it is inactive
+type IntrospectionResponse struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	Username  string `json:"username,omitempty"`
+	Subject   string `json:"sub,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+}
+
+func writeOAuthError(w http.ResponseWriter, r *http.Request, err error) {
+	var oauthErr *auth.OAuthError
+	if !errors.As(err, &oauthErr) {
+		writeError(w, r, err)
+		return
+	}
+
+	status := http.StatusBadRequest
+	if oauthErr.Code == "invalid_client" {
+		w.Header().Set("WWW-Authenticate", `Basic realm="oauth"`)
+		status = http.StatusUnauthorized
+	}
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(OAuthErrorResponse{
+		Error:            oauthErr.Code,
+		ErrorDescription: oauthErr.Description,
+	}); err != nil {
+		log.Print(err)
+	}
+}
+
+func convertOAuthClientToResponse(client auth.OAuthClient) OAuthClientResponse {
+	scopes := make([]string, 0, len(client.Scopes))
+	for _, s := range client.Scopes {
+		scopes = append(scopes, string(s))
+	}
+
+	return OAuthClientResponse{
+		ClientID:     client.ID,
+		Name:         client.Name,
+		RedirectURIs: client.RedirectURIs,
+		Scopes:       scopes,
+		Confidential: client.Confidential(),
+		DateCreated:  client.DateCreated,
+	}
+}
+
+// PostOAuthClient - a handler registering a third-party app owned by the current user
+func (h *Handler) PostOAuthClient(w http.ResponseWriter, r *http.Request) {
+	var request PostOAuthClientRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeInvalidBody(w, r)
+		return
+	}
+
+	if err := validate.Struct(request); err != nil {
+		writeValidationError(w, r, err)
+		return
+	}
+
+	scopes := make([]auth.Scope, 0, len(request.Scopes))
+	for _, s := range request.Scopes {
+		scopes = append(scopes, auth.Scope(s))
+	}
+
+	secret, client, err := h.Service.Auth.RegisterOAuthClient(
+		r.Context(),
+		currentUserID(r.Context()),
+		request.Name,
+		request.RedirectURIs,
+		scopes,
+		request.Confidential,
+	)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(PostOAuthClientResponse{
+		OAuthClientResponse: convertOAuthClientToResponse(client),
+		ClientSecret:        secret,
+	}); err != nil {
+		panic(err)
+	}
+}
+
+// GetOAuthClients - a handler listing the apps the current user registered
+func (h *Handler) GetOAuthClients(w http.ResponseWriter, r *http.Request) {
+	clients, err := h.Service.Auth.GetOAuthClientsByOwner(r.Context(), currentUserID(r.Context()))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	response := make([]OAuthClientResponse, 0, len(clients))
+	for _, client := range clients {
+		response = append(response, convertOAuthClientToResponse(client))
+	}
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		panic(err)
+	}
+}
+
+// DeleteOAuthClient - a handler removing one of the current user's apps, which logs out all of its users
+func (h *Handler) DeleteOAuthClient(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	if id == "" {
+		writeStatus(w, r, http.StatusBadRequest, "the id is missing")
+		return
+	}
+
+	if err := h.Service.Auth.DeleteOAuthClient(r.Context(), currentUserID(r.Context()), id); err != nil {
+		log.Print(err)
+		writeStatus(w, r, http.StatusNotFound, "oauth client not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (body AuthorizationRequestBody) authorizationRequest() auth.AuthorizationRequest {
+	return auth.AuthorizationRequest{
+		ClientID:            body.ClientID,
+		RedirectURI:         body.RedirectURI,
+		Scopes:              auth.ParseScopes(body.Scope),
+		State:               body.State,
+		CodeChallenge:       body.CodeChallenge,
+		CodeChallengeMethod: body.CodeChallengeMethod,
+	}
+}
+
+// GetAuthorization - a handler for the consent screen. The app sent the user here with the parameters of an
+// authorization request, and the screen shows which app asks for which scopes.
+func (h *Handler) GetAuthorization(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("response_type") != "code" {
+		writeOAuthError(w, r, &auth.OAuthError{Code: "unsupported_response_type", Description: "only the code response type is supported"})
+		return
+	}
+
+	req := AuthorizationRequestBody{
+		ClientID:            query.Get("client_id"),
+		RedirectURI:         query.Get("redirect_uri"),
+		Scope:               query.Get("scope"),
+		State:               query.Get("state"),
+		CodeChallenge:       query.Get("code_challenge"),
+		CodeChallengeMethod: query.Get("code_challenge_method"),
+	}.authorizationRequest()
+
+	client, consentRequired, err := h.Service.Auth.ValidateAuthorizationRequest(r.Context(), currentUserID(r.Context()), &req)
+	if err != nil {
+		writeOAuthError(w, r, err)
+		return
+	}
+
+	scopes := make([]string, 0, len(req.Scopes))
+	for _, s := range req.Scopes {
+		scopes = append(scopes, string(s))
+	}
+
+	if err := json.NewEncoder(w).Encode(ConsentResponse{
+		ClientID:        client.ID,
+		ClientName:      client.Name,
+		RedirectURI:     req.RedirectURI,
+		Scopes:          scopes,
+		ConsentRequired: consentRequired,
+	}); err != nil {
+		panic(err)
+	}
+}
+
+// PostAuthorization - a handler recording the user's answer on the consent screen. It returns where to send
+// the user next: back to the app with a code when they approved, or with an access_denied error.
+func (h *Handler) PostAuthorization(w http.ResponseWriter, r *http.Request) {
+	var body AuthorizationRequestBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeInvalidBody(w, r)
+		return
+	}
+
+	if err := validate.Struct(body); err != nil {
+		writeOAuthError(w, r, &auth.OAuthError{Code: "invalid_request", Description: "missing authorization parameters"})
+		return
+	}
+
+	req := body.authorizationRequest()
+	params := url.Values{}
+	if body.Approve {
+		code, err := h.Service.Auth.Authorize(r.Context(), currentUserID(r.Context()), req)
+		if err != nil {
+			writeOAuthError(w, r, err)
+			return
+		}
+		params.Set("code", code)
+	} else {
+		// the redirect uri must be checked before the user is sent to it, even to deny
+		if _, _, err := h.Service.Auth.ValidateAuthorizationRequest(r.Context(), currentUserID(r.Context()), &req); err != nil {
+			writeOAuthError(w, r, err)
+			return
+		}
+		params.Set("error", "access_denied")
+	}
+	if req.State != "" {
+		params.Set("state", req.State)
+	}
+
+	redirectTo, err := url.Parse(req.RedirectURI)
+	if err != nil {
+		writeOAuthError(w, r, &auth.OAuthError{Code: "invalid_request", Description: "not a valid redirect uri"})
+		return
+	}
+	query := redirectTo.Query()
+	for key := range params {
+		query.Set(key, params.Get(key))
+	}
+	redirectTo.RawQuery = query.Encode()
+
+	if err := json.NewEncoder(w).Encode(AuthorizationResponse{RedirectTo: redirectTo.String()}); err != nil {
+		panic(err)
+	}
+}
+
+// authenticateOAuthClient - reads the client's credentials from HTTP basic auth or the form, as RFC 6749
+// section 2.3.1 allows, and checks them
+func (h *Handler) authenticateOAuthClient(r *http.Request) (auth.OAuthClient, error) {
+	clientID, secret, ok := r.BasicAuth()
+	if ok {
+		// basic auth credentials are form encoded before they are base64 encoded
+		var err error
+		if clientID, err = url.QueryUnescape(clientID); err != nil {
+			return auth.OAuthClient{}, &auth.OAuthError{Code: "invalid_client", Description: "client authentication failed"}
+		}
+		if secret, err = url.QueryUnescape(secret); err != nil {
+			return auth.OAuthClient{}, &auth.OAuthError{Code: "invalid_client", Description: "client authentication failed"}
+		}
+	} else {
+		clientID = r.PostFormValue("client_id")
+		secret = r.PostFormValue("client_secret")
+	}
+
+	return h.Service.Auth.AuthenticateOAuthClient(r.Context(), clientID, secret)
+}
+
+// OAuthToken - the token endpoint (RFC 6749, section 3.2) for the authorization_code and refresh_token grants.
+// Like every OAuth2 endpoint used by the apps themselves it takes a form encoded body.
+func (h *Handler) OAuthToken(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	if err := r.ParseForm(); err != nil {
+		writeOAuthError(w, r, &auth.OAuthError{Code: "invalid_request", Description: "not a valid form"})
+		return
+	}
+
+	client, err := h.authenticateOAuthClient(r)
+	if err != nil {
+		writeOAuthError(w, r, err)
+		return
+	}
+
+	var session auth.Session
+	var refreshToken string
+	switch r.PostForm.Get("grant_type") {
+	case "authorization_code":
+		session, refreshToken, err = h.Service.Auth.ExchangeAuthorizationCode(
+			r.Context(),
+			client,
+			r.PostForm.Get("code"),
+			r.PostForm.Get("redirect_uri"),
+			r.PostForm.Get("code_verifier"),
+			clientInfoFromRequest(r, ""),
+		)
+	case "refresh_token":
+		session, refreshToken, err = h.Service.Auth.RefreshOAuthToken(
+			r.Context(),
+			client,
+			r.PostForm.Get("refresh_token"),
+			clientInfoFromRequest(r, ""),
+		)
+	default:
+		err = &auth.OAuthError{Code: "unsupported_grant_type", Description: "only authorization_code and refresh_token are supported"}
+	}
+	if err != nil {
+		writeOAuthError(w, r, err)
+		return
+	}
+
+	u, err := h.Service.User.GetUser(r.Context(), session.UserID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	accessToken, err := h.generateOAuthAccessToken(u, session)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(OAuthTokenResponse{
+		AccessToken:  accessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(oauthAccessTokenLifetime.Seconds()),
+		RefreshToken: refreshToken,
+		Scope:        auth.FormatScopes(session.Scopes),
+	}); err != nil {
+		panic(err)
+	}
+}
+
+// oauthTokenSession - finds the session of an access or refresh token the client holds
+func (h *Handler) oauthTokenSession(r *http.Request, client auth.OAuthClient, token string) (auth.Session, jwt.MapClaims, error) {
+	if parsed, err := h.Keys.Parse(token); err == nil && parsed.Valid {
+		claims, ok := parsed.Claims.(jwt.MapClaims)
+		sessionID, _ := claims["sid"].(string)
+		if !ok || sessionID == "" {
+			return auth.Session{}, nil, auth.ErrSessionNotFound
+		}
+		session, err := h.Service.Auth.GetOAuthTokenSession(r.Context(), client, sessionID, "")
+		return session, claims, err
+	}
+
+	session, err := h.Service.Auth.GetOAuthTokenSession(r.Context(), client, "", token)
+	return session, nil, err
+}
+
+// IntrospectOAuthToken - token introspection (RFC 7662). Clients may only introspect their own tokens.
+func (h *Handler) IntrospectOAuthToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeOAuthError(w, r, &auth.OAuthError{Code: "invalid_request", Description: "not a valid form"})
+		return
+	}
+
+	client, err := h.authenticateOAuthClient(r)
+	if err != nil {
+		writeOAuthError(w, r, err)
+		return
+	}
+
+	response := IntrospectionResponse{Active: false}
+	session, claims, err := h.oauthTokenSession(r, client, r.PostForm.Get("token"))
+	if err == nil {
+		response = IntrospectionResponse{
+			Active:    true,
+			Scope:     auth.FormatScopes(session.Scopes),
+			ClientID:  session.ClientID,
+			Subject:   session.UserID,
+			TokenType: "refresh_token",
+		}
+		if claims != nil {
+			response.TokenType = "access_token"
+			response.Username, _ = claims["username"].(string)
+			if exp, ok := claims["exp"].(float64); ok {
+				response.ExpiresAt = int64(exp)
+			}
+		}
+	}
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		panic(err)
+	}
+}
+
+// RevokeOAuthToken - token revocation (RFC 7009). Revoking either token ends the whole grant, and unknown
+// tokens are answered like revoked ones.
+func (h *Handler) RevokeOAuthToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeOAuthError(w, r, &auth.OAuthError{Code: "invalid_request", Description: "not a valid form"})
+		return
+	}
+
+	client, err := h.authenticateOAuthClient(r)
+	if err != nil {
+		writeOAuthError(w, r, err)
+		return
+	}
+
+	session, _, err := h.oauthTokenSession(r, client, r.PostForm.Get("token"))
+	if err == nil {
+		if err := h.Service.Auth.RevokeOAuthSession(r.Context(), client, session.ID); err != nil {
+			writeError(w, r, err)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
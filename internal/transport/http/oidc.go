@@ -0,0 +1,165 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/yuchida-tamu/git-workout-api/internal/auth"
+)
+
+// oidcStateCookie - holds the hash of the state of the login the browser started, which the callback has to
+// come with; it is only sent to the OIDC routes
+const (
+	oidcStateCookie     = "oidc_state"
+	oidcStateCookiePath = "/api/v1/auth/oidc"
+)
+
+type OIDCLoginResponse struct {
+	AuthorizationURL string `json:"authorization_url"`
+}
+
+type IdentityResponse struct {
+	ID          string    `json:"id"`
+	Provider    string    `json:"provider"`
+	Email       string    `json:"email"`
+	DateCreated time.Time `json:"date_created"`
+}
+
+// GetOIDCProviders - a handler listing the providers users can sign in with
+func (h *Handler) GetOIDCProviders(w http.ResponseWriter, r *http.Request) {
+	if err := json.NewEncoder(w).Encode(h.Service.Auth.ProviderNames()); err != nil {
+		panic(err)
+	}
+}
+
+// BeginOIDCLogin - a handler starting a login with a provider; the client sends the user to the returned url
+func (h *Handler) BeginOIDCLogin(w http.ResponseWriter, r *http.Request) {
+	h.beginOIDCLogin(w, r, "")
+}
+
+// BeginOIDCLink - a handler starting a login which links the provider to the current user's account
+func (h *Handler) BeginOIDCLink(w http.ResponseWriter, r *http.Request) {
+	h.beginOIDCLogin(w, r, currentUserID(r.Context()))
+}
+
+func (h *Handler) beginOIDCLogin(w http.ResponseWriter, r *http.Request, linkUserID string) {
+	vars := mux.Vars(r)
+	authURL, binding, err := h.Service.Auth.BeginOIDCLogin(r.Context(), vars["provider"], linkUserID)
+	if errors.Is(err, auth.ErrUnknownProvider) {
+		writeStatus(w, r, http.StatusNotFound, "unknown identity provider")
+		return
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	// Lax, so that the provider redirecting the browser back to the callback sends it along
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    binding,
+		Path:     oidcStateCookiePath,
+		MaxAge:   int(auth.OIDCLoginLifetime.Seconds()),
+		HttpOnly: true,
+		Secure:   isHTTPS(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	if err := json.NewEncoder(w).Encode(OIDCLoginResponse{AuthorizationURL: authURL}); err != nil {
+		panic(err)
+	}
+}
+
+// OIDCCallback - a handler the provider redirects back to with a code, logging the user in like AuthUser.
+// The optional device query parameter names the session, as the device field does for AuthUser.
+func (h *Handler) OIDCCallback(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	query := r.URL.Query()
+	if query.Get("error") != "" {
+		writeInvalidCredentials(w, r, "the identity provider refused the login")
+		return
+	}
+	if query.Get("code") == "" || query.Get("state") == "" {
+		writeStatus(w, r, http.StatusBadRequest, "not a valid input")
+		return
+	}
+
+	// the state cookie is only good for one callback
+	var binding string
+	if cookie, err := r.Cookie(oidcStateCookie); err == nil {
+		binding = cookie.Value
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Path:     oidcStateCookiePath,
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   isHTTPS(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	u, err := h.Service.Auth.CompleteOIDCLogin(r.Context(), vars["provider"], query.Get("code"), query.Get("state"), binding)
+	switch {
+	case errors.Is(err, auth.ErrUnknownProvider):
+		writeStatus(w, r, http.StatusNotFound, "unknown identity provider")
+		return
+	case errors.Is(err, auth.ErrIdentityLinked):
+		writeStatus(w, r, http.StatusConflict, err.Error())
+		return
+	case err != nil:
+		log.Print(err)
+		writeInvalidCredentials(w, r, "the login could not be completed")
+		return
+	}
+
+	h.writeLoginResponse(w, r, u, query.Get("device"))
+}
+
+// GetIdentities - a handler listing the providers linked to the current user
+func (h *Handler) GetIdentities(w http.ResponseWriter, r *http.Request) {
+	identities, err := h.Service.Auth.GetIdentitiesByUser(r.Context(), currentUserID(r.Context()))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	response := make([]IdentityResponse, 0, len(identities))
+	for _, identity := range identities {
+		response = append(response, IdentityResponse{
+			ID:          identity.ID,
+			Provider:    identity.Provider,
+			Email:       identity.Email,
+			DateCreated: identity.DateCreated,
+		})
+	}
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		panic(err)
+	}
+}
+
+// DeleteIdentity - a handler unlinking a provider from the current user
+func (h *Handler) DeleteIdentity(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	if id == "" {
+		writeStatus(w, r, http.StatusBadRequest, "the id is missing")
+		return
+	}
+
+	if err := h.Service.Auth.UnlinkIdentity(r.Context(), currentUserID(r.Context()), id); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// isHTTPS - whether the client reached the API over https, directly or through a proxy terminating TLS
+func isHTTPS(r *http.Request) bool {
+	return r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https"
+}
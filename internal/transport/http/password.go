@@ -0,0 +1,89 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/yuchida-tamu/git-workout-api/internal/auth"
+	"github.com/yuchida-tamu/git-workout-api/internal/password"
+)
+
+type ForgotPasswordRequest struct {
+	Username string `json:"username" validate:"required"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required"`
+}
+
+// ForgotPassword - a handler mailing a password reset token. It answers the same way whether or not
+// the account exists.
+func (h *Handler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var forgotReq ForgotPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&forgotReq); err != nil {
+		writeInvalidBody(w, r)
+		return
+	}
+
+	if err := validate.Struct(forgotReq); err != nil {
+		writeValidationError(w, r, err)
+		return
+	}
+
+	if err := h.Service.Auth.RequestPasswordReset(r.Context(), forgotReq.Username); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// ResetPassword - a handler setting a new password with a token from ForgotPassword
+func (h *Handler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var resetReq ResetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&resetReq); err != nil {
+		writeInvalidBody(w, r)
+		return
+	}
+
+	if err := validate.Struct(resetReq); err != nil {
+		writeValidationError(w, r, err)
+		return
+	}
+
+	if err := h.Service.Auth.ResetPassword(r.Context(), resetReq.Token, resetReq.Password); err != nil {
+		log.Print(err)
+		if writePasswordPolicyError(w, r, err) {
+			return
+		}
+		if errors.Is(err, auth.ErrInvalidOneTimeToken) {
+			writeStatus(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeStatus(w, r, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writePasswordPolicyError - answers 400 listing every broken rule when err is a password policy violation,
+// and reports whether it did
+func writePasswordPolicyError(w http.ResponseWriter, r *http.Request, err error) bool {
+	var policyErr *password.PolicyError
+	if !errors.As(err, &policyErr) {
+		return false
+	}
+
+	p := newProblem(http.StatusBadRequest, "the password does not meet the password policy")
+	p.Type = problemTypeWeakPassword
+	p.ErrorCode = errorCodeWeakPassword
+	for _, problem := range policyErr.Problems {
+		p.Errors = append(p.Errors, FieldError{Field: "password", Rule: "policy", Detail: problem})
+	}
+	writeProblem(w, r, p)
+	return true
+}
@@ -0,0 +1,170 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// problem types of RFC 7807; a problem which is fully described by its status uses about:blank
+const (
+	problemTypeBlank              = "about:blank"
+	problemTypeValidation         = "urn:git-workout:problem:validation-error"
+	problemTypeInvalidCredentials = "urn:git-workout:problem:invalid-credentials"
+	problemTypeTooManyAttempts    = "urn:git-workout:problem:too-many-attempts"
+	problemTypeTokenExpired       = "urn:git-workout:problem:token-expired"
+	problemTypeWeakPassword       = "urn:git-workout:problem:weak-password"
+	problemTypeInsufficientScope  = "urn:git-workout:problem:insufficient-scope"
+)
+
+// error codes of the responses before problem details, kept as an extension member for clients which still
+// switch on them
+const (
+	errorCodeInvalidCredentials = -1000
+	errorCodeTooManyAttempts    = -1001
+	errorCodeTokenExpired       = -2000
+	errorCodeWeakPassword       = -3000
+)
+
+// Problem - an RFC 7807 problem details body, which every error response of the API is
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	RequestID string       `json:"request_id,omitempty"`
+	ErrorCode int          `json:"error_code,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError - one field of the request body which failed validation
+type FieldError struct {
+	Field  string `json:"field"`
+	Rule   string `json:"rule"`
+	Detail string `json:"detail"`
+}
+
+func newProblem(status int, detail string) Problem {
+	return Problem{
+		Type:   problemTypeBlank,
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	}
+}
+
+// writeProblem - writes p as application/problem+json, tagged with the request it answers
+func writeProblem(w http.ResponseWriter, r *http.Request, p Problem) {
+	if p.Type == "" {
+		p.Type = problemTypeBlank
+	}
+	if p.Title == "" {
+		p.Title = http.StatusText(p.Status)
+	}
+	p.Instance = r.URL.Path
+	p.RequestID = currentRequestID(r.Context())
+
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(p.Status)
+	if err := json.NewEncoder(w).Encode(p); err != nil {
+		log.Print(err)
+	}
+}
+
+// writeStatus - a problem which says no more than its status and detail
+func writeStatus(w http.ResponseWriter, r *http.Request, status int, detail string) {
+	writeProblem(w, r, newProblem(status, detail))
+}
+
+// writeInvalidBody - the request body could not be decoded
+func writeInvalidBody(w http.ResponseWriter, r *http.Request) {
+	writeStatus(w, r, http.StatusBadRequest, "the request body is not valid JSON")
+}
+
+// validate - checks request bodies; field errors name fields the way the JSON does
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+		switch name {
+		case "-":
+			return ""
+		case "":
+			return field.Name
+		}
+		return name
+	})
+	return v
+}
+
+// writeValidationError - answers 400 listing every field of the body which failed validation
+func writeValidationError(w http.ResponseWriter, r *http.Request, err error) {
+	p := newProblem(http.StatusBadRequest, "the request body failed validation")
+	p.Type = problemTypeValidation
+
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		log.Print(err)
+		writeProblem(w, r, p)
+		return
+	}
+
+	for _, fe := range validationErrs {
+		// the namespace starts with the name of the request struct, which means nothing to the client
+		field := fe.Namespace()
+		if i := strings.Index(field, "."); i >= 0 {
+			field = field[i+1:]
+		}
+		p.Errors = append(p.Errors, FieldError{
+			Field:  field,
+			Rule:   fe.Tag(),
+			Detail: describeFieldError(fe),
+		})
+	}
+	writeProblem(w, r, p)
+}
+
+func describeFieldError(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be an email address"
+	case "uuid":
+		return "must be a uuid"
+	case "oneof":
+		return fmt.Sprintf("must be one of: %s", fe.Param())
+	case "min":
+		if fe.Kind() == reflect.String {
+			return fmt.Sprintf("must be at least %s characters long", fe.Param())
+		}
+		return fmt.Sprintf("must have at least %s items", fe.Param())
+	case "max":
+		if fe.Kind() == reflect.String {
+			return fmt.Sprintf("must be at most %s characters long", fe.Param())
+		}
+		return fmt.Sprintf("must have at most %s items", fe.Param())
+	default:
+		return fmt.Sprintf("fails the %s rule", fe.Tag())
+	}
+}
+
+// NotFoundHandler - answers requests to paths without a route
+func NotFoundHandler(w http.ResponseWriter, r *http.Request) {
+	writeStatus(w, r, http.StatusNotFound, "no route matches the path")
+}
+
+// MethodNotAllowedHandler - answers requests with a method the path's routes do not accept
+func MethodNotAllowedHandler(w http.ResponseWriter, r *http.Request) {
+	writeStatus(w, r, http.StatusMethodNotAllowed, "the path does not accept the method")
+}
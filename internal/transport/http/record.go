@@ -3,12 +3,11 @@ package http
 import (
 	"context"
 	"encoding/json"
-	"log"
 	"net/http"
 	"time"
 
-	"github.com/go-playground/validator/v10"
 	"github.com/gorilla/mux"
+	"github.com/yuchida-tamu/git-workout-api/internal/coach"
 	"github.com/yuchida-tamu/git-workout-api/internal/record"
 )
 
@@ -27,56 +26,150 @@ func convertPostRecordRequestToRecord(r PostRecordRequest) record.Record {
 	}
 }
 
+// ImportRecordsRequest - records kept elsewhere, brought over at once; they keep their dates, records
+// without one are dated today
+type ImportRecordsRequest struct {
+	Records []ImportRecord `json:"records" validate:"required,min=1,max=1000,dive"`
+}
+
+type ImportRecord struct {
+	DateCreated string `json:"date_created" validate:"omitempty,datetime=2006-01-02"`
+	MessageBody string `json:"message_body" validate:"required"`
+}
+
 type RecordService interface {
 	GetRecordsByAuthor(context.Context, string) ([]record.Record, error)
 	GetRecordById(context.Context, string) (record.Record, error)
 	PostRecord(context.Context, record.Record) (record.Record, error)
 	UpdateRecord(ctx context.Context, ID string, rcd record.Record) (record.Record, error)
-	DeleteRecord(context.Context, string) error
+	DeleteRecord(ctx context.Context, ID string, version int) error
+	ImportRecords(ctx context.Context, author string, rcds []record.Record) ([]record.Record, error)
+	GetCommentsByRecord(ctx context.Context, recordID string) ([]record.Comment, error)
+	PostComment(context.Context, record.Comment) (record.Comment, error)
+}
+
+type PostCommentRequest struct {
+	MessageBody string `json:"message_body" validate:"required"`
+}
+
+type CommentResponse struct {
+	ID          string    `json:"id"`
+	RecordID    string    `json:"record_id"`
+	Author      string    `json:"author"`
+	MessageBody string    `json:"message_body"`
+	DateCreated time.Time `json:"date_created"`
+}
+
+func convertCommentToResponse(c record.Comment) CommentResponse {
+	return CommentResponse{
+		ID:          c.ID,
+		RecordID:    c.RecordID,
+		Author:      c.Author,
+		MessageBody: c.MessageBody,
+		DateCreated: c.DateCreated,
+	}
 }
 
 func (h *Handler) PostRecord(w http.ResponseWriter, r *http.Request) {
 	var record PostRecordRequest
 	if err := json.NewDecoder(r.Body).Decode(&record); err != nil {
+		writeInvalidBody(w, r)
 		return
 	}
 
-	validate := validator.New()
 	err := validate.Struct(record)
 	if err != nil {
-		http.Error(w, "not a valid record", http.StatusBadRequest)
+		writeValidationError(w, r, err)
+		return
+	}
+
+	// records can only be posted on the current user's own behalf
+	if hasAccess := checkUserHasAccess(r.Context(), record.Author); !hasAccess {
+		writeStatus(w, r, http.StatusForbidden, "forbidden")
 		return
 	}
 
 	postedRecord, err := h.Service.Record.PostRecord(r.Context(), convertPostRecordRequestToRecord(record))
 	if err != nil {
-		log.Print(err)
+		writeError(w, r, err)
 		return
 	}
 
+	w.Header().Set("ETag", formatETag(postedRecord.Version))
 	if err := json.NewEncoder(w).Encode(postedRecord); err != nil {
 		panic(err)
 	}
 }
 
-func (h *Handler) GetRecordByAuthor(w http.ResponseWriter, r *http.Request) {
+// ImportRecords - a handler posting many records of the current user at once; either all of them are
+// imported or, when one fails, none is
+func (h *Handler) ImportRecords(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
 	if id == "" {
-		w.WriteHeader(http.StatusBadRequest)
+		writeStatus(w, r, http.StatusBadRequest, "the id is missing")
 		return
 	}
 
-	// validate userId and currentId in the context match
+	// records can only be imported on the current user's own behalf
 	if hasAccess := checkUserHasAccess(r.Context(), id); !hasAccess {
-		w.WriteHeader(http.StatusBadRequest)
+		writeStatus(w, r, http.StatusForbidden, "forbidden")
+		return
+	}
+
+	var request ImportRecordsRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeInvalidBody(w, r)
+		return
+	}
+
+	if err := validate.Struct(request); err != nil {
+		writeValidationError(w, r, err)
+		return
+	}
+
+	today := time.Now().Format("2006-01-02")
+	rcds := make([]record.Record, 0, len(request.Records))
+	for _, imported := range request.Records {
+		rcd := record.Record{
+			DateCreated: imported.DateCreated,
+			MessageBody: imported.MessageBody,
+		}
+		if rcd.DateCreated == "" {
+			rcd.DateCreated = today
+		}
+		rcds = append(rcds, rcd)
+	}
+
+	imported, err := h.Service.Record.ImportRecords(r.Context(), id, rcds)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(imported); err != nil {
+		panic(err)
+	}
+}
+
+func (h *Handler) GetRecordByAuthor(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	if id == "" {
+		writeStatus(w, r, http.StatusBadRequest, "the id is missing")
+		return
+	}
+
+	// the author themselves or a coach allowed to read their records
+	if hasAccess := h.checkUserCanAccessAuthor(r.Context(), id, coach.ScopeReadRecords); !hasAccess {
+		writeStatus(w, r, http.StatusForbidden, "forbidden")
 		return
 	}
 
 	records, err := h.Service.Record.GetRecordsByAuthor(r.Context(), id)
 	if err != nil {
-		log.Print(err)
-		w.WriteHeader(http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
@@ -89,17 +182,24 @@ func (h *Handler) GetRecordById(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
 	if id == "" {
-		w.WriteHeader(http.StatusBadRequest)
+		writeStatus(w, r, http.StatusBadRequest, "the id is missing")
 		return
 	}
 
 	record, err := h.Service.Record.GetRecordById(r.Context(), id)
 	if err != nil {
-		log.Print(err)
-		w.WriteHeader(http.StatusInternalServerError)
+		writeError(w, r, err)
+		return
+	}
+
+	if hasAccess := h.checkUserCanAccessAuthor(r.Context(), record.Author, coach.ScopeReadRecords); !hasAccess {
+		writeStatus(w, r, http.StatusForbidden, "forbidden")
 		return
 	}
 
+	if writeNotModified(w, r, record.Version) {
+		return
+	}
 	if err := json.NewEncoder(w).Encode(record); err != nil {
 		panic(err)
 	}
@@ -109,35 +209,46 @@ func (h *Handler) UpdateRecord(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
 	if id == "" {
-		w.WriteHeader(http.StatusBadRequest)
+		writeStatus(w, r, http.StatusBadRequest, "the id is missing")
 		return
 	}
 
-	// validate userId and currentId in the context match
-	if hasAccess := checkUserHasAccess(r.Context(), id); !hasAccess {
-		w.WriteHeader(http.StatusBadRequest)
+	// only the author may change a record
+	existing, err := h.Service.Record.GetRecordById(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if hasAccess := checkUserHasAccess(r.Context(), existing.Author); !hasAccess {
+		writeStatus(w, r, http.StatusForbidden, "forbidden")
+		return
+	}
+	version, ok := checkIfMatch(w, r, existing.Version)
+	if !ok {
 		return
 	}
 
 	var record record.Record
 	if err := json.NewDecoder(r.Body).Decode(&record); err != nil {
+		writeInvalidBody(w, r)
 		return
 	}
+	record.Author = existing.Author
+	record.Version = version
 
-	validate := validator.New()
-	err := validate.Struct(record)
+	err = validate.Struct(record)
 	if err != nil {
-		http.Error(w, "not a valid record", http.StatusBadRequest)
+		writeValidationError(w, r, err)
 		return
 	}
 
 	record, err = h.Service.Record.UpdateRecord(r.Context(), id, record)
 	if err != nil {
-		log.Print(err)
-		w.WriteHeader(http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
+	w.Header().Set("ETag", formatETag(record.Version))
 	if err := json.NewEncoder(w).Encode(record); err != nil {
 		panic(err)
 	}
@@ -147,20 +258,28 @@ func (h *Handler) DeleteRecord(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
 	if id == "" {
-		w.WriteHeader(http.StatusBadRequest)
+		writeStatus(w, r, http.StatusBadRequest, "the id is missing")
 		return
 	}
 
-	// validate userId and currentId in the context match
-	if hasAccess := checkUserHasAccess(r.Context(), id); !hasAccess {
-		w.WriteHeader(http.StatusBadRequest)
+	// only the author may delete a record
+	existing, err := h.Service.Record.GetRecordById(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if hasAccess := checkUserHasAccess(r.Context(), existing.Author); !hasAccess {
+		writeStatus(w, r, http.StatusForbidden, "forbidden")
+		return
+	}
+	version, ok := checkIfMatch(w, r, existing.Version)
+	if !ok {
 		return
 	}
 
-	err := h.Service.Record.DeleteRecord(r.Context(), id)
+	err = h.Service.Record.DeleteRecord(r.Context(), id, version)
 	if err != nil {
-		log.Print(err)
-		w.WriteHeader(http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
@@ -168,3 +287,86 @@ func (h *Handler) DeleteRecord(w http.ResponseWriter, r *http.Request) {
 		panic(err)
 	}
 }
+
+// GetCommentsByRecord - a handler listing the comments on a record for its author and coaches allowed to read it
+func (h *Handler) GetCommentsByRecord(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	if id == "" {
+		writeStatus(w, r, http.StatusBadRequest, "the id is missing")
+		return
+	}
+
+	rcd, err := h.Service.Record.GetRecordById(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	if hasAccess := h.checkUserCanAccessAuthor(r.Context(), rcd.Author, coach.ScopeReadRecords); !hasAccess {
+		writeStatus(w, r, http.StatusForbidden, "forbidden")
+		return
+	}
+
+	comments, err := h.Service.Record.GetCommentsByRecord(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	response := make([]CommentResponse, 0, len(comments))
+	for _, c := range comments {
+		response = append(response, convertCommentToResponse(c))
+	}
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		panic(err)
+	}
+}
+
+// PostComment - a handler for the author, or a coach granted the comment scope, to annotate a record
+func (h *Handler) PostComment(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	if id == "" {
+		writeStatus(w, r, http.StatusBadRequest, "the id is missing")
+		return
+	}
+
+	var commentReq PostCommentRequest
+	if err := json.NewDecoder(r.Body).Decode(&commentReq); err != nil {
+		writeInvalidBody(w, r)
+		return
+	}
+
+	if err := validate.Struct(commentReq); err != nil {
+		writeValidationError(w, r, err)
+		return
+	}
+
+	rcd, err := h.Service.Record.GetRecordById(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	if hasAccess := h.checkUserCanAccessAuthor(r.Context(), rcd.Author, coach.ScopeComment); !hasAccess {
+		writeStatus(w, r, http.StatusForbidden, "forbidden")
+		return
+	}
+
+	comment, err := h.Service.Record.PostComment(r.Context(), record.Comment{
+		RecordID:    id,
+		Author:      currentUserID(r.Context()),
+		MessageBody: commentReq.MessageBody,
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(convertCommentToResponse(comment)); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,131 @@
+package http_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/yuchida-tamu/git-workout-api/internal/memstore"
+	"github.com/yuchida-tamu/git-workout-api/internal/record"
+	"github.com/yuchida-tamu/git-workout-api/internal/user"
+)
+
+// failingWrites - a memory store whose writes fail once writesLeft of them have gone through; while it is
+// negative every write goes through
+type failingWrites struct {
+	*memstore.Store
+	writesLeft int
+}
+
+func (s *failingWrites) write() error {
+	if s.writesLeft == 0 {
+		return errDatabaseDown
+	}
+	if s.writesLeft > 0 {
+		s.writesLeft--
+	}
+	return nil
+}
+
+func (s *failingWrites) PostRecord(ctx context.Context, rcd record.Record) (record.Record, error) {
+	if err := s.write(); err != nil {
+		return record.Record{}, err
+	}
+	return s.Store.PostRecord(ctx, rcd)
+}
+
+func (s *failingWrites) PostAuditEvent(ctx context.Context, event user.AuditEvent) (user.AuditEvent, error) {
+	if err := s.write(); err != nil {
+		return user.AuditEvent{}, err
+	}
+	return s.Store.PostAuditEvent(ctx, event)
+}
+
+func TestImportRecords(t *testing.T) {
+	ctx := context.Background()
+	s := newTestServer(t)
+	path := "/api/v1/record/author/" + s.user.ID + "/import"
+
+	rec := s.do(t, http.MethodPost, path, `{"records": [
+		{"date_created": "2026-01-05", "message_body": "squat 5x5"},
+		{"message_body": "bench 5x5"}
+	]}`, nil)
+	requireStatus(t, rec, http.StatusCreated)
+	var imported []record.Record
+	if err := json.NewDecoder(rec.Body).Decode(&imported); err != nil {
+		t.Fatal(err)
+	}
+	if len(imported) != 2 || imported[0].DateCreated != "2026-01-05" || imported[1].DateCreated == "" {
+		t.Fatalf("unexpected imported records: %+v", imported)
+	}
+	for _, rcd := range imported {
+		if rcd.Author != s.user.ID || rcd.ID == "" {
+			t.Fatalf("unexpected imported record: %+v", rcd)
+		}
+	}
+
+	// one invalid record keeps all of them out
+	rec = s.do(t, http.MethodPost, path, `{"records": [
+		{"message_body": "deadlift 3x5"},
+		{"date_created": "5th of January", "message_body": "row 4x8"}
+	]}`, nil)
+	requireStatus(t, rec, http.StatusBadRequest)
+
+	// records are only imported for the current user
+	other, err := s.store.PostUser(ctx, user.User{Username: "bob", Email: "bob@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec = s.do(t, http.MethodPost, "/api/v1/record/author/"+other.ID+"/import", `{"records": [{"message_body": "curl 3x10"}]}`, nil)
+	requireStatus(t, rec, http.StatusForbidden)
+
+	records, err := s.store.GetRecordsByAuthor(ctx, s.user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected only the first import to be kept, got %d records", len(records))
+	}
+}
+
+func TestTransactionsRollBack(t *testing.T) {
+	ctx := context.Background()
+	mem := memstore.NewStore()
+	store := &failingWrites{Store: mem, writesLeft: -1}
+	s := newTestServerWith(t, mem, store)
+
+	t.Run("ImportRecords", func(t *testing.T) {
+		// the third of three records fails to be stored
+		store.writesLeft = 2
+		rec := s.do(t, http.MethodPost, "/api/v1/record/author/"+s.user.ID+"/import", `{"records": [
+			{"message_body": "squat 5x5"},
+			{"message_body": "bench 5x5"},
+			{"message_body": "deadlift 1x5"}
+		]}`, nil)
+		requireStatus(t, rec, http.StatusInternalServerError)
+
+		records, err := mem.GetRecordsByAuthor(ctx, s.user.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(records) != 0 {
+			t.Fatalf("expected the records stored before the failure to be rolled back, got %+v", records)
+		}
+	})
+
+	t.Run("ScheduleUserDeletion", func(t *testing.T) {
+		// the deletion is marked, but its audit event fails to be stored
+		store.writesLeft = 0
+		rec := s.do(t, http.MethodDelete, "/api/v1/user/"+s.user.ID, "", nil)
+		requireStatus(t, rec, http.StatusInternalServerError)
+
+		u, err := mem.GetUser(ctx, s.user.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !u.DeleteAfter.IsZero() {
+			t.Fatal("expected the deletion to be rolled back with its audit event")
+		}
+	})
+}
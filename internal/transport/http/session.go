@@ -0,0 +1,83 @@
+package http
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/yuchida-tamu/git-workout-api/internal/auth"
+)
+
+type SessionResponse struct {
+	ID string `json:"id"`
+	// ClientID names the third-party app a session was granted to
+	ClientID    string    `json:"client_id,omitempty"`
+	Device      string    `json:"device"`
+	IP          string    `json:"ip"`
+	UserAgent   string    `json:"user_agent"`
+	Current     bool      `json:"current"`
+	LastUsed    time.Time `json:"last_used"`
+	DateCreated time.Time `json:"date_created"`
+}
+
+// clientInfoFromRequest - collects the client details stored on a session.
+// The IP is taken from the connection, not from forwarding headers which the client controls.
+func clientInfoFromRequest(r *http.Request, device string) auth.ClientInfo {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+
+	return auth.ClientInfo{
+		Device:    device,
+		IP:        ip,
+		UserAgent: r.UserAgent(),
+	}
+}
+
+// GetSessions - a handler listing where the current user is logged in
+func (h *Handler) GetSessions(w http.ResponseWriter, r *http.Request) {
+	sessions, err := h.Service.Auth.GetSessionsByUser(r.Context(), currentUserID(r.Context()))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	currentSessionId := currentSessionID(r.Context())
+	response := make([]SessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		response = append(response, SessionResponse{
+			ID:          session.ID,
+			ClientID:    session.ClientID,
+			Device:      session.Device,
+			IP:          session.IP,
+			UserAgent:   session.UserAgent,
+			Current:     session.ID == currentSessionId,
+			LastUsed:    session.LastUsed,
+			DateCreated: session.DateCreated,
+		})
+	}
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		panic(err)
+	}
+}
+
+// DeleteSession - a handler revoking one of the current user's sessions, e.g. on a lost device
+func (h *Handler) DeleteSession(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+	if id == "" {
+		writeStatus(w, r, http.StatusBadRequest, "the id is missing")
+		return
+	}
+
+	if err := h.Service.Auth.RevokeSession(r.Context(), currentUserID(r.Context()), id); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
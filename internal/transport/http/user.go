@@ -3,50 +3,148 @@ package http
 import (
 	"context"
 	"encoding/json"
-	"fmt"
 	"log"
 	"net/http"
-	"os"
 	"time"
 
 	"github.com/dgrijalva/jwt-go"
-	"github.com/go-playground/validator/v10"
 	"github.com/gorilla/mux"
+	"github.com/yuchida-tamu/git-workout-api/internal/auth"
 	"github.com/yuchida-tamu/git-workout-api/internal/user"
 )
 
 type PostUserRequest struct {
-	Username string
-	Password string
+	Username string `validate:"required,min=3,max=32"`
+	Password string `validate:"required"`
+	Email    string `json:"email" validate:"required,email"`
+}
+
+// UpdateUserRequest - what a user may change of their account here; the email changes through its own
+// verification instead
+type UpdateUserRequest struct {
+	Username string `validate:"required,min=3,max=32"`
+	Password string `validate:"required"`
 }
 
 type AuthData struct {
 	Username string
 	Password string
+	// Device is an optional client supplied name shown in the session list, e.g. "Pixel 7"
+	Device string `json:"device"`
 }
 
 type UserForClient struct {
+	ID            string
+	Username      string
+	Email         string
+	EmailVerified bool
+	// DeleteAfter is only set while the deletion of the account is pending
+	DeleteAfter *time.Time `json:",omitempty"`
+}
+
+// PublicUserForClient - what anyone but the user themselves and admins may see of a user
+type PublicUserForClient struct {
 	ID       string
 	Username string
 }
 
+// DeleteUserResponse - when the account is purged unless the user logs in before
+type DeleteUserResponse struct {
+	DeleteAfter time.Time `json:"delete_after"`
+}
+
+func convertUserToUserForClient(u user.User) UserForClient {
+	userForClient := UserForClient{
+		ID:            u.ID,
+		Username:      u.Username,
+		Email:         u.Email,
+		EmailVerified: u.EmailVerified,
+	}
+	if !u.DeleteAfter.IsZero() {
+		userForClient.DeleteAfter = &u.DeleteAfter
+	}
+
+	return userForClient
+}
+
 type AuthUserResponse struct {
 	Token        string `json:"accesss_token"`
 	RefreshToken string `json:"refresh_token"`
 	UserID       string `json:"user_id"`
+	// MFARequired is set instead of issuing tokens when the user has two-factor authentication enabled;
+	// MFAToken then has to be exchanged together with a code at /api/v1/auth/mfa/challenge
+	MFARequired bool   `json:"mfa_required,omitempty"`
+	MFAToken    string `json:"mfa_token,omitempty"`
+}
+
+type AuthService interface {
+	IssueRefreshToken(ctx context.Context, userID string, client auth.ClientInfo) (string, auth.RefreshToken, error)
+	RotateRefreshToken(ctx context.Context, raw string, client auth.ClientInfo) (string, auth.RefreshToken, error)
+	RevokeRefreshToken(ctx context.Context, raw string) error
+	IsSessionActive(ctx context.Context, ID string) (bool, error)
+	GetSessionsByUser(ctx context.Context, userID string) ([]auth.Session, error)
+	RevokeSession(ctx context.Context, userID string, ID string) error
+	RevokeSessionsByUser(ctx context.Context, userID string) error
+	EnrollMFA(ctx context.Context, userID string, accountName string) (auth.Enrollment, error)
+	ConfirmMFA(ctx context.Context, userID string, code string) error
+	IsMFAEnabled(ctx context.Context, userID string) (bool, error)
+	VerifyMFA(ctx context.Context, userID string, code string) error
+	IssueMFAChallenge(ctx context.Context, userID string) (string, error)
+	CompleteMFAChallenge(ctx context.Context, userID string, ID string, code string) error
+	DisableMFA(ctx context.Context, userID string, code string) error
+	RequestPasswordReset(ctx context.Context, username string) error
+	ResetPassword(ctx context.Context, raw string, password string) error
+	SendEmailVerification(ctx context.Context, userID string) error
+	VerifyEmail(ctx context.Context, raw string) error
+	LoginLockedFor(username string, ip string) time.Duration
+	RecordLoginFailure(ctx context.Context, username string, ip string)
+	RecordLoginSuccess(username string)
+	GetLockoutEventsByUser(ctx context.Context, userID string) ([]auth.LockoutEvent, error)
+	CreateAPIKey(ctx context.Context, userID string, name string, scopes []auth.Scope, expiresAt time.Time) (string, auth.APIKey, error)
+	AuthenticateAPIKey(ctx context.Context, raw string) (auth.APIKey, user.User, error)
+	GetAPIKeysByUser(ctx context.Context, userID string) ([]auth.APIKey, error)
+	RevokeAPIKey(ctx context.Context, userID string, ID string) error
+	ProviderNames() []string
+	BeginOIDCLogin(ctx context.Context, provider string, linkUserID string) (string, string, error)
+	CompleteOIDCLogin(ctx context.Context, provider string, code string, state string, binding string) (user.User, error)
+	GetIdentitiesByUser(ctx context.Context, userID string) ([]auth.Identity, error)
+	UnlinkIdentity(ctx context.Context, userID string, ID string) error
+	RegisterOAuthClient(ctx context.Context, ownerID string, name string, redirectURIs []string, scopes []auth.Scope, confidential bool) (string, auth.OAuthClient, error)
+	GetOAuthClientsByOwner(ctx context.Context, ownerID string) ([]auth.OAuthClient, error)
+	DeleteOAuthClient(ctx context.Context, ownerID string, ID string) error
+	ValidateAuthorizationRequest(ctx context.Context, userID string, req *auth.AuthorizationRequest) (auth.OAuthClient, bool, error)
+	Authorize(ctx context.Context, userID string, req auth.AuthorizationRequest) (string, error)
+	AuthenticateOAuthClient(ctx context.Context, clientID string, secret string) (auth.OAuthClient, error)
+	ExchangeAuthorizationCode(ctx context.Context, client auth.OAuthClient, code string, redirectURI string, verifier string, info auth.ClientInfo) (auth.Session, string, error)
+	RefreshOAuthToken(ctx context.Context, client auth.OAuthClient, raw string, info auth.ClientInfo) (auth.Session, string, error)
+	GetOAuthTokenSession(ctx context.Context, client auth.OAuthClient, sessionID string, rawRefreshToken string) (auth.Session, error)
+	RevokeOAuthSession(ctx context.Context, client auth.OAuthClient, sessionID string) error
 }
 
 type UserService interface {
+	GetUsers(ctx context.Context) ([]user.User, error)
 	GetUser(ctx context.Context, ID string) (user.User, error)
 	PostUser(context.Context, user.User) (user.User, error)
 	UpdateUser(ctx context.Context, ID string, user user.User) (user.User, error)
-	DeleteUser(ctx context.Context, ID string) error
+	ScheduleUserDeletion(ctx context.Context, ID string, version int) (time.Time, error)
+	CancelUserDeletion(ctx context.Context, ID string) error
 	AuthUser(ctx context.Context, username string, password string) (user.User, error)
+	UpdateUserRole(ctx context.Context, ID string, role user.Role) error
+	SetUserDisabled(ctx context.Context, ID string, disabled bool) error
+	UpdateUserEmail(ctx context.Context, ID string, email string) error
 }
 
 // TODO: remove password from reponse
 
 func convertPostUserRequestToUser(u PostUserRequest) user.User {
+	return user.User{
+		Username: u.Username,
+		Password: u.Password,
+		Email:    u.Email,
+	}
+}
+
+func convertUpdateUserRequestToUser(u UpdateUserRequest) user.User {
 	return user.User{
 		Username: u.Username,
 		Password: u.Password,
@@ -56,13 +154,13 @@ func convertPostUserRequestToUser(u PostUserRequest) user.User {
 func (h *Handler) PostUser(w http.ResponseWriter, r *http.Request) {
 	var user PostUserRequest
 	if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
+		writeInvalidBody(w, r)
 		return
 	}
 
-	validate := validator.New()
 	err := validate.Struct(user)
 	if err != nil {
-		http.Error(w, "not a valid user", http.StatusBadRequest)
+		writeValidationError(w, r, err)
 		return
 	}
 
@@ -70,15 +168,18 @@ func (h *Handler) PostUser(w http.ResponseWriter, r *http.Request) {
 
 	postedUser, err := h.Service.User.PostUser(r.Context(), convertedUser)
 	if err != nil {
-		log.Print(err)
+		writeError(w, r, err)
 		return
 	}
 
-	userForClient := UserForClient{
-		ID:       postedUser.ID,
-		Username: postedUser.Username,
+	// the account is usable without a verified address, so a failed mail does not fail the signup
+	if err := h.Service.Auth.SendEmailVerification(r.Context(), postedUser.ID); err != nil {
+		log.Print(err)
 	}
 
+	userForClient := convertUserToUserForClient(postedUser)
+	w.Header().Set("ETag", formatETag(postedUser.Version))
+
 	if err := json.NewEncoder(w).Encode(userForClient); err != nil {
 		panic(err)
 	}
@@ -89,22 +190,33 @@ func (h *Handler) GetUser(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
 	if id == "" {
-		w.WriteHeader(http.StatusBadRequest)
+		writeStatus(w, r, http.StatusBadRequest, "the id is missing")
 		return
 	}
 
 	user, err := h.Service.User.GetUser(r.Context(), id)
 	if err != nil {
-		log.Print(err)
-		w.WriteHeader(http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
-	userForClient := UserForClient{
-		ID:       user.ID,
-		Username: user.Username,
+	if writeNotModified(w, r, user.Version) {
+		return
 	}
 
+	if !checkUserCanSeePrivate(r.Context(), id) {
+		publicUser := PublicUserForClient{
+			ID:       user.ID,
+			Username: user.Username,
+		}
+		if err := json.NewEncoder(w).Encode(publicUser); err != nil {
+			panic(err)
+		}
+		return
+	}
+
+	userForClient := convertUserToUserForClient(user)
+
 	if err := json.NewEncoder(w).Encode(userForClient); err != nil {
 		panic(err)
 	}
@@ -114,40 +226,49 @@ func (h *Handler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
 	if id == "" {
-		w.WriteHeader(http.StatusBadRequest)
+		writeStatus(w, r, http.StatusBadRequest, "the id is missing")
 		return
 	}
 
-	var user user.User
-	if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
+	var updateReq UpdateUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&updateReq); err != nil {
+		writeInvalidBody(w, r)
 		return
 	}
 
 	// validate userId and currentId in the context match
 	if hasAccess := checkUserHasAccess(r.Context(), id); !hasAccess {
-		w.WriteHeader(http.StatusBadRequest)
+		writeStatus(w, r, http.StatusForbidden, "forbidden")
 		return
 	}
 
-	validate := validator.New()
-	err := validate.Struct(user)
+	err := validate.Struct(updateReq)
 	if err != nil {
-		http.Error(w, "not a valid user", http.StatusBadRequest)
+		writeValidationError(w, r, err)
 		return
 	}
+	user := convertUpdateUserRequestToUser(updateReq)
 
-	user, err = h.Service.User.UpdateUser(r.Context(), id, user)
+	existing, err := h.Service.User.GetUser(r.Context(), id)
 	if err != nil {
-		log.Print(err)
-		w.WriteHeader(http.StatusInternalServerError)
+		writeError(w, r, err)
+		return
+	}
+	version, ok := checkIfMatch(w, r, existing.Version)
+	if !ok {
 		return
 	}
+	user.Version = version
 
-	userForClient := UserForClient{
-		ID:       user.ID,
-		Username: user.Username,
+	user, err = h.Service.User.UpdateUser(r.Context(), id, user)
+	if err != nil {
+		writeError(w, r, err)
+		return
 	}
 
+	userForClient := convertUserToUserForClient(user)
+	w.Header().Set("ETag", formatETag(user.Version))
+
 	if err := json.NewEncoder(w).Encode(userForClient); err != nil {
 		panic(err)
 	}
@@ -157,24 +278,41 @@ func (h *Handler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
 	if id == "" {
-		w.WriteHeader(http.StatusBadRequest)
+		writeStatus(w, r, http.StatusBadRequest, "the id is missing")
 		return
 	}
 
 	// validate userId and currentId in the context match
 	if hasAccess := checkUserHasAccess(r.Context(), id); !hasAccess {
-		w.WriteHeader(http.StatusBadRequest)
+		writeStatus(w, r, http.StatusForbidden, "forbidden")
 		return
 	}
 
-	err := h.Service.User.DeleteUser(r.Context(), id)
+	existing, err := h.Service.User.GetUser(r.Context(), id)
 	if err != nil {
-		log.Print(err)
-		w.WriteHeader(http.StatusInternalServerError)
+		writeError(w, r, err)
+		return
+	}
+	version, ok := checkIfMatch(w, r, existing.Version)
+	if !ok {
+		return
+	}
+
+	// the account is only purged after the grace period, logging in before then keeps it
+	deleteAfter, err := h.Service.User.ScheduleUserDeletion(r.Context(), id, version)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	// API keys are suspended rather than revoked, they are refused while the deletion is pending
+	if err := h.Service.Auth.RevokeSessionsByUser(r.Context(), id); err != nil {
+		writeError(w, r, err)
 		return
 	}
 
-	if err := json.NewEncoder(w).Encode(Response{message: "Successfully deleted"}); err != nil {
+	w.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(w).Encode(DeleteUserResponse{DeleteAfter: deleteAfter}); err != nil {
 		panic(err)
 	}
 }
@@ -182,51 +320,61 @@ func (h *Handler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) AuthUser(w http.ResponseWriter, r *http.Request) {
 	var authData AuthData
 	if err := json.NewDecoder(r.Body).Decode(&authData); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		writeInvalidBody(w, r)
 		return
 	}
 
-	validate := validator.New()
 	err := validate.Struct(authData)
 
 	if err != nil {
-		http.Error(w, "not a valid input", http.StatusBadRequest)
+		writeValidationError(w, r, err)
+		return
+	}
+
+	ip := clientInfoFromRequest(r, "").IP
+	if retryAfter := h.Service.Auth.LoginLockedFor(authData.Username, ip); retryAfter > 0 {
+		writeTooManyAttempts(w, r, retryAfter)
 		return
 	}
 
 	user, err := h.Service.User.AuthUser(r.Context(), authData.Username, authData.Password)
 
 	if err != nil {
-		response := AuthUserResponse{
-			Token:  "",
-			UserID: "",
-		}
-
-		if err := json.NewEncoder(w).Encode(response); err != nil {
-			panic(err)
-		}
+		log.Print(err)
+		h.Service.Auth.RecordLoginFailure(r.Context(), authData.Username, ip)
+		writeInvalidCredentials(w, r, "invalid username or password")
 		return
 	}
 
-	tokenPair, err := generateTokenPair(authData.Username, user.ID)
+	h.writeLoginResponse(w, r, user, authData.Device)
+}
 
+// writeLoginResponse - finishes a login which proved who the user is, answering with an MFA challenge
+// when the user has MFA enabled and with a token pair otherwise. The failed attempts of the username are only
+// forgotten once tokens are issued, a correct password alone must not reset the count of wrong MFA codes.
+func (h *Handler) writeLoginResponse(w http.ResponseWriter, r *http.Request, u user.User, device string) {
+	mfaEnabled, err := h.Service.Auth.IsMFAEnabled(r.Context(), u.ID)
 	if err != nil {
-		response := AuthUserResponse{
-			Token:        "",
-			RefreshToken: "",
-			UserID:       "",
-		}
+		writeError(w, r, err)
+		return
+	}
+	if mfaEnabled {
+		h.writeMFAChallenge(w, r, u, device)
+		return
+	}
 
-		if err := json.NewEncoder(w).Encode(response); err != nil {
-			panic(err)
-		}
+	tokenPair, err := h.generateTokenPair(r.Context(), u, clientInfoFromRequest(r, device))
+
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
+	h.Service.Auth.RecordLoginSuccess(u.Username)
 
 	response := AuthUserResponse{
 		Token:        tokenPair["access_token"],
 		RefreshToken: tokenPair["refresh_token"],
-		UserID:       user.ID,
+		UserID:       u.ID,
 	}
 
 	if err := json.NewEncoder(w).Encode(response); err != nil {
@@ -236,47 +384,50 @@ func (h *Handler) AuthUser(w http.ResponseWriter, r *http.Request) {
 }
 
 type tokenReqBody struct {
-	RefreshToken string `json:"refresh_token"`
-	UserID       string `json:"user_id"`
-	Username     string `json:"username"`
+	RefreshToken string `json:"refresh_token" validate:"required"`
 }
 
-// RefreshToken - a handler to refresh token for client
+// RefreshToken - a handler to refresh token for client.
+// The presented refresh token is rotated, and the user is taken from the stored token rather than the request.
 func (h *Handler) RefreshToken(w http.ResponseWriter, r *http.Request) {
 	var tokenReq tokenReqBody
 	if err := json.NewDecoder(r.Body).Decode(&tokenReq); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		writeInvalidBody(w, r)
 		return
 	}
 
-	token, err := jwt.Parse(tokenReq.RefreshToken, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return "", fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(os.Getenv("SIGNING_SECRET")), nil
-	})
+	if err := validate.Struct(tokenReq); err != nil {
+		writeValidationError(w, r, err)
+		return
+	}
 
+	refreshToken, storedToken, err := h.Service.Auth.RotateRefreshToken(r.Context(), tokenReq.RefreshToken, clientInfoFromRequest(r, ""))
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		log.Print(err)
+		writeInvalidCredentials(w, r, "not a valid refresh token")
 		return
 	}
 
-	_, ok := token.Claims.(jwt.MapClaims)
-	if !ok || !token.Valid {
-		w.WriteHeader(http.StatusBadRequest)
+	// the user is looked up again so that role changes and disabled accounts take effect on refresh
+	currentUser, err := h.Service.User.GetUser(r.Context(), storedToken.UserID)
+	if err != nil || currentUser.Disabled {
+		if err := h.Service.Auth.RevokeRefreshToken(r.Context(), refreshToken); err != nil {
+			log.Print(err)
+		}
+		writeInvalidCredentials(w, r, "not a valid refresh token")
 		return
 	}
 
-	newTokenPair, err := generateTokenPair(tokenReq.Username, tokenReq.UserID)
+	accessToken, err := h.generateAccessToken(currentUser, storedToken.FamilyID)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		writeError(w, r, err)
 		return
 	}
 
 	response := AuthUserResponse{
-		Token:        newTokenPair["access_token"],
-		RefreshToken: newTokenPair["refresh_token"],
-		UserID:       tokenReq.UserID,
+		Token:        accessToken,
+		RefreshToken: refreshToken,
+		UserID:       currentUser.ID,
 	}
 
 	if err := json.NewEncoder(w).Encode(response); err != nil {
@@ -285,32 +436,43 @@ func (h *Handler) RefreshToken(w http.ResponseWriter, r *http.Request) {
 
 }
 
-func generateTokenPair(username string, id string) (map[string]string, error) {
-	// Create token
-	token := jwt.New(jwt.SigningMethodHS256)
+// Logout - a handler which revokes the refresh token, and every token rotated from the same login
+func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) {
+	var tokenReq tokenReqBody
+	if err := json.NewDecoder(r.Body).Decode(&tokenReq); err != nil {
+		writeInvalidBody(w, r)
+		return
+	}
 
-	// Set claims
-	// This is the information which frontend can use
-	// The backend can also decode the token and get admin etc.
-	claims := token.Claims.(jwt.MapClaims)
-	claims["sub"] = 1
-	claims["username"] = username
-	claims["userId"] = id
-	claims["exp"] = time.Now().Add(time.Minute * 60).Unix()
+	if err := validate.Struct(tokenReq); err != nil {
+		writeValidationError(w, r, err)
+		return
+	}
+
+	if err := h.Service.Auth.RevokeRefreshToken(r.Context(), tokenReq.RefreshToken); err != nil {
+		log.Print(err)
+		writeInvalidCredentials(w, r, "not a valid refresh token")
+		return
+	}
 
-	// Generate encoded token and send it as response.
-	// The signing string should be secret (a generated UUID works too)
-	t, err := token.SignedString([]byte(os.Getenv("SIGNING_SECRET")))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// generateTokenPair - starts a new session for the user and signs an access token bound to it
+func (h *Handler) generateTokenPair(ctx context.Context, u user.User, client auth.ClientInfo) (map[string]string, error) {
+	// every way of logging in ends here, and logging in takes back a pending deletion
+	if !u.DeleteAfter.IsZero() {
+		if err := h.Service.User.CancelUserDeletion(ctx, u.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	rt, storedToken, err := h.Service.Auth.IssueRefreshToken(ctx, u.ID, client)
 	if err != nil {
 		return nil, err
 	}
 
-	refreshToken := jwt.New(jwt.SigningMethodHS256)
-	rtClaims := refreshToken.Claims.(jwt.MapClaims)
-	rtClaims["sub"] = 1
-	rtClaims["exp"] = time.Now().Add(time.Hour * 24).Unix()
-
-	rt, err := refreshToken.SignedString([]byte(os.Getenv("SIGNING_SECRETE")))
+	t, err := h.generateAccessToken(u, storedToken.FamilyID)
 	if err != nil {
 		return nil, err
 	}
@@ -321,7 +483,84 @@ func generateTokenPair(username string, id string) (map[string]string, error) {
 	}, nil
 }
 
+func (h *Handler) generateAccessToken(u user.User, sessionID string) (string, error) {
+	// Set claims
+	// This is the information which frontend can use
+	// The backend can also decode the token and get admin etc.
+	claims := jwt.MapClaims{}
+	claims["sub"] = 1
+	claims["username"] = u.Username
+	claims["userId"] = u.ID
+	claims["role"] = string(u.Role)
+	claims["sid"] = sessionID
+	claims["typ"] = accessTokenType
+	// logins to this API's own clients may do everything the user may
+	claims["scope"] = auth.FormatScopes(auth.AllScopes)
+	claims["exp"] = time.Now().Add(time.Minute * 60).Unix()
+
+	// Sign with the current key of the key set, which names itself in the kid header
+	return h.Keys.Sign(claims)
+}
+
+// generateOAuthAccessToken - an access token for a session granted to a third-party app, which names the app
+// and the scopes the user consented to
+func (h *Handler) generateOAuthAccessToken(u user.User, session auth.Session) (string, error) {
+	claims := jwt.MapClaims{}
+	claims["sub"] = u.ID
+	claims["username"] = u.Username
+	claims["userId"] = u.ID
+	claims["role"] = string(u.Role)
+	claims["sid"] = session.ID
+	claims["client_id"] = session.ClientID
+	claims["typ"] = accessTokenType
+	claims["scope"] = auth.FormatScopes(session.Scopes)
+	claims["exp"] = time.Now().Add(oauthAccessTokenLifetime).Unix()
+
+	return h.Keys.Sign(claims)
+}
+
 func checkUserHasAccess(ctx context.Context, id string) bool {
 	currentUserId, ok := ctx.Value("user_id").(string)
 	return ok && currentUserId == id
 }
+
+// checkUserCanSeePrivate - the email address and account state of a user are only shown to the user
+// themselves and to admins
+func checkUserCanSeePrivate(ctx context.Context, id string) bool {
+	return checkUserHasAccess(ctx, id) || currentUserRole(ctx).Can(user.PermissionManageUsers)
+}
+
+func currentUserID(ctx context.Context) string {
+	currentUserId, _ := ctx.Value("user_id").(string)
+	return currentUserId
+}
+
+func currentSessionID(ctx context.Context) string {
+	sessionId, _ := ctx.Value("session_id").(string)
+	return sessionId
+}
+
+func currentAPIKeyID(ctx context.Context) string {
+	apiKeyId, _ := ctx.Value("api_key_id").(string)
+	return apiKeyId
+}
+
+func currentClientID(ctx context.Context) string {
+	clientId, _ := ctx.Value("client_id").(string)
+	return clientId
+}
+
+func currentScopes(ctx context.Context) []auth.Scope {
+	scopes, _ := ctx.Value("scopes").([]auth.Scope)
+	return scopes
+}
+
+func currentRequestID(ctx context.Context) string {
+	requestId, _ := ctx.Value("request_id").(string)
+	return requestId
+}
+
+func currentUserRole(ctx context.Context) user.Role {
+	role, _ := ctx.Value("user_role").(string)
+	return user.Role(role)
+}
@@ -0,0 +1,49 @@
+package http_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	transportHttp "github.com/yuchida-tamu/git-workout-api/internal/transport/http"
+)
+
+func TestUpdateUser(t *testing.T) {
+	s := newTestServer(t)
+	path := "/api/v1/user/" + s.user.ID
+
+	for _, c := range []struct {
+		name string
+		body string
+	}{
+		{"NoUsername", `{"Password": "quiet lantern orchard velvet"}`},
+		{"ShortUsername", `{"Username": "al", "Password": "quiet lantern orchard velvet"}`},
+		{"NoPassword", `{"Username": "alice-renamed"}`},
+		// the username changes, but the password still gives away the email the account has
+		{"PasswordWithEmail", `{"Username": "alice-renamed", "Password": "alice@example.com quiet lantern"}`},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			requireStatus(t, s.do(t, http.MethodPut, path, c.body, nil), http.StatusBadRequest)
+		})
+	}
+
+	// the email is not changed here, even when the body names one
+	rec := s.do(t, http.MethodPut, path, `{"Username": "alice-renamed", "Password": "quiet lantern orchard velvet", "Email": "mallory@example.com"}`, nil)
+	requireStatus(t, rec, http.StatusOK)
+	var updated transportHttp.UserForClient
+	if err := json.NewDecoder(rec.Body).Decode(&updated); err != nil {
+		t.Fatal(err)
+	}
+	if updated.Username != "alice-renamed" || updated.Email != "alice@example.com" {
+		t.Fatalf("unexpected updated user: %+v", updated)
+	}
+
+	u, err := s.store.GetUser(context.Background(), s.user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Email != "alice@example.com" {
+		t.Fatalf("expected the email to be kept, got %q", u.Email)
+	}
+}
@@ -0,0 +1,109 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DeletionGracePeriod - how long a user can still take back the deletion of their account by logging in
+const DeletionGracePeriod = 14 * 24 * time.Hour
+
+// AuditAction - what happened to an account
+type AuditAction string
+
+const (
+	AuditActionDeletionScheduled AuditAction = "account_deletion_scheduled"
+	AuditActionDeletionCancelled AuditAction = "account_deletion_cancelled"
+	AuditActionPurged            AuditAction = "account_purged"
+)
+
+// AuditEvent - a record of something which happened to an account. Events are kept after the account is
+// purged, as the proof that it was.
+type AuditEvent struct {
+	ID          string
+	UserID      string
+	Action      AuditAction
+	DateCreated time.Time
+}
+
+// ScheduleUserDeletion - marks the account for deletion after the grace period and returns when it is due.
+// Only a user still at version is marked, any version when that is 0.
+func (s *Service) ScheduleUserDeletion(ctx context.Context, ID string, version int) (time.Time, error) {
+	now := time.Now().UTC()
+	deleteAfter := now.Add(DeletionGracePeriod)
+
+	err := s.Store.WithTx(ctx, func(ctx context.Context) error {
+		if err := s.Store.SetUserDeleteAfter(ctx, ID, deleteAfter, version); err != nil {
+			return err
+		}
+		return s.audit(ctx, ID, AuditActionDeletionScheduled, now)
+	})
+	if err != nil {
+		fmt.Println(err)
+		return time.Time{}, err
+	}
+
+	return deleteAfter, nil
+}
+
+// CancelUserDeletion - keeps an account whose deletion is pending; logging in does this
+func (s *Service) CancelUserDeletion(ctx context.Context, ID string) error {
+	err := s.Store.WithTx(ctx, func(ctx context.Context) error {
+		if err := s.Store.SetUserDeleteAfter(ctx, ID, time.Time{}, 0); err != nil {
+			return err
+		}
+		return s.audit(ctx, ID, AuditActionDeletionCancelled, time.Now().UTC())
+	})
+	if err != nil {
+		fmt.Println(err)
+		return err
+	}
+
+	return nil
+}
+
+// PurgeDueUsers - purges every account whose grace period is over and reports how many it purged. Each
+// account is purged in a transaction of its own, so one failing does not keep the others.
+func (s *Service) PurgeDueUsers(ctx context.Context, now time.Time) (int, error) {
+	due, err := s.Store.GetUsersDueForDeletion(ctx, now)
+	if err != nil {
+		fmt.Println(err)
+		return 0, err
+	}
+
+	purged := 0
+	for _, u := range due {
+		err := s.purgeUser(ctx, u.ID, now)
+		// the user may have logged in since the due accounts were listed
+		if errors.Is(err, ErrDeletionNotDue) {
+			continue
+		}
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+		purged++
+	}
+
+	return purged, nil
+}
+
+func (s *Service) purgeUser(ctx context.Context, ID string, now time.Time) error {
+	return s.Store.WithTx(ctx, func(ctx context.Context) error {
+		if err := s.Store.PurgeUser(ctx, ID, now); err != nil {
+			return fmt.Errorf("failed to purge user %s: %w", ID, err)
+		}
+		return s.audit(ctx, ID, AuditActionPurged, now)
+	})
+}
+
+func (s *Service) audit(ctx context.Context, userID string, action AuditAction, now time.Time) error {
+	_, err := s.Store.PostAuditEvent(ctx, AuditEvent{
+		UserID:      userID,
+		Action:      action,
+		DateCreated: now,
+	})
+	return err
+}
@@ -0,0 +1,18 @@
+package user
+
+import "errors"
+
+var (
+	// ErrNotFound - the user does not exist
+	ErrNotFound = errors.New("user not found")
+	// ErrConflict - the username or email belongs to another user
+	ErrConflict = errors.New("user conflict")
+	// ErrValidation - the user is not valid
+	ErrValidation = errors.New("invalid user")
+	// ErrForbidden - the caller may not access the user
+	ErrForbidden = errors.New("not allowed to access the user")
+	// ErrVersionMismatch - a write expected a version of the user which is no longer current
+	ErrVersionMismatch = errors.New("the user has been changed since it was read")
+	// ErrDeletionNotDue - the user's deletion was cancelled or is not due yet
+	ErrDeletionNotDue = errors.New("the user is not due for deletion")
+)
@@ -0,0 +1,56 @@
+package user
+
+// Role - the role a user holds, which decides what they are permitted to do
+type Role string
+
+const (
+	RoleUser  Role = "user"
+	RoleCoach Role = "coach"
+	RoleAdmin Role = "admin"
+)
+
+// Permission - a single action a route can require from the current user
+type Permission string
+
+const (
+	PermissionReadOwnRecords  Permission = "records:read:own"
+	PermissionWriteOwnRecords Permission = "records:write:own"
+	PermissionCoachAthletes   Permission = "athletes:coach"
+	PermissionListUsers       Permission = "users:list"
+	PermissionManageUsers     Permission = "users:manage"
+)
+
+var rolePermissions = map[Role][]Permission{
+	RoleUser: {
+		PermissionReadOwnRecords,
+		PermissionWriteOwnRecords,
+	},
+	RoleCoach: {
+		PermissionReadOwnRecords,
+		PermissionWriteOwnRecords,
+		PermissionCoachAthletes,
+	},
+	RoleAdmin: {
+		PermissionReadOwnRecords,
+		PermissionWriteOwnRecords,
+		PermissionCoachAthletes,
+		PermissionListUsers,
+		PermissionManageUsers,
+	},
+}
+
+// Valid - reports whether the role is one of the known roles
+func (r Role) Valid() bool {
+	_, ok := rolePermissions[r]
+	return ok
+}
+
+// Can - reports whether the role grants the given permission
+func (r Role) Can(p Permission) bool {
+	for _, granted := range rolePermissions[r] {
+		if granted == p {
+			return true
+		}
+	}
+	return false
+}
@@ -3,37 +3,67 @@ package user
 import (
 	"context"
 	"fmt"
+	"time"
 
-	"golang.org/x/crypto/bcrypt"
+	"github.com/yuchida-tamu/git-workout-api/internal/password"
 )
 
 type User struct {
 	ID       string
 	Username string
 	Password string
+	Role     Role
+	Disabled bool
+	// Email is unique regardless of case; EmailVerified is set once the user redeems a verification token
+	Email         string
+	EmailVerified bool
+	// DeleteAfter is when the account is purged, zero unless its deletion is pending
+	DeleteAfter time.Time
+	// Version counts the writes to the user, starting at 1
+	Version int
 }
 
 type Store interface {
 	GetUsers(context.Context) ([]User, error)
 	GetUser(context.Context, string) (User, error)
 	PostUser(context.Context, User) (User, error)
+	// UpdateUser only updates a user still at user.Version, unless that is 0, and returns them with the next version
 	UpdateUser(context.Context, string, User) (User, error)
 	DeleteUser(context.Context, string) error
 	GetUserByUsername(context.Context, string) (User, error)
-}
+	UpdateUserRole(ctx context.Context, ID string, role Role) error
+	SetUserDisabled(ctx context.Context, ID string, disabled bool) error
+	GetUserByEmail(context.Context, string) (User, error)
+	UpdateUserEmail(ctx context.Context, ID string, email string) error
+	UpdateUserPassword(ctx context.Context, ID string, hash string) error
+	// SetUserDeleteAfter schedules the purge of the account if the user is still at version, any version when
+	// that is 0, and fails with ErrVersionMismatch otherwise; a zero time cancels it
+	SetUserDeleteAfter(ctx context.Context, ID string, deleteAfter time.Time, version int) error
+	GetUsersDueForDeletion(ctx context.Context, now time.Time) ([]User, error)
+	// PurgeUser deletes the user along with everything stored about them, except for audit events, if their
+	// deletion is due at now; otherwise it fails with ErrDeletionNotDue and has to be rolled back
+	PurgeUser(ctx context.Context, ID string, now time.Time) error
+	PostAuditEvent(context.Context, AuditEvent) (AuditEvent, error)
+	GetAuditEventsByUser(ctx context.Context, userID string) ([]AuditEvent, error)
 
-type Service struct {
-	Store Store
+	// WithTx - runs fn in a transaction which every call passing on fn's context takes part in; it rolls back
+	// when fn returns an error or panics. The transaction is carried by the context rather than by a Store
+	// handed to fn: one database is the user, record and auth Store at once, and a WithTx(func(tx Store)) for
+	// each of them would be three methods of the same name.
+	WithTx(ctx context.Context, fn func(ctx context.Context) error) error
 }
 
-func checkPasswordHash(password, hash string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
-	return err == nil
+type Service struct {
+	Store          Store
+	PasswordPolicy password.Policy
+	Hasher         password.Hasher
 }
 
-func NewService(store Store) *Service {
+func NewService(store Store, passwordPolicy password.Policy, hasher password.Hasher) *Service {
 	return &Service{
-		Store: store,
+		Store:          store,
+		PasswordPolicy: passwordPolicy,
+		Hasher:         hasher,
 	}
 }
 
@@ -57,17 +87,35 @@ func (s *Service) GetUser(ctx context.Context, ID string) (User, error) {
 }
 
 func (s *Service) PostUser(ctx context.Context, user User) (User, error) {
-	user, err := s.Store.PostUser(ctx, user)
+	// new accounts always start with the least privileged role
+	user.Role = RoleUser
+	user.Disabled = false
+	user.EmailVerified = false
+
+	if err := s.PasswordPolicy.Validate(user.Password, user.Username, user.Email); err != nil {
+		return User{}, err
+	}
+
+	hash, err := s.Hasher.Hash(user.Password)
 	if err != nil {
 		fmt.Println(err)
 		return User{}, err
 	}
+	user.Password = hash
 
-	return user, nil
-}
+	// the unique indexes are what keeps two users from getting the same username or email, the checks only
+	// turn the common case into a clearer error than the store's conflict
+	err = s.Store.WithTx(ctx, func(ctx context.Context) error {
+		if err := s.checkUsernameIsFree(ctx, user.Username, ""); err != nil {
+			return err
+		}
+		if err := s.checkEmailIsFree(ctx, user.Email, ""); err != nil {
+			return err
+		}
 
-func (s *Service) UpdateUser(ctx context.Context, ID string, user User) (User, error) {
-	user, err := s.Store.UpdateUser(ctx, ID, user)
+		user, err = s.Store.PostUser(ctx, user)
+		return err
+	})
 	if err != nil {
 		fmt.Println(err)
 		return User{}, err
@@ -76,26 +124,133 @@ func (s *Service) UpdateUser(ctx context.Context, ID string, user User) (User, e
 	return user, nil
 }
 
-func (s *Service) DeleteUser(ctx context.Context, ID string) error {
-	err := s.Store.DeleteUser(ctx, ID)
+// UpdateUser - changes the username and password of the user; the password policy is checked against the
+// email the user has, which cannot be changed here
+func (s *Service) UpdateUser(ctx context.Context, ID string, user User) (User, error) {
+	existing, err := s.Store.GetUser(ctx, ID)
 	if err != nil {
 		fmt.Println(err)
+		return User{}, err
+	}
+	if err := s.PasswordPolicy.Validate(user.Password, user.Username, existing.Email); err != nil {
+		return User{}, err
+	}
+
+	hash, err := s.Hasher.Hash(user.Password)
+	if err != nil {
+		fmt.Println(err)
+		return User{}, err
+	}
+	user.Password = hash
+
+	err = s.Store.WithTx(ctx, func(ctx context.Context) error {
+		if err := s.checkUsernameIsFree(ctx, user.Username, ID); err != nil {
+			return err
+		}
+
+		user, err = s.Store.UpdateUser(ctx, ID, user)
 		return err
+	})
+	if err != nil {
+		fmt.Println(err)
+		return User{}, err
 	}
 
-	return nil
+	return user, nil
 }
 
-func (s *Service) AuthUser(ctx context.Context, username string, password string) (User, error) {
+func (s *Service) AuthUser(ctx context.Context, username string, plain string) (User, error) {
 	user, err := s.Store.GetUserByUsername(ctx, username)
 	if err != nil {
 		fmt.Println(err)
 		return User{}, err
 	}
 
-	if !checkPasswordHash(password, user.Password) {
+	if ok, err := password.Verify(plain, user.Password); !ok {
+		if err != nil {
+			fmt.Println(err)
+		}
 		return User{}, fmt.Errorf("Failed to authenticate the user")
 	}
 
+	if user.Disabled {
+		return User{}, fmt.Errorf("the user account is disabled")
+	}
+
+	// the plain password is only at hand during login, so this is when older hashes get upgraded
+	if s.Hasher.NeedsRehash(user.Password) {
+		hash, err := s.Hasher.Hash(plain)
+		if err == nil {
+			err = s.Store.UpdateUserPassword(ctx, user.ID, hash)
+		}
+		if err != nil {
+			fmt.Println(err)
+		} else {
+			user.Password = hash
+		}
+	}
+
 	return user, nil
 }
+
+func (s *Service) UpdateUserRole(ctx context.Context, ID string, role Role) error {
+	if !role.Valid() {
+		return fmt.Errorf("%w: unknown role %q", ErrValidation, role)
+	}
+
+	if err := s.Store.UpdateUserRole(ctx, ID, role); err != nil {
+		fmt.Println(err)
+		return err
+	}
+
+	return nil
+}
+
+func (s *Service) SetUserDisabled(ctx context.Context, ID string, disabled bool) error {
+	if err := s.Store.SetUserDisabled(ctx, ID, disabled); err != nil {
+		fmt.Println(err)
+		return err
+	}
+
+	return nil
+}
+
+// checkUsernameIsFree - usernames are unique regardless of case; the store enforces this too, this check
+// only gives a clearer error
+func (s *Service) checkUsernameIsFree(ctx context.Context, username string, ID string) error {
+	existing, err := s.Store.GetUserByUsername(ctx, username)
+	if err == nil && existing.ID != ID {
+		return fmt.Errorf("%w: the username is already taken", ErrConflict)
+	}
+
+	return nil
+}
+
+// checkEmailIsFree - emails are unique regardless of case; the store enforces this too, this check only
+// gives a clearer error
+func (s *Service) checkEmailIsFree(ctx context.Context, email string, ID string) error {
+	if email == "" {
+		return nil
+	}
+
+	existing, err := s.Store.GetUserByEmail(ctx, email)
+	if err == nil && existing.ID != ID {
+		return fmt.Errorf("%w: the email address is already in use", ErrConflict)
+	}
+
+	return nil
+}
+
+// UpdateUserEmail - changes the user's email address and marks it as unverified
+func (s *Service) UpdateUserEmail(ctx context.Context, ID string, email string) error {
+	if err := s.checkEmailIsFree(ctx, email, ID); err != nil {
+		return err
+	}
+
+	if err := s.Store.UpdateUserEmail(ctx, ID, email); err != nil {
+		fmt.Println(err)
+		return err
+	}
+
+	return nil
+}
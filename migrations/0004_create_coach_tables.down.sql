@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS record_comments;
+DROP TABLE IF EXISTS coach_relationships;
@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS coach_relationships (
+    ID uuid PRIMARY KEY,
+    COACH_ID uuid NOT NULL,
+    ATHLETE_ID uuid NOT NULL,
+    SCOPES text NOT NULL,
+    STATUS text NOT NULL,
+    DATE_CREATED timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS coach_relationships_coach_id_idx ON coach_relationships (COACH_ID);
+CREATE INDEX IF NOT EXISTS coach_relationships_athlete_id_idx ON coach_relationships (ATHLETE_ID);
+
+CREATE TABLE IF NOT EXISTS record_comments (
+    ID uuid PRIMARY KEY,
+    RECORD_ID uuid NOT NULL,
+    AUTHOR uuid NOT NULL,
+    MESSAGE_BODY text NOT NULL,
+    DATE_CREATED timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS record_comments_record_id_idx ON record_comments (RECORD_ID);
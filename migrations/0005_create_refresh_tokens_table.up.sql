@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    ID uuid PRIMARY KEY,
+    USER_ID uuid NOT NULL,
+    FAMILY_ID uuid NOT NULL,
+    TOKEN_HASH text NOT NULL UNIQUE,
+    USED boolean NOT NULL DEFAULT false,
+    REVOKED boolean NOT NULL DEFAULT false,
+    EXPIRES_AT timestamptz NOT NULL,
+    DATE_CREATED timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS refresh_tokens_family_id_idx ON refresh_tokens (FAMILY_ID);
+CREATE INDEX IF NOT EXISTS refresh_tokens_user_id_idx ON refresh_tokens (USER_ID);
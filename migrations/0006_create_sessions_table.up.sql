@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS sessions (
+    ID uuid PRIMARY KEY,
+    USER_ID uuid NOT NULL,
+    DEVICE text NOT NULL DEFAULT '',
+    IP text NOT NULL DEFAULT '',
+    USER_AGENT text NOT NULL DEFAULT '',
+    REVOKED boolean NOT NULL DEFAULT false,
+    LAST_USED timestamptz NOT NULL DEFAULT now(),
+    DATE_CREATED timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS sessions_user_id_idx ON sessions (USER_ID);
+
+-- refresh tokens issued before sessions existed have no session to belong to
+UPDATE refresh_tokens SET REVOKED = true;
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS user_mfa (
+    USER_ID uuid PRIMARY KEY,
+    SECRET text NOT NULL,
+    ENABLED boolean NOT NULL DEFAULT false,
+    RECOVERY_CODE_HASHES text NOT NULL DEFAULT '',
+    LAST_USED_STEP bigint NOT NULL DEFAULT 0,
+    DATE_CREATED timestamptz NOT NULL DEFAULT now()
+);
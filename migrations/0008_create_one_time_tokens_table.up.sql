@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS one_time_tokens (
+    ID uuid PRIMARY KEY,
+    USER_ID uuid NOT NULL,
+    PURPOSE text NOT NULL,
+    TOKEN_HASH text NOT NULL UNIQUE,
+    USED boolean NOT NULL DEFAULT false,
+    EXPIRES_AT timestamptz NOT NULL,
+    DATE_CREATED timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS one_time_tokens_user_id_purpose_idx ON one_time_tokens (USER_ID, PURPOSE);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS users_email_lower_idx;
+ALTER TABLE users DROP COLUMN IF EXISTS EMAIL_VERIFIED;
+ALTER TABLE users DROP COLUMN IF EXISTS EMAIL;
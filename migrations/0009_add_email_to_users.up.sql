@@ -0,0 +1,4 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS EMAIL text;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS EMAIL_VERIFIED boolean NOT NULL DEFAULT false;
+
+CREATE UNIQUE INDEX IF NOT EXISTS users_email_lower_idx ON users (lower(EMAIL)) WHERE EMAIL IS NOT NULL;
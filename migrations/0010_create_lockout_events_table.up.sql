@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS lockout_events (
+    ID uuid PRIMARY KEY,
+    USER_ID uuid,
+    USERNAME text NOT NULL,
+    IP text NOT NULL,
+    REASON text NOT NULL,
+    LOCKED_UNTIL timestamptz NOT NULL,
+    DATE_CREATED timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS lockout_events_user_id_idx ON lockout_events (USER_ID);
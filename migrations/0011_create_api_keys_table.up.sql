@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS api_keys (
+    ID uuid PRIMARY KEY,
+    USER_ID uuid NOT NULL,
+    NAME text NOT NULL,
+    PREFIX text NOT NULL,
+    KEY_HASH text NOT NULL UNIQUE,
+    SCOPES text NOT NULL,
+    REVOKED boolean NOT NULL DEFAULT false,
+    EXPIRES_AT timestamptz,
+    LAST_USED timestamptz,
+    DATE_CREATED timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS api_keys_user_id_idx ON api_keys (USER_ID);
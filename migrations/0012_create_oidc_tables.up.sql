@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS oidc_logins (
+    ID uuid PRIMARY KEY,
+    STATE_HASH text NOT NULL UNIQUE,
+    PROVIDER text NOT NULL,
+    VERIFIER text NOT NULL,
+    NONCE text NOT NULL,
+    LINK_USER_ID uuid,
+    EXPIRES_AT timestamptz NOT NULL,
+    DATE_CREATED timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS user_identities (
+    ID uuid PRIMARY KEY,
+    USER_ID uuid NOT NULL,
+    PROVIDER text NOT NULL,
+    SUBJECT text NOT NULL,
+    EMAIL text NOT NULL DEFAULT '',
+    DATE_CREATED timestamptz NOT NULL DEFAULT now(),
+    UNIQUE (PROVIDER, SUBJECT)
+);
+
+CREATE INDEX IF NOT EXISTS user_identities_user_id_idx ON user_identities (USER_ID);
@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS oauth_consents;
+DROP TABLE IF EXISTS oauth_authorization_codes;
+DROP TABLE IF EXISTS oauth_clients;
+
+DROP INDEX IF EXISTS sessions_client_id_idx;
+
+ALTER TABLE sessions
+    DROP COLUMN IF EXISTS CLIENT_ID,
+    DROP COLUMN IF EXISTS SCOPES;
@@ -0,0 +1,39 @@
+ALTER TABLE sessions
+    ADD COLUMN IF NOT EXISTS CLIENT_ID text NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS SCOPES text NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS sessions_client_id_idx ON sessions (CLIENT_ID) WHERE CLIENT_ID <> '';
+
+CREATE TABLE IF NOT EXISTS oauth_clients (
+    ID uuid PRIMARY KEY,
+    OWNER_ID uuid NOT NULL,
+    NAME text NOT NULL,
+    SECRET_HASH text NOT NULL DEFAULT '',
+    REDIRECT_URIS text NOT NULL,
+    SCOPES text NOT NULL,
+    DATE_CREATED timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS oauth_clients_owner_id_idx ON oauth_clients (OWNER_ID);
+
+CREATE TABLE IF NOT EXISTS oauth_authorization_codes (
+    ID uuid PRIMARY KEY,
+    CODE_HASH text NOT NULL UNIQUE,
+    CLIENT_ID uuid NOT NULL,
+    USER_ID uuid NOT NULL,
+    REDIRECT_URI text NOT NULL,
+    SCOPES text NOT NULL,
+    CODE_CHALLENGE text NOT NULL,
+    USED boolean NOT NULL DEFAULT false,
+    SESSION_ID uuid,
+    EXPIRES_AT timestamptz NOT NULL,
+    DATE_CREATED timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS oauth_consents (
+    USER_ID uuid NOT NULL,
+    CLIENT_ID uuid NOT NULL,
+    SCOPES text NOT NULL,
+    DATE_CREATED timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (USER_ID, CLIENT_ID)
+);
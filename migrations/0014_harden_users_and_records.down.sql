@@ -0,0 +1,17 @@
+-- the rows removed or renamed by the cleanup are not restored
+DROP INDEX IF EXISTS records_author_date_created_idx;
+
+ALTER TABLE records
+    DROP CONSTRAINT IF EXISTS records_author_fkey,
+    DROP CONSTRAINT IF EXISTS records_pkey,
+    ALTER COLUMN MESSAGE_BODY DROP NOT NULL,
+    ALTER COLUMN DATE_CREATED DROP NOT NULL,
+    ALTER COLUMN AUTHOR DROP NOT NULL,
+    ALTER COLUMN AUTHOR TYPE text USING AUTHOR::text;
+
+DROP INDEX IF EXISTS users_username_lower_idx;
+
+ALTER TABLE users
+    DROP CONSTRAINT IF EXISTS users_pkey,
+    ALTER COLUMN PASSWORD DROP NOT NULL,
+    ALTER COLUMN USERNAME DROP NOT NULL;
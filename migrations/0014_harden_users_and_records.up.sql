@@ -0,0 +1,43 @@
+-- users and records were created without keys or constraints; rows which would violate them are cleaned
+-- up first
+
+-- rows without an id, or with the id of another row, cannot be addressed by the API
+DELETE FROM users WHERE ID IS NULL;
+DELETE FROM users a USING users b WHERE a.ID = b.ID AND a.ctid > b.ctid;
+
+-- usernames are unique regardless of case from now on; instead of deleting accounts, every later duplicate
+-- gets the start of its id appended
+UPDATE users SET USERNAME = ID::text WHERE USERNAME IS NULL;
+UPDATE users u SET USERNAME = u.USERNAME || '-' || left(u.ID::text, 8)
+FROM users earlier
+WHERE lower(u.USERNAME) = lower(earlier.USERNAME) AND u.ctid > earlier.ctid;
+UPDATE users SET PASSWORD = '' WHERE PASSWORD IS NULL;
+
+ALTER TABLE users
+    ALTER COLUMN USERNAME SET NOT NULL,
+    ALTER COLUMN PASSWORD SET NOT NULL,
+    ADD PRIMARY KEY (ID);
+
+CREATE UNIQUE INDEX IF NOT EXISTS users_username_lower_idx ON users (lower(USERNAME));
+
+DELETE FROM records WHERE ID IS NULL;
+DELETE FROM records a USING records b WHERE a.ID = b.ID AND a.ctid > b.ctid;
+
+-- records whose author does not exist, or is not even a uuid, can be read by nobody
+DELETE FROM records r
+WHERE r.AUTHOR IS NULL OR NOT EXISTS (SELECT 1 FROM users u WHERE u.ID::text = lower(r.AUTHOR));
+
+UPDATE records SET DATE_CREATED = '' WHERE DATE_CREATED IS NULL;
+UPDATE records SET MESSAGE_BODY = '' WHERE MESSAGE_BODY IS NULL;
+
+-- DATE_CREATED stays text: it is the calendar date the client sent, returned exactly as it was stored.
+-- A user's records go with their account.
+ALTER TABLE records
+    ALTER COLUMN AUTHOR TYPE uuid USING AUTHOR::uuid,
+    ALTER COLUMN AUTHOR SET NOT NULL,
+    ALTER COLUMN DATE_CREATED SET NOT NULL,
+    ALTER COLUMN MESSAGE_BODY SET NOT NULL,
+    ADD PRIMARY KEY (ID),
+    ADD CONSTRAINT records_author_fkey FOREIGN KEY (AUTHOR) REFERENCES users (ID) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS records_author_date_created_idx ON records (AUTHOR, DATE_CREATED);
@@ -0,0 +1,14 @@
+-- DELETE_AFTER is set while the deletion of an account is pending; the account is purged once it has passed
+ALTER TABLE users ADD COLUMN IF NOT EXISTS DELETE_AFTER timestamptz;
+
+CREATE INDEX IF NOT EXISTS users_delete_after_idx ON users (DELETE_AFTER) WHERE DELETE_AFTER IS NOT NULL;
+
+-- audit events outlive the user they are about, so USER_ID references nothing
+CREATE TABLE IF NOT EXISTS audit_events (
+    ID uuid PRIMARY KEY,
+    USER_ID uuid NOT NULL,
+    ACTION text NOT NULL,
+    DATE_CREATED timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS audit_events_user_id_idx ON audit_events (USER_ID);
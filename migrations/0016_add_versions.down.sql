@@ -0,0 +1,2 @@
+ALTER TABLE records DROP COLUMN IF EXISTS VERSION;
+ALTER TABLE users DROP COLUMN IF EXISTS VERSION;
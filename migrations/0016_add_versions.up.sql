@@ -0,0 +1,4 @@
+-- VERSION counts the writes to a row; it is the entity tag clients send back in If-Match to update only the
+-- version they have seen
+ALTER TABLE users ADD COLUMN IF NOT EXISTS VERSION integer NOT NULL DEFAULT 1;
+ALTER TABLE records ADD COLUMN IF NOT EXISTS VERSION integer NOT NULL DEFAULT 1;
@@ -0,0 +1,15 @@
+DROP TABLE IF EXISTS oauth_consents;
+DROP TABLE IF EXISTS oauth_authorization_codes;
+DROP TABLE IF EXISTS oauth_clients;
+DROP TABLE IF EXISTS user_identities;
+DROP TABLE IF EXISTS oidc_logins;
+DROP TABLE IF EXISTS api_keys;
+DROP TABLE IF EXISTS lockout_events;
+DROP TABLE IF EXISTS one_time_tokens;
+DROP TABLE IF EXISTS user_mfa;
+DROP TABLE IF EXISTS sessions;
+DROP TABLE IF EXISTS refresh_tokens;
+DROP TABLE IF EXISTS record_comments;
+DROP TABLE IF EXISTS coach_relationships;
+DROP TABLE IF EXISTS records;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,177 @@
+-- the sqlite schema starts from the state of the Postgres migrations 0001 to 0013; uuids are stored as
+-- text and booleans as 0/1
+CREATE TABLE IF NOT EXISTS users (
+    ID text PRIMARY KEY,
+    USERNAME text,
+    PASSWORD text,
+    ROLE text NOT NULL DEFAULT 'user',
+    DISABLED boolean NOT NULL DEFAULT false,
+    EMAIL text,
+    EMAIL_VERIFIED boolean NOT NULL DEFAULT false
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS users_email_lower_idx ON users (lower(EMAIL)) WHERE EMAIL IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS records (
+    ID text PRIMARY KEY,
+    DATE_CREATED text,
+    MESSAGE_BODY text,
+    AUTHOR text
+);
+
+CREATE TABLE IF NOT EXISTS coach_relationships (
+    ID text PRIMARY KEY,
+    COACH_ID text NOT NULL,
+    ATHLETE_ID text NOT NULL,
+    SCOPES text NOT NULL,
+    STATUS text NOT NULL,
+    DATE_CREATED timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS coach_relationships_coach_id_idx ON coach_relationships (COACH_ID);
+CREATE INDEX IF NOT EXISTS coach_relationships_athlete_id_idx ON coach_relationships (ATHLETE_ID);
+
+CREATE TABLE IF NOT EXISTS record_comments (
+    ID text PRIMARY KEY,
+    RECORD_ID text NOT NULL,
+    AUTHOR text NOT NULL,
+    MESSAGE_BODY text NOT NULL,
+    DATE_CREATED timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS record_comments_record_id_idx ON record_comments (RECORD_ID);
+
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    ID text PRIMARY KEY,
+    USER_ID text NOT NULL,
+    FAMILY_ID text NOT NULL,
+    TOKEN_HASH text NOT NULL UNIQUE,
+    USED boolean NOT NULL DEFAULT false,
+    REVOKED boolean NOT NULL DEFAULT false,
+    EXPIRES_AT timestamp NOT NULL,
+    DATE_CREATED timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS refresh_tokens_family_id_idx ON refresh_tokens (FAMILY_ID);
+CREATE INDEX IF NOT EXISTS refresh_tokens_user_id_idx ON refresh_tokens (USER_ID);
+
+CREATE TABLE IF NOT EXISTS sessions (
+    ID text PRIMARY KEY,
+    USER_ID text NOT NULL,
+    CLIENT_ID text NOT NULL DEFAULT '',
+    SCOPES text NOT NULL DEFAULT '',
+    DEVICE text NOT NULL DEFAULT '',
+    IP text NOT NULL DEFAULT '',
+    USER_AGENT text NOT NULL DEFAULT '',
+    REVOKED boolean NOT NULL DEFAULT false,
+    LAST_USED timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    DATE_CREATED timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS sessions_user_id_idx ON sessions (USER_ID);
+CREATE INDEX IF NOT EXISTS sessions_client_id_idx ON sessions (CLIENT_ID) WHERE CLIENT_ID <> '';
+
+CREATE TABLE IF NOT EXISTS user_mfa (
+    USER_ID text PRIMARY KEY,
+    SECRET text NOT NULL,
+    ENABLED boolean NOT NULL DEFAULT false,
+    RECOVERY_CODE_HASHES text NOT NULL DEFAULT '',
+    LAST_USED_STEP bigint NOT NULL DEFAULT 0,
+    DATE_CREATED timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS one_time_tokens (
+    ID text PRIMARY KEY,
+    USER_ID text NOT NULL,
+    PURPOSE text NOT NULL,
+    TOKEN_HASH text NOT NULL UNIQUE,
+    USED boolean NOT NULL DEFAULT false,
+    EXPIRES_AT timestamp NOT NULL,
+    DATE_CREATED timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS one_time_tokens_user_id_purpose_idx ON one_time_tokens (USER_ID, PURPOSE);
+
+CREATE TABLE IF NOT EXISTS lockout_events (
+    ID text PRIMARY KEY,
+    USER_ID text,
+    USERNAME text NOT NULL,
+    IP text NOT NULL,
+    REASON text NOT NULL,
+    LOCKED_UNTIL timestamp NOT NULL,
+    DATE_CREATED timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS lockout_events_user_id_idx ON lockout_events (USER_ID);
+
+CREATE TABLE IF NOT EXISTS api_keys (
+    ID text PRIMARY KEY,
+    USER_ID text NOT NULL,
+    NAME text NOT NULL,
+    PREFIX text NOT NULL,
+    KEY_HASH text NOT NULL UNIQUE,
+    SCOPES text NOT NULL,
+    REVOKED boolean NOT NULL DEFAULT false,
+    EXPIRES_AT timestamp,
+    LAST_USED timestamp,
+    DATE_CREATED timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS api_keys_user_id_idx ON api_keys (USER_ID);
+
+CREATE TABLE IF NOT EXISTS oidc_logins (
+    ID text PRIMARY KEY,
+    STATE_HASH text NOT NULL UNIQUE,
+    PROVIDER text NOT NULL,
+    VERIFIER text NOT NULL,
+    NONCE text NOT NULL,
+    LINK_USER_ID text,
+    EXPIRES_AT timestamp NOT NULL,
+    DATE_CREATED timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS user_identities (
+    ID text PRIMARY KEY,
+    USER_ID text NOT NULL,
+    PROVIDER text NOT NULL,
+    SUBJECT text NOT NULL,
+    EMAIL text NOT NULL DEFAULT '',
+    DATE_CREATED timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (PROVIDER, SUBJECT)
+);
+
+CREATE INDEX IF NOT EXISTS user_identities_user_id_idx ON user_identities (USER_ID);
+
+CREATE TABLE IF NOT EXISTS oauth_clients (
+    ID text PRIMARY KEY,
+    OWNER_ID text NOT NULL,
+    NAME text NOT NULL,
+    SECRET_HASH text NOT NULL DEFAULT '',
+    REDIRECT_URIS text NOT NULL,
+    SCOPES text NOT NULL,
+    DATE_CREATED timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS oauth_clients_owner_id_idx ON oauth_clients (OWNER_ID);
+
+CREATE TABLE IF NOT EXISTS oauth_authorization_codes (
+    ID text PRIMARY KEY,
+    CODE_HASH text NOT NULL UNIQUE,
+    CLIENT_ID text NOT NULL,
+    USER_ID text NOT NULL,
+    REDIRECT_URI text NOT NULL,
+    SCOPES text NOT NULL,
+    CODE_CHALLENGE text NOT NULL,
+    USED boolean NOT NULL DEFAULT false,
+    SESSION_ID text,
+    EXPIRES_AT timestamp NOT NULL,
+    DATE_CREATED timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS oauth_consents (
+    USER_ID text NOT NULL,
+    CLIENT_ID text NOT NULL,
+    SCOPES text NOT NULL,
+    DATE_CREATED timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (USER_ID, CLIENT_ID)
+);
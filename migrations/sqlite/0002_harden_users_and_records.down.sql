@@ -0,0 +1,15 @@
+-- the rows removed or renamed by the cleanup are not restored
+CREATE TABLE records_old (
+    ID text PRIMARY KEY,
+    DATE_CREATED text,
+    MESSAGE_BODY text,
+    AUTHOR text
+);
+
+INSERT INTO records_old (ID, DATE_CREATED, MESSAGE_BODY, AUTHOR)
+SELECT ID, DATE_CREATED, MESSAGE_BODY, AUTHOR FROM records;
+
+DROP TABLE records;
+ALTER TABLE records_old RENAME TO records;
+
+DROP INDEX IF EXISTS users_username_lower_idx;
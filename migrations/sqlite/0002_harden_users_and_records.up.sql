@@ -0,0 +1,31 @@
+-- the counterpart of the Postgres migration 0014; users already has its primary key here
+
+-- usernames are unique regardless of case from now on; instead of deleting accounts, every later duplicate
+-- gets the start of its id appended
+UPDATE users SET USERNAME = ID WHERE USERNAME IS NULL;
+UPDATE users SET USERNAME = USERNAME || '-' || substr(ID, 1, 8)
+WHERE EXISTS (
+    SELECT 1 FROM users earlier
+    WHERE lower(earlier.USERNAME) = lower(users.USERNAME) AND earlier.rowid < users.rowid
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS users_username_lower_idx ON users (lower(USERNAME));
+
+-- records whose author does not exist can be read by nobody
+DELETE FROM records WHERE ID IS NULL OR AUTHOR IS NULL OR AUTHOR NOT IN (SELECT ID FROM users);
+
+-- sqlite cannot add constraints to an existing table, so records is rebuilt
+CREATE TABLE records_new (
+    ID text PRIMARY KEY NOT NULL,
+    DATE_CREATED text NOT NULL,
+    MESSAGE_BODY text NOT NULL,
+    AUTHOR text NOT NULL REFERENCES users (ID) ON DELETE CASCADE
+);
+
+INSERT INTO records_new (ID, DATE_CREATED, MESSAGE_BODY, AUTHOR)
+SELECT ID, COALESCE(DATE_CREATED, ''), COALESCE(MESSAGE_BODY, ''), AUTHOR FROM records;
+
+DROP TABLE records;
+ALTER TABLE records_new RENAME TO records;
+
+CREATE INDEX IF NOT EXISTS records_author_date_created_idx ON records (AUTHOR, DATE_CREATED);
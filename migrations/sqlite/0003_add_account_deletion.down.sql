@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS audit_events;
+DROP INDEX IF EXISTS users_delete_after_idx;
+ALTER TABLE users DROP COLUMN DELETE_AFTER;
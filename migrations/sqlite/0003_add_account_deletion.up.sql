@@ -0,0 +1,13 @@
+-- the counterpart of the Postgres migration 0015
+ALTER TABLE users ADD COLUMN DELETE_AFTER timestamp;
+
+CREATE INDEX IF NOT EXISTS users_delete_after_idx ON users (DELETE_AFTER) WHERE DELETE_AFTER IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS audit_events (
+    ID text PRIMARY KEY,
+    USER_ID text NOT NULL,
+    ACTION text NOT NULL,
+    DATE_CREATED timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS audit_events_user_id_idx ON audit_events (USER_ID);
@@ -0,0 +1,2 @@
+ALTER TABLE records DROP COLUMN VERSION;
+ALTER TABLE users DROP COLUMN VERSION;
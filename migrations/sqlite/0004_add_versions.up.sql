@@ -0,0 +1,3 @@
+-- the counterpart of the Postgres migration 0016
+ALTER TABLE users ADD COLUMN VERSION integer NOT NULL DEFAULT 1;
+ALTER TABLE records ADD COLUMN VERSION integer NOT NULL DEFAULT 1;